@@ -25,6 +25,7 @@ type User struct {
 
 type Stock struct {
 	Symbol       string `json:"symbol"`
+	ISIN         string `json:"isin"`
 	Name         string `json:"name"`
 	Exchange     string `json:"exchange"`
 	CurrentPrice string `json:"current_price"`
@@ -159,16 +160,34 @@ func seedStocks(db *sql.DB) error {
 			continue
 		}
 
-		_, err = db.Exec(`
-			INSERT INTO stocks (symbol, name, exchange, current_price, is_active)
-			VALUES ($1, $2, $3, $4, $5)
-			ON CONFLICT (symbol) DO UPDATE SET
-				name = EXCLUDED.name,
-				exchange = EXCLUDED.exchange,
-				current_price = EXCLUDED.current_price,
-				is_active = EXCLUDED.is_active,
-				updated_at = CURRENT_TIMESTAMP
-		`, stock.Symbol, stock.Name, stock.Exchange, price, stock.IsActive)
+		if stock.ISIN == "" {
+			_, err = db.Exec(`
+				INSERT INTO stocks (symbol, name, exchange, current_price, is_active)
+				VALUES ($1, $2, $3, $4, $5)
+				ON CONFLICT (symbol) DO UPDATE SET
+					name = EXCLUDED.name,
+					exchange = EXCLUDED.exchange,
+					current_price = EXCLUDED.current_price,
+					is_active = EXCLUDED.is_active,
+					updated_at = CURRENT_TIMESTAMP
+			`, stock.Symbol, stock.Name, stock.Exchange, price, stock.IsActive)
+		} else {
+			// Stocks are matched on ISIN so that a stock renamed by a
+			// SYMBOL_CHANGE is not seeded again under its old symbol.
+			_, err = db.Exec(`UPDATE stocks SET isin = $1 WHERE symbol = $2 AND isin IS NULL`, stock.ISIN, stock.Symbol)
+			if err == nil {
+				_, err = db.Exec(`
+					INSERT INTO stocks (symbol, isin, name, exchange, current_price, is_active)
+					VALUES ($1, $2, $3, $4, $5, $6)
+					ON CONFLICT (isin) DO UPDATE SET
+						name = EXCLUDED.name,
+						exchange = EXCLUDED.exchange,
+						current_price = EXCLUDED.current_price,
+						is_active = EXCLUDED.is_active,
+						updated_at = CURRENT_TIMESTAMP
+				`, stock.Symbol, stock.ISIN, stock.Name, stock.Exchange, price, stock.IsActive)
+			}
+		}
 
 		if err != nil {
 			return fmt.Errorf("failed to insert stock %s: %w", stock.Symbol, err)
@@ -0,0 +1,41 @@
+package config
+
+import "time"
+
+type CorporateActionConfig struct {
+	DividendTDSRate      float64
+	DividendTDSRateNoPAN float64
+	DividendTDSThreshold float64
+
+	SchedulerEnabled      bool
+	SchedulerInterval     time.Duration
+	SchedulerMaxAttempts  int
+	SchedulerRetryBackoff time.Duration
+
+	ProcessingChunkSize int
+}
+
+// LoadCorporateActionConfig reads dividend TDS settings. TDS applies once a
+// user's dividends from one stock in a financial year exceed the threshold,
+// at the higher no-PAN rate when the user has not provided a PAN.
+//
+// The scheduler processes due corporate actions every interval; a transient
+// failure is retried up to SchedulerMaxAttempts times, waiting
+// SchedulerRetryBackoff multiplied by the attempt number between tries.
+//
+// Processing applies an action to ProcessingChunkSize holders per
+// transaction.
+func LoadCorporateActionConfig() *CorporateActionConfig {
+	return &CorporateActionConfig{
+		DividendTDSRate:      getEnvFloat("DIVIDEND_TDS_RATE", 0.10),
+		DividendTDSRateNoPAN: getEnvFloat("DIVIDEND_TDS_RATE_NO_PAN", 0.20),
+		DividendTDSThreshold: getEnvFloat("DIVIDEND_TDS_THRESHOLD", 5000),
+
+		SchedulerEnabled:      getEnvBool("CORPORATE_ACTION_SCHEDULER_ENABLED", true),
+		SchedulerInterval:     time.Duration(getEnvInt("CORPORATE_ACTION_SCHEDULER_INTERVAL_SECONDS", 300)) * time.Second,
+		SchedulerMaxAttempts:  getEnvInt("CORPORATE_ACTION_SCHEDULER_MAX_ATTEMPTS", 3),
+		SchedulerRetryBackoff: time.Duration(getEnvInt("CORPORATE_ACTION_SCHEDULER_RETRY_BACKOFF_SECONDS", 5)) * time.Second,
+
+		ProcessingChunkSize: getEnvInt("CORPORATE_ACTION_CHUNK_SIZE", 1000),
+	}
+}
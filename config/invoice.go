@@ -0,0 +1,38 @@
+package config
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+type InvoiceConfig struct {
+	SupplierName      string
+	SupplierGSTIN     string
+	SupplierAddress   string
+	SupplierStateCode string
+	NumberPrefix      string
+	SACCode           string
+}
+
+// LoadInvoiceConfig reads the supplier details printed on GST invoices. The
+// supplier state defaults to the first two digits of the GSTIN. Invoice
+// numbers are <prefix>/<FY>/<sequence> and GST allows at most 16
+// characters, so the prefix is limited to 4.
+func LoadInvoiceConfig() *InvoiceConfig {
+	cfg := &InvoiceConfig{
+		SupplierName:      getEnv("INVOICE_SUPPLIER_NAME", "Stocky Technologies Private Limited"),
+		SupplierGSTIN:     getEnv("INVOICE_SUPPLIER_GSTIN", ""),
+		SupplierAddress:   getEnv("INVOICE_SUPPLIER_ADDRESS", ""),
+		SupplierStateCode: getEnv("INVOICE_SUPPLIER_STATE_CODE", ""),
+		NumberPrefix:      getEnv("INVOICE_NUMBER_PREFIX", "STK"),
+		SACCode:           getEnv("INVOICE_SAC_CODE", "997152"),
+	}
+
+	if cfg.SupplierStateCode == "" && len(cfg.SupplierGSTIN) >= 2 {
+		cfg.SupplierStateCode = cfg.SupplierGSTIN[:2]
+	}
+	if len(cfg.NumberPrefix) > 4 {
+		logrus.Warnf("INVOICE_NUMBER_PREFIX %q is longer than 4 characters, using %q", cfg.NumberPrefix, cfg.NumberPrefix[:4])
+		cfg.NumberPrefix = cfg.NumberPrefix[:4]
+	}
+	return cfg
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+// KYC policies for new rewards: NONE ignores KYC, REQUIRE rejects rewards for
+// users who are not KYC_VERIFIED, PARK holds them as PENDING_KYC until
+// verification completes.
+const (
+	KYCPolicyNone    = "NONE"
+	KYCPolicyRequire = "REQUIRE"
+	KYCPolicyPark    = "PARK"
+)
+
+type RewardConfig struct {
+	ApprovalThreshold float64
+	KYCPolicy         string
+}
+
+func LoadRewardConfig() *RewardConfig {
+	kycPolicy := strings.ToUpper(getEnv("REWARD_KYC_POLICY", KYCPolicyNone))
+	switch kycPolicy {
+	case KYCPolicyNone, KYCPolicyRequire, KYCPolicyPark:
+	default:
+		logrus.Warnf("Invalid REWARD_KYC_POLICY %q, using %s", kycPolicy, KYCPolicyNone)
+		kycPolicy = KYCPolicyNone
+	}
+
+	return &RewardConfig{
+		ApprovalThreshold: getEnvFloat("REWARD_APPROVAL_THRESHOLD", 0),
+		KYCPolicy:         kycPolicy,
+	}
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		logrus.Warnf("Invalid value for %s: %v, using default %v", key, err, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		logrus.Warnf("Invalid value for %s: %v, using default %v", key, err, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value := getEnv(key, "")
+	if value == "" {
+		return defaultValue
+	}
+
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		logrus.Warnf("Invalid value for %s: %v, using default %v", key, err, defaultValue)
+		return defaultValue
+	}
+	return parsed
+}
@@ -0,0 +1,53 @@
+package domain
+
+const (
+	CodeUserNotFound                 = "USER_NOT_FOUND"
+	CodeUserInactive                 = "USER_INACTIVE"
+	CodeUserAlreadyActive            = "USER_ALREADY_ACTIVE"
+	CodeEmailAlreadyExists           = "EMAIL_ALREADY_EXISTS"
+	CodeInvalidPhone                 = "INVALID_PHONE"
+	CodeInvalidUserUpdate            = "INVALID_USER_UPDATE"
+	CodeUserErased                   = "USER_ERASED"
+	CodeInvalidExportFormat          = "INVALID_EXPORT_FORMAT"
+	CodeKYCNotVerified               = "KYC_NOT_VERIFIED"
+	CodeInvalidKYCTransition         = "INVALID_KYC_TRANSITION"
+	CodeInvalidPAN                   = "INVALID_PAN"
+	CodePANAlreadyExists             = "PAN_ALREADY_EXISTS"
+	CodeDematAccountRequired         = "DEMAT_ACCOUNT_REQUIRED"
+	CodeInvalidDematAccount          = "INVALID_DEMAT_ACCOUNT"
+	CodeDematAccountExists           = "DEMAT_ACCOUNT_EXISTS"
+	CodeStockNotFound                = "STOCK_NOT_FOUND"
+	CodeStockDelisted                = "STOCK_DELISTED"
+	CodeSymbolInUse                  = "SYMBOL_IN_USE"
+	CodeRewardNotFound               = "REWARD_NOT_FOUND"
+	CodeDuplicateReward              = "DUPLICATE_REWARD"
+	CodeIdempotencyKeyUsed           = "IDEMPOTENCY_KEY_USED"
+	CodeRequestedByRequired          = "REQUESTED_BY_REQUIRED"
+	CodeRewardNotPendingReview       = "REWARD_NOT_PENDING_APPROVAL"
+	CodeSameOperatorReview           = "SAME_OPERATOR_REVIEW"
+	CodeRewardNotAdjustable          = "REWARD_NOT_ADJUSTABLE"
+	CodeRewardUnitsChanged           = "REWARD_UNITS_CHANGED"
+	CodeInvalidAdjustment            = "INVALID_ADJUSTMENT_QUANTITY"
+	CodeHoldingsNotFound             = "HOLDINGS_NOT_FOUND"
+	CodeInsufficientHoldings         = "INSUFFICIENT_HOLDINGS"
+	CodePendingCorporateAction       = "PENDING_CORPORATE_ACTION"
+	CodeCorporateActionNotFound      = "CORPORATE_ACTION_NOT_FOUND"
+	CodeCorporateActionDone          = "CORPORATE_ACTION_ALREADY_PROCESSED"
+	CodeInvalidCorporateAction       = "INVALID_CORPORATE_ACTION"
+	CodeDividendNotPayable           = "DIVIDEND_NOT_PAYABLE"
+	CodeCorporateActionNotPending    = "CORPORATE_ACTION_NOT_PENDING"
+	CodeCorporateActionNotReversible = "CORPORATE_ACTION_NOT_REVERSIBLE"
+	CodeCorporateActionNotApproved   = "CORPORATE_ACTION_NOT_APPROVED"
+	CodeCorporateActionInProgress    = "CORPORATE_ACTION_IN_PROGRESS"
+	CodeSchedulerBusy                = "SCHEDULER_BUSY"
+	CodeSchedulerRunNotFound         = "SCHEDULER_RUN_NOT_FOUND"
+	CodeFeeVersionNotFound           = "FEE_VERSION_NOT_FOUND"
+	CodeInvalidFeeVersion            = "INVALID_FEE_VERSION"
+	CodeFeeVersionExists             = "FEE_VERSION_EXISTS"
+	CodeFeeVersionNotScheduled       = "FEE_VERSION_NOT_SCHEDULED"
+	CodeInvalidReportRange           = "INVALID_REPORT_RANGE"
+	CodeInvoiceNotFound              = "INVOICE_NOT_FOUND"
+	CodeInvoiceExists                = "INVOICE_EXISTS"
+	CodeInvalidInvoice               = "INVALID_INVOICE"
+	CodeNothingToInvoice             = "NOTHING_TO_INVOICE"
+)
@@ -0,0 +1,82 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+type ErrorKind string
+
+const (
+	KindNotFound     ErrorKind = "NOT_FOUND"
+	KindConflict     ErrorKind = "CONFLICT"
+	KindValidation   ErrorKind = "VALIDATION"
+	KindBusinessRule ErrorKind = "BUSINESS_RULE"
+)
+
+// Sentinels for errors.Is checks against a *Error of the matching kind.
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrValidation   = errors.New("validation failed")
+	ErrBusinessRule = errors.New("business rule violated")
+)
+
+// Error is returned by services for failures the caller can act on. Code is a
+// stable machine-readable identifier; Message is for humans and may change.
+type Error struct {
+	Kind    ErrorKind
+	Code    string
+	Message string
+	Meta    map[string]interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+func (e *Error) Is(target error) bool {
+	switch target {
+	case ErrNotFound:
+		return e.Kind == KindNotFound
+	case ErrConflict:
+		return e.Kind == KindConflict
+	case ErrValidation:
+		return e.Kind == KindValidation
+	case ErrBusinessRule:
+		return e.Kind == KindBusinessRule
+	}
+	return false
+}
+
+func (e *Error) WithMeta(key string, value interface{}) *Error {
+	if e.Meta == nil {
+		e.Meta = make(map[string]interface{})
+	}
+	e.Meta[key] = value
+	return e
+}
+
+func newError(kind ErrorKind, code string, format string, args ...interface{}) *Error {
+	return &Error{
+		Kind:    kind,
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+func NotFound(code string, format string, args ...interface{}) *Error {
+	return newError(KindNotFound, code, format, args...)
+}
+
+func Conflict(code string, format string, args ...interface{}) *Error {
+	return newError(KindConflict, code, format, args...)
+}
+
+func Validation(code string, format string, args ...interface{}) *Error {
+	return newError(KindValidation, code, format, args...)
+}
+
+func BusinessRule(code string, format string, args ...interface{}) *Error {
+	return newError(KindBusinessRule, code, format, args...)
+}
@@ -0,0 +1,103 @@
+package corporate_action
+
+import (
+	"database/sql"
+	"fmt"
+
+	"stocky-backend/domain"
+	"stocky-backend/features/tax_lot"
+
+	"github.com/sirupsen/logrus"
+)
+
+// delistingSteps removes every user's units of the stock and deactivates
+// it. With CASH_SETTLEMENT users are paid exit_price per unit as an INR
+// credit; with WRITE_OFF the cost basis is booked as a loss. Either way the
+// outcome, including realised profit or loss, is recorded per user, and the
+// units leave the user's tax lots as a disposal on the effective date.
+func delistingSteps(action *CorporateAction) (*actionSteps, error) {
+	switch action.DelistingMode {
+	case "", DelistingWriteOff:
+		action.DelistingMode = DelistingWriteOff
+	case DelistingCashSettlement:
+		if action.ExitPrice <= 0 {
+			return nil, domain.Validation(domain.CodeInvalidCorporateAction, "delisting %d is cash settled but has no exit_price", action.ID)
+		}
+	default:
+		return nil, domain.Validation(domain.CodeInvalidCorporateAction, "unknown delisting mode: %s", action.DelistingMode)
+	}
+
+	apply := func(tx *sql.Tx, position heldPosition) error {
+		if position.quantity <= 0 {
+			return nil
+		}
+
+		cost := roundAmount(position.quantity * position.averagePrice)
+		settlement := 0.0
+		if action.DelistingMode == DelistingCashSettlement {
+			settlement = roundAmount(position.quantity * action.ExitPrice)
+		}
+		realizedPnL := settlement - cost
+
+		_, err := tx.Exec(`
+			UPDATE user_stock_holdings
+			SET total_quantity = 0, updated_at = NOW()
+			WHERE user_id = $1 AND stock_id = $2
+		`, position.userID, action.StockID)
+		if err != nil {
+			logrus.Errorf("Failed to process delisting for user %d: %v", position.userID, err)
+			return err
+		}
+
+		if err = postUnitChange(tx, action, position.userID, action.StockID, -position.quantity); err != nil {
+			return err
+		}
+
+		if action.DelistingMode == DelistingCashSettlement {
+			_, err = tx.Exec(`
+				INSERT INTO ledger_entries (corporate_action_id, user_id, entry_type, account_type, stock_id, amount, description)
+				VALUES ($1, $2, 'DEBIT', 'INR_CASH', $3, $4, $5)
+			`, action.ID, position.userID, action.StockID, settlement,
+				fmt.Sprintf("Delisting exit at %.4f per share", action.ExitPrice))
+		} else if cost > 0 {
+			_, err = tx.Exec(`
+				INSERT INTO ledger_entries (corporate_action_id, user_id, entry_type, account_type, stock_id, amount, description)
+				VALUES ($1, $2, 'DEBIT', 'WRITE_OFF_LOSS', $3, $4, 'Holding written off on delisting')
+			`, action.ID, position.userID, action.StockID, cost)
+		}
+		if err != nil {
+			logrus.Errorf("Failed to create delisting ledger entry: %v", err)
+			return err
+		}
+
+		err = tax_lot.Dispose(tx, tax_lot.Disposal{
+			UserID:            position.userID,
+			StockID:           action.StockID,
+			Type:              tax_lot.DisposalDelisting,
+			CorporateActionID: action.ID,
+			DisposedAt:        action.EffectiveDate,
+			Quantity:          position.quantity,
+			Proceeds:          settlement,
+		})
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO corporate_action_entitlements (corporate_action_id, user_id, stock_id, quantity_before, average_price_before,
+			                                           record_quantity, entitled_quantity, quantity_after, average_price_after,
+			                                           settlement_amount, realized_pnl)
+			VALUES ($1, $2, $3, $4, $5, $6, 0, 0, 0, $7, $8)
+		`, action.ID, position.userID, action.StockID, position.quantity, position.averagePrice, position.recordQuantity,
+			settlement, realizedPnL)
+		if err != nil {
+			logrus.Errorf("Failed to record delisting outcome for user %d: %v", position.userID, err)
+		}
+		return err
+	}
+
+	return &actionSteps{apply: apply, finish: func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE stocks SET is_active = false, updated_at = NOW() WHERE id = $1`, action.StockID)
+		return err
+	}}, nil
+}
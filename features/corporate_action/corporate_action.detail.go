@@ -0,0 +1,202 @@
+package corporate_action
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"stocky-backend/domain"
+	"stocky-backend/features/stock"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// buildCorporateActionFilter returns a WHERE clause over corporate_actions
+// aliased as ca, and its positional arguments. A symbol matches actions on
+// the stock and actions naming it as merger target or spin-off; it may be a
+// retired symbol or an ISIN.
+func buildCorporateActionFilter(q queryer, filter CorporateActionFilter) (string, []interface{}, error) {
+	var conditions []string
+	var args []interface{}
+	next := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if filter.Symbol != "" {
+		ref, err := stock.Resolve(q, filter.Symbol)
+		if err == sql.ErrNoRows {
+			return " WHERE false", nil, nil
+		}
+		if err != nil {
+			logrus.Errorf("Failed to resolve stock %s: %v", filter.Symbol, err)
+			return "", nil, err
+		}
+		id := next(ref.ID)
+		conditions = append(conditions, "(ca.stock_id = "+id+" OR ca.merger_to_stock_id = "+id+" OR ca.spinoff_stock_id = "+id+")")
+	}
+	if filter.ActionType != "" {
+		switch CorporateActionType(filter.ActionType) {
+		case ActionStockSplit, ActionMerger, ActionDelisting, ActionDividend, ActionBonus, ActionReverseSplit, ActionSpinoff, ActionSymbolChange:
+		default:
+			return "", nil, domain.Validation(domain.CodeInvalidCorporateAction, "unknown action_type filter: %s", filter.ActionType)
+		}
+		conditions = append(conditions, "ca.action_type = "+next(filter.ActionType))
+	}
+	if filter.Status != "" {
+		switch filter.Status {
+		case "PENDING", "APPROVED", "PROCESSING", "COMPLETED", "CANCELLED", "REVERSED":
+		default:
+			return "", nil, domain.Validation(domain.CodeInvalidCorporateAction, "unknown status filter: %s", filter.Status)
+		}
+		conditions = append(conditions, "ca.status = "+next(filter.Status))
+	}
+	if filter.EffectiveFrom != nil && filter.EffectiveTo != nil && filter.EffectiveTo.Before(*filter.EffectiveFrom) {
+		return "", nil, domain.Validation(domain.CodeInvalidCorporateAction, "effective_to cannot be before effective_from")
+	}
+	if filter.EffectiveFrom != nil {
+		conditions = append(conditions, "ca.effective_date >= "+next(filter.EffectiveFrom.Format("2006-01-02"))+"::date")
+	}
+	if filter.EffectiveTo != nil {
+		conditions = append(conditions, "ca.effective_date <= "+next(filter.EffectiveTo.Format("2006-01-02"))+"::date")
+	}
+
+	if len(conditions) == 0 {
+		return "", args, nil
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args, nil
+}
+
+// fillCurrentHolders sets affected_users on unprocessed actions to the
+// number of users holding their stock now, with one query for the page.
+func fillCurrentHolders(q queryer, actions []CorporateActionResponse) error {
+	var symbols []string
+	for _, action := range actions {
+		if action.ProcessedAt == nil {
+			symbols = append(symbols, action.StockSymbol)
+		}
+	}
+	if len(symbols) == 0 {
+		return nil
+	}
+
+	rows, err := q.Query(`
+		SELECT s.symbol, COUNT(DISTINCT h.user_id)
+		FROM user_stock_holdings h
+		JOIN stocks s ON h.stock_id = s.id
+		WHERE s.symbol = ANY($1) AND h.total_quantity > 0
+		GROUP BY s.symbol
+	`, pq.Array(symbols))
+	if err != nil {
+		logrus.Errorf("Failed to count current holders: %v", err)
+		return err
+	}
+	defer rows.Close()
+
+	holders := make(map[string]int)
+	for rows.Next() {
+		var symbol string
+		var count int
+		if err := rows.Scan(&symbol, &count); err != nil {
+			return err
+		}
+		holders[symbol] = count
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for i := range actions {
+		if actions[i].ProcessedAt == nil {
+			actions[i].AffectedUsers = holders[actions[i].StockSymbol]
+		}
+	}
+	return nil
+}
+
+// recordProcessingResult totals what the action did from the rows its
+// steps wrote. It runs in the switchover transaction, after the finish
+// step; priceBefore is the stock price snapshotted when processing started.
+func recordProcessingResult(tx *sql.Tx, action *CorporateAction, priceBefore float64) error {
+	_, err := tx.Exec(`
+		INSERT INTO corporate_action_results (corporate_action_id, users_affected, units_before, units_after,
+		                                      fractional_units, cash_amount, tds_amount, stock_price_before, stock_price_after)
+		SELECT $1, COUNT(DISTINCT r.user_id), COALESCE(SUM(r.units_before), 0), COALESCE(SUM(r.units_after), 0),
+		       COALESCE(SUM(r.fractional), 0), COALESCE(SUM(r.cash), 0), COALESCE(SUM(r.tds), 0),
+		       $2, (SELECT current_price FROM stocks WHERE id = $3)
+		FROM (
+			SELECT user_id, quantity_before AS units_before, quantity_after AS units_after,
+			       fractional_quantity AS fractional, cash_in_lieu_amount + settlement_amount AS cash, 0 AS tds
+			FROM corporate_action_entitlements WHERE corporate_action_id = $1
+			UNION ALL
+			SELECT user_id, record_quantity, record_quantity, 0, net_amount, tds_amount
+			FROM dividend_entitlements WHERE corporate_action_id = $1
+			UNION ALL
+			SELECT user_id, total_quantity, total_quantity, 0, 0, 0
+			FROM corporate_action_holding_snapshots
+			WHERE corporate_action_id = $1 AND $4 AND total_quantity > 0
+		) r
+	`, action.ID, priceBefore, action.StockID, action.ActionType == ActionSymbolChange)
+	if err != nil {
+		logrus.Errorf("Failed to record processing result for corporate action %d: %v", action.ID, err)
+	}
+	return err
+}
+
+// GetCorporateAction returns one action with its operators, reversal, the
+// progress of its chunked run and the result captured at the switchover.
+func (s *CorporateActionService) GetCorporateAction(actionID int) (*CorporateActionDetail, error) {
+	action, err := getCorporateAction(s.db, actionID)
+	if err != nil {
+		return nil, err
+	}
+	detail := &CorporateActionDetail{CorporateActionResponse: *action}
+
+	err = s.db.QueryRow(`
+		SELECT COALESCE(updated_by, ''), cancelled_at, COALESCE(reversed_by, ''), reversed_at, COALESCE(reversal_reason, '')
+		FROM corporate_actions WHERE id = $1
+	`, actionID).Scan(&detail.UpdatedBy, &detail.CancelledAt, &detail.ReversedBy, &detail.ReversedAt, &detail.ReversalReason)
+	if err != nil {
+		logrus.Errorf("Failed to fetch corporate action detail: %v", err)
+		return nil, err
+	}
+
+	var progress ProcessingProgress
+	err = s.db.QueryRow(`
+		SELECT total_positions, processed_positions, chunks_processed, last_user_id,
+		       COALESCE(started_by, ''), COALESCE(last_error, ''), started_at, updated_at, completed_at
+		FROM corporate_action_processing_progress WHERE corporate_action_id = $1
+	`, actionID).Scan(&progress.TotalPositions, &progress.ProcessedPositions, &progress.ChunksProcessed, &progress.LastUserID,
+		&progress.StartedBy, &progress.LastError, &progress.StartedAt, &progress.UpdatedAt, &progress.CompletedAt)
+	if err == nil {
+		detail.ProcessingProgress = &progress
+	} else if err != sql.ErrNoRows {
+		logrus.Errorf("Failed to fetch corporate action progress: %v", err)
+		return nil, err
+	}
+
+	var result ProcessingResult
+	var priceBefore, priceAfter sql.NullFloat64
+	err = s.db.QueryRow(`
+		SELECT users_affected, units_before, units_after, fractional_units, cash_amount, tds_amount,
+		       stock_price_before, stock_price_after, created_at
+		FROM corporate_action_results WHERE corporate_action_id = $1
+	`, actionID).Scan(&result.UsersAffected, &result.UnitsBefore, &result.UnitsAfter, &result.FractionalUnits,
+		&result.CashAmount, &result.TDSAmount, &priceBefore, &priceAfter, &result.CapturedAt)
+	if err == sql.ErrNoRows {
+		return detail, nil
+	}
+	if err != nil {
+		logrus.Errorf("Failed to fetch corporate action result: %v", err)
+		return nil, err
+	}
+	if priceBefore.Valid {
+		result.StockPriceBefore = &priceBefore.Float64
+	}
+	if priceAfter.Valid {
+		result.StockPriceAfter = &priceAfter.Float64
+	}
+	detail.ProcessingResult = &result
+	return detail, nil
+}
@@ -0,0 +1,179 @@
+package corporate_action
+
+import (
+	"database/sql"
+	"math"
+	"time"
+
+	"stocky-backend/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// dividendSteps pays a cash dividend to everyone who held the stock at the
+// end of the record date (see snapshotRecordHoldings), so rewards issued
+// between record and payment date are not entitled. Nothing changes at the
+// stock level, so there is no finish step.
+func (s *CorporateActionService) dividendSteps(action *CorporateAction) (*actionSteps, error) {
+	if action.RecordDate == nil || action.PaymentDate == nil || action.DividendPerShare <= 0 {
+		return nil, domain.Validation(domain.CodeInvalidCorporateAction, "dividend %d is missing record date, payment date or per-share amount", action.ID)
+	}
+
+	fyStart, fyEnd := financialYear(*action.PaymentDate)
+
+	apply := func(tx *sql.Tx, holding heldPosition) error {
+		gross := roundAmount(holding.recordQuantity * action.DividendPerShare)
+		if gross <= 0 {
+			return nil
+		}
+
+		var paidThisYear float64
+		err := tx.QueryRow(`
+			SELECT COALESCE(SUM(de.gross_amount), 0)
+			FROM dividend_entitlements de
+			JOIN corporate_actions ca ON de.corporate_action_id = ca.id
+			WHERE de.user_id = $1 AND de.stock_id = $2
+			AND ca.status <> 'REVERSED'
+			AND ca.payment_date >= $3 AND ca.payment_date < $4
+		`, holding.userID, action.StockID, fyStart, fyEnd).Scan(&paidThisYear)
+		if err != nil {
+			logrus.Errorf("Failed to aggregate dividends for user %d: %v", holding.userID, err)
+			return err
+		}
+
+		tdsRate := 0.0
+		if paidThisYear+gross > s.config.DividendTDSThreshold {
+			tdsRate = s.config.DividendTDSRate
+			if !holding.hasPAN {
+				tdsRate = s.config.DividendTDSRateNoPAN
+			}
+		}
+		tds := roundAmount(gross * tdsRate)
+		net := gross - tds
+
+		_, err = tx.Exec(`
+			INSERT INTO dividend_entitlements (corporate_action_id, user_id, stock_id, record_quantity, dividend_per_share,
+			                                   gross_amount, tds_rate, tds_amount, net_amount)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, action.ID, holding.userID, action.StockID, holding.recordQuantity, action.DividendPerShare, gross, tdsRate, tds, net)
+		if err != nil {
+			logrus.Errorf("Failed to record dividend entitlement for user %d: %v", holding.userID, err)
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO ledger_entries (corporate_action_id, user_id, entry_type, account_type, stock_id, amount, description)
+			VALUES ($1, $2, 'DEBIT', 'DIVIDEND_CASH', $3, $4, 'Dividend paid (net of TDS)')
+		`, action.ID, holding.userID, action.StockID, net)
+		if err != nil {
+			logrus.Errorf("Failed to create dividend ledger entry: %v", err)
+			return err
+		}
+
+		if tds > 0 {
+			_, err = tx.Exec(`
+				INSERT INTO ledger_entries (corporate_action_id, user_id, entry_type, account_type, stock_id, amount, description)
+				VALUES ($1, $2, 'CREDIT', 'TDS_WITHHELD', $3, $4, 'TDS withheld on dividend')
+			`, action.ID, holding.userID, action.StockID, tds)
+			if err != nil {
+				logrus.Errorf("Failed to create TDS ledger entry: %v", err)
+				return err
+			}
+		}
+		return nil
+	}
+
+	return &actionSteps{apply: apply}, nil
+}
+
+func checkDividendPayable(tx *sql.Tx, action *CorporateAction) error {
+	if action.PaymentDate == nil {
+		return domain.Validation(domain.CodeInvalidCorporateAction, "dividend %d has no payment date", action.ID)
+	}
+
+	var payable bool
+	err := tx.QueryRow(`SELECT CURRENT_DATE >= $1::date`, action.PaymentDate).Scan(&payable)
+	if err != nil {
+		return err
+	}
+	if !payable {
+		return domain.BusinessRule(domain.CodeDividendNotPayable, "dividend cannot be paid before its payment date %s", action.PaymentDate.Format("2006-01-02")).
+			WithMeta("payment_date", action.PaymentDate.Format("2006-01-02"))
+	}
+	return nil
+}
+
+func (s *CorporateActionService) GetDividendPayouts(actionID int) (*DividendPayoutReport, error) {
+	report := &DividendPayoutReport{CorporateActionID: actionID, Payouts: []DividendPayout{}}
+
+	var actionType CorporateActionType
+	var dividendPerShare sql.NullFloat64
+	err := s.db.QueryRow(`
+		SELECT s.symbol, ca.action_type, ca.dividend_per_share,
+		       COALESCE(TO_CHAR(ca.record_date, 'YYYY-MM-DD'), ''),
+		       COALESCE(TO_CHAR(ca.payment_date, 'YYYY-MM-DD'), ''),
+		       ca.status
+		FROM corporate_actions ca
+		JOIN stocks s ON ca.stock_id = s.id
+		WHERE ca.id = $1
+	`, actionID).Scan(&report.StockSymbol, &actionType, &dividendPerShare, &report.RecordDate, &report.PaymentDate, &report.Status)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeCorporateActionNotFound, "corporate action not found")
+	}
+	if err != nil {
+		logrus.Errorf("Failed to fetch corporate action: %v", err)
+		return nil, err
+	}
+	if actionType != ActionDividend {
+		return nil, domain.Validation(domain.CodeInvalidCorporateAction, "corporate action %d is a %s, not a dividend", actionID, actionType)
+	}
+	report.DividendPerShare = dividendPerShare.Float64
+
+	rows, err := s.db.Query(`
+		SELECT de.user_id, u.name, u.email, de.record_quantity, de.dividend_per_share,
+		       de.gross_amount, de.tds_rate, de.tds_amount, de.net_amount, de.paid_at
+		FROM dividend_entitlements de
+		JOIN users u ON de.user_id = u.id
+		WHERE de.corporate_action_id = $1
+		ORDER BY de.user_id
+	`, actionID)
+	if err != nil {
+		logrus.Errorf("Failed to query dividend payouts: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var payout DividendPayout
+		err := rows.Scan(
+			&payout.UserID, &payout.UserName, &payout.UserEmail, &payout.RecordQuantity, &payout.DividendPerShare,
+			&payout.GrossAmount, &payout.TDSRate, &payout.TDSAmount, &payout.NetAmount, &payout.PaidAt,
+		)
+		if err != nil {
+			logrus.Errorf("Failed to scan dividend payout: %v", err)
+			return nil, err
+		}
+		report.TotalGross += payout.GrossAmount
+		report.TotalTDS += payout.TDSAmount
+		report.TotalNet += payout.NetAmount
+		report.Payouts = append(report.Payouts, payout)
+	}
+	report.TotalUsers = len(report.Payouts)
+
+	return report, rows.Err()
+}
+
+// financialYear returns the Indian financial year (April to March) that
+// contains t, as a half-open [start, end) range.
+func financialYear(t time.Time) (time.Time, time.Time) {
+	year := t.Year()
+	if t.Month() < time.April {
+		year--
+	}
+	start := time.Date(year, time.April, 1, 0, 0, 0, 0, time.UTC)
+	return start, start.AddDate(1, 0, 0)
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
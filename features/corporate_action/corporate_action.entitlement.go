@@ -0,0 +1,324 @@
+package corporate_action
+
+import (
+	"database/sql"
+	"math"
+
+	"stocky-backend/domain"
+	"stocky-backend/features/tax_lot"
+
+	"github.com/sirupsen/logrus"
+)
+
+type heldPosition struct {
+	userID         int
+	quantity       float64
+	averagePrice   float64
+	recordQuantity float64
+	hasPAN         bool
+}
+
+type settledEntitlement struct {
+	entitled     float64
+	fraction     float64
+	quantity     float64
+	averagePrice float64
+	cashInLieu   float64
+}
+
+// settleEntitlement converts one holding by factor and settles the
+// fractional part according to the action's policy. Cost basis follows the
+// units actually held: with cash in lieu the fraction's share of the cost
+// leaves with the cash, otherwise the whole cost stays on the new position.
+func settleEntitlement(action *CorporateAction, position heldPosition, factor float64) settledEntitlement {
+	entitled := roundQuantity(position.quantity * factor)
+	whole := math.Floor(entitled + quantityEpsilon)
+	cost := position.quantity * position.averagePrice
+
+	result := settledEntitlement{
+		entitled: entitled,
+		fraction: roundQuantity(entitled - whole),
+		quantity: entitled,
+	}
+
+	switch action.FractionalPolicy {
+	case FractionalRoundDownCash:
+		result.quantity = whole
+		result.cashInLieu = roundAmount(result.fraction * action.CashInLieuPrice)
+		if entitled > 0 {
+			cost = cost * whole / entitled
+		}
+	case FractionalRoundHalfUp:
+		result.quantity = math.Floor(entitled + 0.5)
+	}
+
+	if result.quantity > 0 {
+		result.averagePrice = roundPrice(cost / result.quantity)
+	}
+	return result
+}
+
+func validateFractionalPolicy(action *CorporateAction) error {
+	switch action.FractionalPolicy {
+	case FractionalKeep, FractionalRoundHalfUp:
+		return nil
+	case FractionalRoundDownCash:
+		if action.CashInLieuPrice <= 0 {
+			return domain.Validation(domain.CodeInvalidCorporateAction, "corporate action %d pays cash in lieu but has no cash_in_lieu_price", action.ID)
+		}
+		return nil
+	}
+	return domain.Validation(domain.CodeInvalidCorporateAction, "unknown fractional policy: %s", action.FractionalPolicy)
+}
+
+// entitlementStep converts the entitled part of a record-date holding of the
+// action's stock into toStockID units at factor. When toStockID is the same
+// stock the holding is adjusted in place and units acquired after the record
+// date are carried over unchanged; otherwise the source holding is emptied
+// and the target holding topped up at a weighted average price. The user's
+// tax lots follow the converted units (see tax_lot.Convert). Each user's
+// outcome is written to corporate_action_entitlements.
+func entitlementStep(action *CorporateAction, toStockID int, factor float64) (positionStep, error) {
+	if err := validateFractionalPolicy(action); err != nil {
+		return nil, err
+	}
+
+	return func(tx *sql.Tx, position heldPosition) error {
+		entitled := entitledQuantity(action, position)
+		if position.quantity <= 0 || entitled <= 0 {
+			return nil
+		}
+
+		settled := settleEntitlement(action, heldPosition{quantity: entitled, averagePrice: position.averagePrice}, factor)
+		quantityAfter, averagePriceAfter := settled.quantity, settled.averagePrice
+
+		if toStockID == action.StockID {
+			carried := roundQuantity(position.quantity - entitled)
+			if carried > 0 {
+				quantityAfter = settled.quantity + carried
+				averagePriceAfter = roundPrice((settled.quantity*settled.averagePrice + carried*position.averagePrice) / quantityAfter)
+			}
+
+			_, err := tx.Exec(`
+				UPDATE user_stock_holdings
+				SET total_quantity = $1, average_price = $2, updated_at = NOW()
+				WHERE user_id = $3 AND stock_id = $4
+			`, quantityAfter, averagePriceAfter, position.userID, action.StockID)
+			if err != nil {
+				logrus.Errorf("Failed to adjust holding for user %d: %v", position.userID, err)
+				return err
+			}
+			if err = postUnitChange(tx, action, position.userID, action.StockID, quantityAfter-position.quantity); err != nil {
+				return err
+			}
+			if err = postCashInLieu(tx, action, position.userID, action.StockID, settled.cashInLieu); err != nil {
+				return err
+			}
+
+			kind := tax_lot.ConvertReplace
+			if action.ActionType == ActionBonus {
+				kind = tax_lot.ConvertBonus
+			}
+			if err = convertLots(tx, action, position.userID, action.StockID, kind, entitled, settled, 1); err != nil {
+				return err
+			}
+		} else {
+			_, err := tx.Exec(`
+				UPDATE user_stock_holdings
+				SET total_quantity = 0, updated_at = NOW()
+				WHERE user_id = $1 AND stock_id = $2
+			`, position.userID, action.StockID)
+			if err != nil {
+				logrus.Errorf("Failed to close holding for user %d: %v", position.userID, err)
+				return err
+			}
+			if err = postUnitChange(tx, action, position.userID, action.StockID, -position.quantity); err != nil {
+				return err
+			}
+
+			if err = creditHolding(tx, action, position.userID, toStockID, settled); err != nil {
+				return err
+			}
+			if err = convertLots(tx, action, position.userID, toStockID, tax_lot.ConvertReplace, entitled, settled, 1); err != nil {
+				return err
+			}
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO corporate_action_entitlements (corporate_action_id, user_id, stock_id, to_stock_id, quantity_before, average_price_before,
+			                                           record_quantity, entitled_quantity, fractional_quantity, fractional_policy, cash_in_lieu_amount,
+			                                           quantity_after, average_price_after)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		`, action.ID, position.userID, action.StockID, toStockID, position.quantity, position.averagePrice,
+			position.recordQuantity, settled.entitled, settled.fraction, action.FractionalPolicy, settled.cashInLieu,
+			quantityAfter, averagePriceAfter)
+		if err != nil {
+			logrus.Errorf("Failed to record entitlement for user %d: %v", position.userID, err)
+		}
+		return err
+	}, nil
+}
+
+// creditHolding adds settled units of stockID to a user's holding at a
+// weighted average price, posts the unit change and any cash in lieu.
+func creditHolding(tx *sql.Tx, action *CorporateAction, userID, stockID int, settled settledEntitlement) error {
+	if settled.quantity > 0 {
+		_, err := tx.Exec(`
+			INSERT INTO user_stock_holdings (user_id, stock_id, total_quantity, average_price)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, stock_id)
+			DO UPDATE SET
+				total_quantity = user_stock_holdings.total_quantity + EXCLUDED.total_quantity,
+				average_price = ((user_stock_holdings.total_quantity * user_stock_holdings.average_price) +
+				                (EXCLUDED.total_quantity * EXCLUDED.average_price)) /
+				                (user_stock_holdings.total_quantity + EXCLUDED.total_quantity),
+				updated_at = NOW()
+		`, userID, stockID, settled.quantity, settled.averagePrice)
+		if err != nil {
+			logrus.Errorf("Failed to credit target holding for user %d: %v", userID, err)
+			return err
+		}
+		if err = postUnitChange(tx, action, userID, stockID, settled.quantity); err != nil {
+			return err
+		}
+	}
+
+	return postCashInLieu(tx, action, userID, stockID, settled.cashInLieu)
+}
+
+// convertLots carries quantity converted units of the action's stock over
+// to the settled units of toStockID in the user's tax lots.
+func convertLots(tx *sql.Tx, action *CorporateAction, userID, toStockID int, kind string, quantity float64, settled settledEntitlement, costShare float64) error {
+	return tax_lot.Convert(tx, tax_lot.Conversion{
+		Kind:              kind,
+		UserID:            userID,
+		CorporateActionID: action.ID,
+		Source:            string(action.ActionType),
+		FromStockID:       action.StockID,
+		ToStockID:         toStockID,
+		Quantity:          quantity,
+		Entitled:          settled.entitled,
+		Settled:           settled.quantity,
+		CashInLieu:        settled.cashInLieu,
+		CostShare:         costShare,
+		At:                action.EffectiveDate,
+	})
+}
+
+func postCashInLieu(tx *sql.Tx, action *CorporateAction, userID, stockID int, amount float64) error {
+	if amount <= 0 {
+		return nil
+	}
+	_, err := tx.Exec(`
+		INSERT INTO ledger_entries (corporate_action_id, user_id, entry_type, account_type, stock_id, amount, description)
+		VALUES ($1, $2, 'DEBIT', 'CASH_IN_LIEU', $3, $4, 'Cash in lieu of fractional entitlement')
+	`, action.ID, userID, stockID, amount)
+	if err != nil {
+		logrus.Errorf("Failed to create cash in lieu ledger entry: %v", err)
+	}
+	return err
+}
+
+// postUnitChange records a change in units caused by a corporate action,
+// following the reward ledger's convention: units in are a DEBIT, units out
+// a CREDIT with a negative quantity.
+func postUnitChange(tx *sql.Tx, action *CorporateAction, userID, stockID int, delta float64) error {
+	delta = roundQuantity(delta)
+	if delta == 0 {
+		return nil
+	}
+
+	entryType := "DEBIT"
+	if delta < 0 {
+		entryType = "CREDIT"
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO ledger_entries (corporate_action_id, user_id, entry_type, account_type, stock_id, quantity, description)
+		VALUES ($1, $2, $3, 'STOCK_UNITS', $4, $5, $6)
+	`, action.ID, userID, entryType, stockID, delta, string(action.ActionType)+" unit adjustment")
+	if err != nil {
+		logrus.Errorf("Failed to create unit adjustment ledger entry: %v", err)
+	}
+	return err
+}
+
+func (s *CorporateActionService) GetEntitlements(actionID int) (*EntitlementReport, error) {
+	report := &EntitlementReport{CorporateActionID: actionID, Entitlements: []UserEntitlement{}}
+
+	var cashInLieuPrice, exitPrice sql.NullFloat64
+	err := s.db.QueryRow(`
+		SELECT s.symbol, ca.action_type, ca.status, ca.fractional_policy, ca.cash_in_lieu_price,
+		       COALESCE(ca.delisting_mode, ''), ca.exit_price
+		FROM corporate_actions ca
+		JOIN stocks s ON ca.stock_id = s.id
+		WHERE ca.id = $1
+	`, actionID).Scan(&report.StockSymbol, &report.ActionType, &report.Status, &report.FractionalPolicy, &cashInLieuPrice,
+		&report.DelistingMode, &exitPrice)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeCorporateActionNotFound, "corporate action not found")
+	}
+	if err != nil {
+		logrus.Errorf("Failed to fetch corporate action: %v", err)
+		return nil, err
+	}
+	report.CashInLieuPrice = cashInLieuPrice.Float64
+	report.ExitPrice = exitPrice.Float64
+
+	rows, err := s.db.Query(`
+		SELECT e.user_id, u.name, u.email, COALESCE(ts.symbol, ''),
+		       e.quantity_before, e.average_price_before, COALESCE(e.record_quantity, e.quantity_before),
+		       e.entitled_quantity, e.fractional_quantity,
+		       e.cash_in_lieu_amount, e.settlement_amount, e.realized_pnl, e.quantity_after, e.average_price_after,
+		       e.source_average_price_after
+		FROM corporate_action_entitlements e
+		JOIN users u ON e.user_id = u.id
+		LEFT JOIN stocks ts ON e.to_stock_id = ts.id
+		WHERE e.corporate_action_id = $1
+		ORDER BY e.user_id
+	`, actionID)
+	if err != nil {
+		logrus.Errorf("Failed to query entitlements: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entitlement UserEntitlement
+		err := rows.Scan(
+			&entitlement.UserID, &entitlement.UserName, &entitlement.UserEmail, &entitlement.ToStockSymbol,
+			&entitlement.QuantityBefore, &entitlement.AveragePriceBefore, &entitlement.RecordQuantity,
+			&entitlement.EntitledQuantity,
+			&entitlement.FractionalQuantity, &entitlement.CashInLieuAmount,
+			&entitlement.SettlementAmount, &entitlement.RealizedPnL,
+			&entitlement.QuantityAfter, &entitlement.AveragePriceAfter,
+			&entitlement.SourceAveragePriceAfter,
+		)
+		if err != nil {
+			logrus.Errorf("Failed to scan entitlement: %v", err)
+			return nil, err
+		}
+		report.TotalFractional += entitlement.FractionalQuantity
+		report.TotalCashInLieu += entitlement.CashInLieuAmount
+		report.TotalSettlement += entitlement.SettlementAmount
+		report.TotalRealizedPnL += entitlement.RealizedPnL
+		report.Entitlements = append(report.Entitlements, entitlement)
+	}
+	report.TotalUsers = len(report.Entitlements)
+	report.TotalFractional = roundQuantity(report.TotalFractional)
+	report.TotalCashInLieu = roundAmount(report.TotalCashInLieu)
+	report.TotalSettlement = roundAmount(report.TotalSettlement)
+	report.TotalRealizedPnL = roundAmount(report.TotalRealizedPnL)
+
+	return report, rows.Err()
+}
+
+const quantityEpsilon = 1e-9
+
+func roundQuantity(quantity float64) float64 {
+	return math.Round(quantity*1e6) / 1e6
+}
+
+func roundPrice(price float64) float64 {
+	return math.Round(price*1e4) / 1e4
+}
@@ -1,9 +1,11 @@
 package corporate_action
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
 
+	"stocky-backend/domain"
 	"stocky-backend/middleware"
 
 	"github.com/gin-gonic/gin"
@@ -11,11 +13,12 @@ import (
 )
 
 type CorporateActionHandler struct {
-	service *CorporateActionService
+	service   *CorporateActionService
+	scheduler *Scheduler
 }
 
-func NewCorporateActionHandler(service *CorporateActionService) *CorporateActionHandler {
-	return &CorporateActionHandler{service: service}
+func NewCorporateActionHandler(service *CorporateActionService, scheduler *Scheduler) *CorporateActionHandler {
+	return &CorporateActionHandler{service: service, scheduler: scheduler}
 }
 
 func (h *CorporateActionHandler) CreateCorporateAction(c *gin.Context) {
@@ -25,22 +28,15 @@ func (h *CorporateActionHandler) CreateCorporateAction(c *gin.Context) {
 		return
 	}
 
-	if req.ActionType == ActionStockSplit && req.SplitRatio <= 0 {
-		c.Error(middleware.BadRequestError("Invalid split ratio", "split_ratio is required and must be greater than 0 for stock split"))
+	if err := validateCorporateActionRequest(&req); err != nil {
+		c.Error(middleware.WrapServiceError(err, "Invalid corporate action"))
 		return
 	}
 
-	if req.ActionType == ActionMerger {
-		if req.MergerToSymbol == "" || req.MergerRatio <= 0 {
-			c.Error(middleware.BadRequestError("Invalid merger parameters", "merger_to_symbol and merger_ratio are required for merger"))
-			return
-		}
-	}
-
 	action, err := h.service.CreateCorporateAction(req)
 	if err != nil {
 		logrus.Errorf("Error creating corporate action: %v", err)
-		c.Error(middleware.InternalServerError("Failed to create corporate action", err.Error()))
+		c.Error(middleware.WrapServiceError(err, "Failed to create corporate action"))
 		return
 	}
 
@@ -57,9 +53,18 @@ func (h *CorporateActionHandler) ProcessCorporateAction(c *gin.Context) {
 		return
 	}
 
-	if err := h.service.ProcessCorporateAction(actionID); err != nil {
+	// The body is optional; it only names the operator for the status history.
+	var req ProcessCorporateActionRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+			return
+		}
+	}
+
+	if err := h.service.ProcessCorporateAction(actionID, req.ProcessedBy); err != nil {
 		logrus.Errorf("Error processing corporate action: %v", err)
-		c.Error(middleware.InternalServerError("Failed to process corporate action", err.Error()))
+		c.Error(middleware.WrapServiceError(err, "Failed to process corporate action"))
 		return
 	}
 
@@ -70,12 +75,274 @@ func (h *CorporateActionHandler) GetAllCorporateActions(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
 
-	response, err := h.service.GetAllCorporateActions(page, pageSize)
+	var filter CorporateActionFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.Error(middleware.BadRequestError("Invalid query parameters", err.Error()))
+		return
+	}
+
+	response, err := h.service.GetAllCorporateActions(filter, page, pageSize)
 	if err != nil {
 		logrus.Errorf("Error getting corporate actions: %v", err)
-		c.Error(middleware.InternalServerError("Failed to retrieve corporate actions", err.Error()))
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve corporate actions"))
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *CorporateActionHandler) GetCorporateAction(c *gin.Context) {
+	actionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid action ID", err.Error()))
+		return
+	}
+
+	detail, err := h.service.GetCorporateAction(actionID)
+	if err != nil {
+		logrus.Errorf("Error getting corporate action: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve corporate action"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": detail})
+}
+
+func (h *CorporateActionHandler) GetDividendPayouts(c *gin.Context) {
+	actionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid action ID", err.Error()))
+		return
+	}
+
+	report, err := h.service.GetDividendPayouts(actionID)
+	if err != nil {
+		logrus.Errorf("Error getting dividend payouts: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve dividend payouts"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+func (h *CorporateActionHandler) GetEntitlements(c *gin.Context) {
+	actionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid action ID", err.Error()))
+		return
+	}
+
+	report, err := h.service.GetEntitlements(actionID)
+	if err != nil {
+		logrus.Errorf("Error getting corporate action entitlements: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve corporate action entitlements"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
+
+func (h *CorporateActionHandler) PreviewCorporateAction(c *gin.Context) {
+	actionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid action ID", err.Error()))
+		return
+	}
+
+	format := c.DefaultQuery("format", PreviewFormatJSON)
+	if format != PreviewFormatJSON && format != PreviewFormatCSV {
+		c.Error(domain.Validation(domain.CodeInvalidExportFormat, "unsupported preview format %q: use json or csv", format))
+		return
+	}
+
+	preview, err := h.service.PreviewCorporateAction(actionID)
+	if err != nil {
+		logrus.Errorf("Error previewing corporate action: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to preview corporate action"))
+		return
+	}
+
+	if format == PreviewFormatJSON {
+		c.JSON(http.StatusOK, gin.H{"data": preview})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="corporate-action-%d-preview.csv"`, actionID))
+	c.Status(http.StatusOK)
+	if err := WritePreviewCSV(c.Writer, preview); err != nil {
+		logrus.Errorf("Error writing corporate action preview: %v", err)
+	}
+}
+
+func (h *CorporateActionHandler) ReverseCorporateAction(c *gin.Context) {
+	actionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid action ID", err.Error()))
+		return
+	}
+
+	var req ReverseCorporateActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	result, err := h.service.ReverseCorporateAction(actionID, req)
+	if err != nil {
+		logrus.Errorf("Error reversing corporate action: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to reverse corporate action"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Corporate action reversed successfully",
+		"data":    result,
+	})
+}
+
+func (h *CorporateActionHandler) RunScheduler(c *gin.Context) {
+	run, err := h.scheduler.RunOnce(c.Request.Context(), TriggerManual)
+	if err != nil {
+		logrus.Errorf("Error running corporate action scheduler: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to run corporate action scheduler"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Corporate action scheduler run completed",
+		"data":    run,
+	})
+}
+
+func (h *CorporateActionHandler) GetSchedulerRuns(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 10
+	}
+
+	response, err := h.scheduler.GetRuns(page, pageSize)
+	if err != nil {
+		logrus.Errorf("Error getting scheduler runs: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve scheduler runs"))
 		return
 	}
 
 	c.JSON(http.StatusOK, response)
 }
+
+func (h *CorporateActionHandler) GetSchedulerRun(c *gin.Context) {
+	runID, err := strconv.Atoi(c.Param("runId"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid run ID", err.Error()))
+		return
+	}
+
+	run, err := h.scheduler.GetRun(runID)
+	if err != nil {
+		logrus.Errorf("Error getting scheduler run: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve scheduler run"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": run})
+}
+
+func (h *CorporateActionHandler) UpdateCorporateAction(c *gin.Context) {
+	actionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid action ID", err.Error()))
+		return
+	}
+
+	var req UpdateCorporateActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	action, err := h.service.UpdateCorporateAction(actionID, req)
+	if err != nil {
+		logrus.Errorf("Error updating corporate action: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to update corporate action"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Corporate action updated successfully",
+		"data":    action,
+	})
+}
+
+func (h *CorporateActionHandler) ApproveCorporateAction(c *gin.Context) {
+	actionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid action ID", err.Error()))
+		return
+	}
+
+	var req ApproveCorporateActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	action, err := h.service.ApproveCorporateAction(actionID, req)
+	if err != nil {
+		logrus.Errorf("Error approving corporate action: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to approve corporate action"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Corporate action approved successfully",
+		"data":    action,
+	})
+}
+
+func (h *CorporateActionHandler) CancelCorporateAction(c *gin.Context) {
+	actionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid action ID", err.Error()))
+		return
+	}
+
+	var req CancelCorporateActionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	action, err := h.service.CancelCorporateAction(actionID, req)
+	if err != nil {
+		logrus.Errorf("Error cancelling corporate action: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to cancel corporate action"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Corporate action cancelled successfully",
+		"data":    action,
+	})
+}
+
+func (h *CorporateActionHandler) GetStatusHistory(c *gin.Context) {
+	actionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid action ID", err.Error()))
+		return
+	}
+
+	report, err := h.service.GetStatusHistory(actionID)
+	if err != nil {
+		logrus.Errorf("Error getting corporate action history: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve corporate action history"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
@@ -0,0 +1,316 @@
+package corporate_action
+
+import (
+	"database/sql"
+	"encoding/json"
+
+	"stocky-backend/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// An action moves PENDING -> APPROVED -> COMPLETED, and COMPLETED ->
+// REVERSED. PENDING and APPROVED actions can be edited, which sends them back
+// to PENDING for a fresh approval, or CANCELLED.
+
+type fieldChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// recordStatusChange appends a row to the action's history. fromStatus is
+// empty for creation; changes is nil unless the action was edited.
+func recordStatusChange(tx *sql.Tx, actionID int, fromStatus, toStatus, changedBy, reason string, changes map[string]fieldChange) error {
+	var changesJSON interface{}
+	if len(changes) > 0 {
+		encoded, err := json.Marshal(changes)
+		if err != nil {
+			return err
+		}
+		changesJSON = string(encoded)
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO corporate_action_status_history (corporate_action_id, from_status, to_status, changed_by, reason, changes)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`, actionID, nullString(fromStatus), toStatus, nullString(changedBy), nullString(reason), changesJSON)
+	if err != nil {
+		logrus.Errorf("Failed to record status history for corporate action %d: %v", actionID, err)
+	}
+	return err
+}
+
+func checkEditable(action *CorporateAction, verb string) error {
+	if action.Status == "PENDING" || action.Status == "APPROVED" {
+		return nil
+	}
+	return domain.Conflict(domain.CodeCorporateActionNotPending, "only pending or approved corporate actions can be %s; this one is %s", verb, action.Status).
+		WithMeta("status", action.Status)
+}
+
+func patchField[T comparable](changes map[string]fieldChange, name string, current *T, update *T) {
+	if update == nil || *update == *current {
+		return
+	}
+	changes[name] = fieldChange{From: *current, To: *update}
+	*current = *update
+}
+
+func (s *CorporateActionService) UpdateCorporateAction(actionID int, req UpdateCorporateActionRequest) (*CorporateActionResponse, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	action, err := loadCorporateAction(tx, actionID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkEditable(action, "edited"); err != nil {
+		return nil, err
+	}
+
+	current, err := getCorporateAction(tx, actionID)
+	if err != nil {
+		return nil, err
+	}
+
+	merged := CreateCorporateActionRequest{
+		StockSymbol:          current.StockSymbol,
+		ActionType:           current.ActionType,
+		SplitRatio:           current.SplitRatio,
+		MergerToSymbol:       current.MergerToSymbol,
+		MergerRatio:          current.MergerRatio,
+		RatioNumerator:       current.RatioNumerator,
+		RatioDenominator:     current.RatioDenominator,
+		SpinoffSymbol:        current.SpinoffSymbol,
+		CostApportionmentPct: current.CostApportionmentPct,
+		NewSymbol:            current.NewSymbol,
+		FractionalPolicy:     current.FractionalPolicy,
+		CashInLieuPrice:      current.CashInLieuPrice,
+		DelistingMode:        current.DelistingMode,
+		ExitPrice:            current.ExitPrice,
+		DividendPerShare:     current.DividendPerShare,
+		RecordDate:           current.RecordDate,
+		PaymentDate:          current.PaymentDate,
+		EffectiveDate:        current.EffectiveDate,
+		Description:          current.Description,
+	}
+
+	changes := make(map[string]fieldChange)
+	patchField(changes, "split_ratio", &merged.SplitRatio, req.SplitRatio)
+	patchField(changes, "merger_to_symbol", &merged.MergerToSymbol, req.MergerToSymbol)
+	patchField(changes, "merger_ratio", &merged.MergerRatio, req.MergerRatio)
+	patchField(changes, "ratio_numerator", &merged.RatioNumerator, req.RatioNumerator)
+	patchField(changes, "ratio_denominator", &merged.RatioDenominator, req.RatioDenominator)
+	patchField(changes, "spinoff_symbol", &merged.SpinoffSymbol, req.SpinoffSymbol)
+	patchField(changes, "cost_apportionment_pct", &merged.CostApportionmentPct, req.CostApportionmentPct)
+	patchField(changes, "new_symbol", &merged.NewSymbol, req.NewSymbol)
+	patchField(changes, "fractional_policy", &merged.FractionalPolicy, req.FractionalPolicy)
+	patchField(changes, "cash_in_lieu_price", &merged.CashInLieuPrice, req.CashInLieuPrice)
+	patchField(changes, "delisting_mode", &merged.DelistingMode, req.DelistingMode)
+	patchField(changes, "exit_price", &merged.ExitPrice, req.ExitPrice)
+	patchField(changes, "dividend_per_share", &merged.DividendPerShare, req.DividendPerShare)
+	patchField(changes, "record_date", &merged.RecordDate, req.RecordDate)
+	patchField(changes, "payment_date", &merged.PaymentDate, req.PaymentDate)
+	patchField(changes, "effective_date", &merged.EffectiveDate, req.EffectiveDate)
+	patchField(changes, "description", &merged.Description, req.Description)
+
+	// Defaulted dates keep following the date they were defaulted from, as
+	// on create: a dividend's effective date tracks its payment date, and
+	// other actions' record date tracks the effective date.
+	if merged.ActionType == ActionDividend && req.PaymentDate != nil && req.EffectiveDate == nil {
+		patchField(changes, "effective_date", &merged.EffectiveDate, req.PaymentDate)
+	}
+	if merged.ActionType != ActionDividend && req.EffectiveDate != nil && req.RecordDate == nil &&
+		(current.RecordDate == "" || current.RecordDate == current.EffectiveDate) {
+		patchField(changes, "record_date", &merged.RecordDate, req.EffectiveDate)
+	}
+
+	if len(changes) == 0 {
+		return nil, domain.Validation(domain.CodeInvalidCorporateAction, "update does not change any field")
+	}
+	if err = validateCorporateActionRequest(&merged); err != nil {
+		return nil, err
+	}
+
+	mergerToStockID, _, spinoffStockID, err := resolveTargetStocks(tx, &merged, action.StockID)
+	if err != nil {
+		return nil, err
+	}
+
+	if merged.ActionType == ActionSymbolChange {
+		if err = checkSymbolAvailable(tx, merged.NewSymbol, action.StockID); err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = tx.Exec(`
+		UPDATE corporate_actions
+		SET split_ratio = $2, merger_to_stock_id = $3, merger_ratio = $4,
+		    ratio_numerator = $5, ratio_denominator = $6, fractional_policy = $7, cash_in_lieu_price = $8,
+		    delisting_mode = $9, exit_price = $10,
+		    dividend_per_share = $11, record_date = $12, payment_date = $13, effective_date = $14, description = $15,
+		    spinoff_stock_id = $17, cost_apportionment_pct = $18, new_symbol = $19,
+		    status = 'PENDING', approved_by = NULL, approved_at = NULL,
+		    updated_by = $16, updated_at = NOW()
+		WHERE id = $1
+	`, actionID, nullFloat64(merged.SplitRatio), mergerToStockID, nullFloat64(merged.MergerRatio),
+		nullInt(merged.RatioNumerator), nullInt(merged.RatioDenominator), merged.FractionalPolicy, nullFloat64(merged.CashInLieuPrice),
+		nullString(string(merged.DelistingMode)), nullFloat64(merged.ExitPrice),
+		nullFloat64(merged.DividendPerShare), nullString(merged.RecordDate), nullString(merged.PaymentDate),
+		merged.EffectiveDate, merged.Description, req.UpdatedBy, spinoffStockID, nullFloat64(merged.CostApportionmentPct),
+		nullString(merged.NewSymbol))
+	if err != nil {
+		logrus.Errorf("Failed to update corporate action: %v", err)
+		return nil, err
+	}
+
+	if err = recordStatusChange(tx, actionID, action.Status, "PENDING", req.UpdatedBy, req.Reason, changes); err != nil {
+		return nil, err
+	}
+
+	updated, err := getCorporateAction(tx, actionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Corporate action %d updated by %s", actionID, req.UpdatedBy)
+	return updated, nil
+}
+
+// ApproveCorporateAction records the second operator's sign-off. The
+// approver must be neither the creator nor whoever last edited the action.
+func (s *CorporateActionService) ApproveCorporateAction(actionID int, req ApproveCorporateActionRequest) (*CorporateActionResponse, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	action, err := loadCorporateAction(tx, actionID)
+	if err != nil {
+		return nil, err
+	}
+	if action.Status != "PENDING" {
+		return nil, domain.Conflict(domain.CodeCorporateActionNotPending, "only pending corporate actions can be approved; this one is %s", action.Status).
+			WithMeta("status", action.Status)
+	}
+	if req.ApprovedBy == action.CreatedBy || req.ApprovedBy == action.UpdatedBy {
+		return nil, domain.BusinessRule(domain.CodeSameOperatorReview, "corporate action must be approved by a different operator than the one who created or last edited it")
+	}
+
+	_, err = tx.Exec(`
+		UPDATE corporate_actions
+		SET status = 'APPROVED', approved_by = $2, approved_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+	`, actionID, req.ApprovedBy)
+	if err != nil {
+		logrus.Errorf("Failed to approve corporate action: %v", err)
+		return nil, err
+	}
+
+	if err = recordStatusChange(tx, actionID, action.Status, "APPROVED", req.ApprovedBy, "", nil); err != nil {
+		return nil, err
+	}
+
+	approved, err := getCorporateAction(tx, actionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Corporate action %d approved by %s", actionID, req.ApprovedBy)
+	return approved, nil
+}
+
+func (s *CorporateActionService) CancelCorporateAction(actionID int, req CancelCorporateActionRequest) (*CorporateActionResponse, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	action, err := loadCorporateAction(tx, actionID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkEditable(action, "cancelled"); err != nil {
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE corporate_actions
+		SET status = 'CANCELLED', cancelled_by = $2, cancelled_at = NOW(), cancellation_reason = $3, updated_at = NOW()
+		WHERE id = $1
+	`, actionID, req.CancelledBy, req.Reason)
+	if err != nil {
+		logrus.Errorf("Failed to cancel corporate action: %v", err)
+		return nil, err
+	}
+
+	if err = recordStatusChange(tx, actionID, action.Status, "CANCELLED", req.CancelledBy, req.Reason, nil); err != nil {
+		return nil, err
+	}
+
+	cancelled, err := getCorporateAction(tx, actionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Corporate action %d cancelled by %s", actionID, req.CancelledBy)
+	return cancelled, nil
+}
+
+func (s *CorporateActionService) GetStatusHistory(actionID int) (*StatusHistoryReport, error) {
+	report := &StatusHistoryReport{CorporateActionID: actionID, History: []StatusHistoryEntry{}}
+
+	err := s.db.QueryRow(`SELECT status FROM corporate_actions WHERE id = $1`, actionID).Scan(&report.Status)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeCorporateActionNotFound, "corporate action not found")
+	}
+	if err != nil {
+		logrus.Errorf("Failed to fetch corporate action: %v", err)
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, COALESCE(from_status, ''), to_status, COALESCE(changed_by, ''), COALESCE(reason, ''), changes, created_at
+		FROM corporate_action_status_history
+		WHERE corporate_action_id = $1
+		ORDER BY created_at, id
+	`, actionID)
+	if err != nil {
+		logrus.Errorf("Failed to query corporate action history: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry StatusHistoryEntry
+		var changes []byte
+		err := rows.Scan(&entry.ID, &entry.FromStatus, &entry.ToStatus, &entry.ChangedBy, &entry.Reason, &changes, &entry.CreatedAt)
+		if err != nil {
+			logrus.Errorf("Failed to scan corporate action history: %v", err)
+			return nil, err
+		}
+		if len(changes) > 0 {
+			entry.Changes = json.RawMessage(changes)
+		}
+		report.History = append(report.History, entry)
+	}
+
+	return report, rows.Err()
+}
@@ -1,55 +1,228 @@
 package corporate_action
 
 import (
+	"encoding/json"
 	"time"
 )
 
 type CorporateActionType string
 
 const (
-	ActionStockSplit CorporateActionType = "STOCK_SPLIT"
-	ActionMerger     CorporateActionType = "MERGER"
-	ActionDelisting  CorporateActionType = "DELISTING"
+	ActionStockSplit   CorporateActionType = "STOCK_SPLIT"
+	ActionMerger       CorporateActionType = "MERGER"
+	ActionDelisting    CorporateActionType = "DELISTING"
+	ActionDividend     CorporateActionType = "DIVIDEND"
+	ActionBonus        CorporateActionType = "BONUS"
+	ActionReverseSplit CorporateActionType = "REVERSE_SPLIT"
+	ActionSpinoff      CorporateActionType = "SPINOFF"
+	ActionSymbolChange CorporateActionType = "SYMBOL_CHANGE"
+)
+
+// FractionalPolicy decides what happens to the part of a unit entitlement
+// below one whole share.
+type FractionalPolicy string
+
+const (
+	FractionalKeep          FractionalPolicy = "KEEP"
+	FractionalRoundDownCash FractionalPolicy = "ROUND_DOWN_CASH"
+	FractionalRoundHalfUp   FractionalPolicy = "ROUND_HALF_UP"
+)
+
+// DelistingMode decides what users receive for units of a delisted stock.
+type DelistingMode string
+
+const (
+	DelistingCashSettlement DelistingMode = "CASH_SETTLEMENT"
+	DelistingWriteOff       DelistingMode = "WRITE_OFF"
 )
 
 type CorporateAction struct {
-	ID             int                 `json:"id"`
-	StockID        int                 `json:"stock_id"`
-	StockSymbol    string              `json:"stock_symbol"`
-	ActionType     CorporateActionType `json:"action_type"`
-	SplitRatio     float64             `json:"split_ratio,omitempty"`
-	MergerToStockID int                `json:"merger_to_stock_id,omitempty"`
-	MergerRatio    float64             `json:"merger_ratio,omitempty"`
-	EffectiveDate  time.Time           `json:"effective_date"`
-	Status         string              `json:"status"`
-	Description    string              `json:"description"`
-	CreatedAt      time.Time           `json:"created_at"`
-	ProcessedAt    *time.Time          `json:"processed_at,omitempty"`
+	ID                   int                 `json:"id"`
+	StockID              int                 `json:"stock_id"`
+	StockSymbol          string              `json:"stock_symbol"`
+	ActionType           CorporateActionType `json:"action_type"`
+	SplitRatio           float64             `json:"split_ratio,omitempty"`
+	MergerToStockID      int                 `json:"merger_to_stock_id,omitempty"`
+	MergerRatio          float64             `json:"merger_ratio,omitempty"`
+	RatioNumerator       int                 `json:"ratio_numerator,omitempty"`
+	RatioDenominator     int                 `json:"ratio_denominator,omitempty"`
+	SpinoffStockID       int                 `json:"spinoff_stock_id,omitempty"`
+	CostApportionmentPct float64             `json:"cost_apportionment_pct,omitempty"`
+	NewSymbol            string              `json:"new_symbol,omitempty"`
+	FractionalPolicy     FractionalPolicy    `json:"fractional_policy"`
+	CashInLieuPrice      float64             `json:"cash_in_lieu_price,omitempty"`
+	DelistingMode        DelistingMode       `json:"delisting_mode,omitempty"`
+	ExitPrice            float64             `json:"exit_price,omitempty"`
+	DividendPerShare     float64             `json:"dividend_per_share,omitempty"`
+	RecordDate           *time.Time          `json:"record_date,omitempty"`
+	PaymentDate          *time.Time          `json:"payment_date,omitempty"`
+	EffectiveDate        time.Time           `json:"effective_date"`
+	Status               string              `json:"status"`
+	Description          string              `json:"description"`
+	CreatedBy            string              `json:"created_by,omitempty"`
+	UpdatedBy            string              `json:"updated_by,omitempty"`
+	CreatedAt            time.Time           `json:"created_at"`
+	ProcessedAt          *time.Time          `json:"processed_at,omitempty"`
 }
 
 type CreateCorporateActionRequest struct {
-	StockSymbol     string              `json:"stock_symbol" binding:"required"`
-	ActionType      CorporateActionType `json:"action_type" binding:"required"`
-	SplitRatio      float64             `json:"split_ratio,omitempty"`
-	MergerToSymbol  string              `json:"merger_to_symbol,omitempty"`
-	MergerRatio     float64             `json:"merger_ratio,omitempty"`
-	EffectiveDate   string              `json:"effective_date" binding:"required"`
-	Description     string              `json:"description"`
+	StockSymbol          string              `json:"stock_symbol" binding:"required"`
+	ActionType           CorporateActionType `json:"action_type" binding:"required"`
+	SplitRatio           float64             `json:"split_ratio,omitempty"`
+	MergerToSymbol       string              `json:"merger_to_symbol,omitempty"`
+	MergerRatio          float64             `json:"merger_ratio,omitempty"`
+	RatioNumerator       int                 `json:"ratio_numerator,omitempty"`
+	RatioDenominator     int                 `json:"ratio_denominator,omitempty"`
+	SpinoffSymbol        string              `json:"spinoff_symbol,omitempty"`
+	CostApportionmentPct float64             `json:"cost_apportionment_pct,omitempty"`
+	NewSymbol            string              `json:"new_symbol,omitempty"`
+	FractionalPolicy     FractionalPolicy    `json:"fractional_policy,omitempty"`
+	CashInLieuPrice      float64             `json:"cash_in_lieu_price,omitempty"`
+	DelistingMode        DelistingMode       `json:"delisting_mode,omitempty"`
+	ExitPrice            float64             `json:"exit_price,omitempty"`
+	DividendPerShare     float64             `json:"dividend_per_share,omitempty"`
+	RecordDate           string              `json:"record_date,omitempty"`
+	PaymentDate          string              `json:"payment_date,omitempty"`
+	EffectiveDate        string              `json:"effective_date"`
+	Description          string              `json:"description"`
+	CreatedBy            string              `json:"created_by" binding:"required"`
+}
+
+// UpdateCorporateActionRequest edits a PENDING or APPROVED action. Only the
+// fields present are changed; stock and action type are fixed, so a wrong
+// one means cancelling and creating a new action.
+type UpdateCorporateActionRequest struct {
+	SplitRatio           *float64          `json:"split_ratio"`
+	MergerToSymbol       *string           `json:"merger_to_symbol"`
+	MergerRatio          *float64          `json:"merger_ratio"`
+	RatioNumerator       *int              `json:"ratio_numerator"`
+	RatioDenominator     *int              `json:"ratio_denominator"`
+	SpinoffSymbol        *string           `json:"spinoff_symbol"`
+	CostApportionmentPct *float64          `json:"cost_apportionment_pct"`
+	NewSymbol            *string           `json:"new_symbol"`
+	FractionalPolicy     *FractionalPolicy `json:"fractional_policy"`
+	CashInLieuPrice      *float64          `json:"cash_in_lieu_price"`
+	DelistingMode        *DelistingMode    `json:"delisting_mode"`
+	ExitPrice            *float64          `json:"exit_price"`
+	DividendPerShare     *float64          `json:"dividend_per_share"`
+	RecordDate           *string           `json:"record_date"`
+	PaymentDate          *string           `json:"payment_date"`
+	EffectiveDate        *string           `json:"effective_date"`
+	Description          *string           `json:"description"`
+	UpdatedBy            string            `json:"updated_by" binding:"required"`
+	Reason               string            `json:"reason"`
+}
+
+type ApproveCorporateActionRequest struct {
+	ApprovedBy string `json:"approved_by" binding:"required"`
+}
+
+type CancelCorporateActionRequest struct {
+	CancelledBy string `json:"cancelled_by" binding:"required"`
+	Reason      string `json:"reason" binding:"required"`
+}
+
+type ProcessCorporateActionRequest struct {
+	ProcessedBy string `json:"processed_by"`
+}
+
+type StatusHistoryEntry struct {
+	ID         int             `json:"id"`
+	FromStatus string          `json:"from_status,omitempty"`
+	ToStatus   string          `json:"to_status"`
+	ChangedBy  string          `json:"changed_by,omitempty"`
+	Reason     string          `json:"reason,omitempty"`
+	Changes    json.RawMessage `json:"changes,omitempty"`
+	CreatedAt  time.Time       `json:"created_at"`
+}
+
+type StatusHistoryReport struct {
+	CorporateActionID int                  `json:"corporate_action_id"`
+	Status            string               `json:"status"`
+	History           []StatusHistoryEntry `json:"history"`
 }
 
 type CorporateActionResponse struct {
-	ID                int                 `json:"id"`
-	StockSymbol       string              `json:"stock_symbol"`
-	ActionType        CorporateActionType `json:"action_type"`
-	SplitRatio        float64             `json:"split_ratio,omitempty"`
-	MergerToSymbol    string              `json:"merger_to_symbol,omitempty"`
-	MergerRatio       float64             `json:"merger_ratio,omitempty"`
-	EffectiveDate     string              `json:"effective_date"`
-	Status            string              `json:"status"`
-	Description       string              `json:"description"`
-	AffectedUsers     int                 `json:"affected_users"`
-	CreatedAt         time.Time           `json:"created_at"`
-	ProcessedAt       *time.Time          `json:"processed_at,omitempty"`
+	ID                   int                 `json:"id"`
+	StockSymbol          string              `json:"stock_symbol"`
+	ActionType           CorporateActionType `json:"action_type"`
+	SplitRatio           float64             `json:"split_ratio,omitempty"`
+	MergerToSymbol       string              `json:"merger_to_symbol,omitempty"`
+	MergerRatio          float64             `json:"merger_ratio,omitempty"`
+	RatioNumerator       int                 `json:"ratio_numerator,omitempty"`
+	RatioDenominator     int                 `json:"ratio_denominator,omitempty"`
+	SpinoffSymbol        string              `json:"spinoff_symbol,omitempty"`
+	CostApportionmentPct float64             `json:"cost_apportionment_pct,omitempty"`
+	OldSymbol            string              `json:"old_symbol,omitempty"`
+	NewSymbol            string              `json:"new_symbol,omitempty"`
+	FractionalPolicy     FractionalPolicy    `json:"fractional_policy"`
+	CashInLieuPrice      float64             `json:"cash_in_lieu_price,omitempty"`
+	DelistingMode        DelistingMode       `json:"delisting_mode,omitempty"`
+	ExitPrice            float64             `json:"exit_price,omitempty"`
+	DividendPerShare     float64             `json:"dividend_per_share,omitempty"`
+	RecordDate           string              `json:"record_date,omitempty"`
+	PaymentDate          string              `json:"payment_date,omitempty"`
+	EffectiveDate        string              `json:"effective_date"`
+	Status               string              `json:"status"`
+	Description          string              `json:"description"`
+	AffectedUsers        int                 `json:"affected_users"`
+	CreatedBy            string              `json:"created_by,omitempty"`
+	ApprovedBy           string              `json:"approved_by,omitempty"`
+	ApprovedAt           *time.Time          `json:"approved_at,omitempty"`
+	CancelledBy          string              `json:"cancelled_by,omitempty"`
+	CancellationReason   string              `json:"cancellation_reason,omitempty"`
+	CreatedAt            time.Time           `json:"created_at"`
+	ProcessedAt          *time.Time          `json:"processed_at,omitempty"`
+}
+
+// CorporateActionFilter narrows GET /api/corporate-action. Dates are
+// inclusive calendar days.
+type CorporateActionFilter struct {
+	Symbol        string     `form:"symbol"`
+	ActionType    string     `form:"action_type"`
+	Status        string     `form:"status"`
+	EffectiveFrom *time.Time `form:"effective_from" time_format:"2006-01-02"`
+	EffectiveTo   *time.Time `form:"effective_to" time_format:"2006-01-02"`
+}
+
+// ProcessingResult is what processing an action did, captured in the same
+// transaction. For mergers and spin-offs UnitsAfter counts units of the
+// target or new stock.
+type ProcessingResult struct {
+	UsersAffected    int       `json:"users_affected"`
+	UnitsBefore      float64   `json:"units_before"`
+	UnitsAfter       float64   `json:"units_after"`
+	FractionalUnits  float64   `json:"fractional_units"`
+	CashAmount       float64   `json:"cash_amount"`
+	TDSAmount        float64   `json:"tds_amount"`
+	StockPriceBefore *float64  `json:"stock_price_before,omitempty"`
+	StockPriceAfter  *float64  `json:"stock_price_after,omitempty"`
+	CapturedAt       time.Time `json:"captured_at"`
+}
+
+// ProcessingProgress tracks a chunked run. LastUserID is the cursor: every
+// position up to and including that user has been applied.
+type ProcessingProgress struct {
+	TotalPositions     int        `json:"total_positions"`
+	ProcessedPositions int        `json:"processed_positions"`
+	ChunksProcessed    int        `json:"chunks_processed"`
+	LastUserID         int        `json:"last_user_id"`
+	StartedBy          string     `json:"started_by,omitempty"`
+	LastError          string     `json:"last_error,omitempty"`
+	StartedAt          time.Time  `json:"started_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
+	CompletedAt        *time.Time `json:"completed_at,omitempty"`
+}
+
+type CorporateActionDetail struct {
+	CorporateActionResponse
+	UpdatedBy          string              `json:"updated_by,omitempty"`
+	CancelledAt        *time.Time          `json:"cancelled_at,omitempty"`
+	ReversedBy         string              `json:"reversed_by,omitempty"`
+	ReversedAt         *time.Time          `json:"reversed_at,omitempty"`
+	ReversalReason     string              `json:"reversal_reason,omitempty"`
+	ProcessingProgress *ProcessingProgress `json:"processing_progress"`
+	ProcessingResult   *ProcessingResult   `json:"processing_result"`
 }
 
 type PaginatedCorporateActionsResponse struct {
@@ -59,3 +232,150 @@ type PaginatedCorporateActionsResponse struct {
 	TotalCount int                       `json:"total_count"`
 	TotalPages int                       `json:"total_pages"`
 }
+
+type DividendPayout struct {
+	UserID           int        `json:"user_id"`
+	UserName         string     `json:"user_name"`
+	UserEmail        string     `json:"user_email"`
+	RecordQuantity   float64    `json:"record_quantity"`
+	DividendPerShare float64    `json:"dividend_per_share"`
+	GrossAmount      float64    `json:"gross_amount"`
+	TDSRate          float64    `json:"tds_rate"`
+	TDSAmount        float64    `json:"tds_amount"`
+	NetAmount        float64    `json:"net_amount"`
+	PaidAt           *time.Time `json:"paid_at,omitempty"`
+}
+
+type DividendPayoutReport struct {
+	CorporateActionID int              `json:"corporate_action_id"`
+	StockSymbol       string           `json:"stock_symbol"`
+	DividendPerShare  float64          `json:"dividend_per_share"`
+	RecordDate        string           `json:"record_date"`
+	PaymentDate       string           `json:"payment_date"`
+	Status            string           `json:"status"`
+	Payouts           []DividendPayout `json:"payouts"`
+	TotalUsers        int              `json:"total_users"`
+	TotalGross        float64          `json:"total_gross"`
+	TotalTDS          float64          `json:"total_tds"`
+	TotalNet          float64          `json:"total_net"`
+}
+
+type UserEntitlement struct {
+	UserID                  int      `json:"user_id"`
+	UserName                string   `json:"user_name"`
+	UserEmail               string   `json:"user_email"`
+	ToStockSymbol           string   `json:"to_stock_symbol"`
+	QuantityBefore          float64  `json:"quantity_before"`
+	AveragePriceBefore      float64  `json:"average_price_before"`
+	RecordQuantity          float64  `json:"record_quantity"`
+	EntitledQuantity        float64  `json:"entitled_quantity"`
+	FractionalQuantity      float64  `json:"fractional_quantity"`
+	CashInLieuAmount        float64  `json:"cash_in_lieu_amount"`
+	SettlementAmount        float64  `json:"settlement_amount"`
+	RealizedPnL             float64  `json:"realized_pnl"`
+	QuantityAfter           float64  `json:"quantity_after"`
+	AveragePriceAfter       float64  `json:"average_price_after"`
+	SourceAveragePriceAfter *float64 `json:"source_average_price_after,omitempty"`
+}
+
+type EntitlementReport struct {
+	CorporateActionID int                 `json:"corporate_action_id"`
+	StockSymbol       string              `json:"stock_symbol"`
+	ActionType        CorporateActionType `json:"action_type"`
+	Status            string              `json:"status"`
+	FractionalPolicy  FractionalPolicy    `json:"fractional_policy"`
+	CashInLieuPrice   float64             `json:"cash_in_lieu_price,omitempty"`
+	DelistingMode     DelistingMode       `json:"delisting_mode,omitempty"`
+	ExitPrice         float64             `json:"exit_price,omitempty"`
+	Entitlements      []UserEntitlement   `json:"entitlements"`
+	TotalUsers        int                 `json:"total_users"`
+	TotalFractional   float64             `json:"total_fractional_quantity"`
+	TotalCashInLieu   float64             `json:"total_cash_in_lieu"`
+	TotalSettlement   float64             `json:"total_settlement"`
+	TotalRealizedPnL  float64             `json:"total_realized_pnl"`
+}
+
+const (
+	PreviewFormatJSON = "json"
+	PreviewFormatCSV  = "csv"
+)
+
+type PreviewRow struct {
+	UserID             int     `json:"user_id"`
+	UserEmail          string  `json:"user_email"`
+	StockSymbol        string  `json:"stock_symbol"`
+	ToStockSymbol      string  `json:"to_stock_symbol"`
+	QuantityBefore     float64 `json:"quantity_before"`
+	AveragePriceBefore float64 `json:"average_price_before"`
+	RecordQuantity     float64 `json:"record_quantity"`
+	EntitledQuantity   float64 `json:"entitled_quantity"`
+	FractionalQuantity float64 `json:"fractional_quantity"`
+	QuantityAfter      float64 `json:"quantity_after"`
+	AveragePriceAfter  float64 `json:"average_price_after"`
+	CashAmount         float64 `json:"cash_amount"`
+	TDSAmount          float64 `json:"tds_amount"`
+	RealizedPnL        float64 `json:"realized_pnl"`
+}
+
+type CorporateActionPreview struct {
+	CorporateActionID   int                 `json:"corporate_action_id"`
+	StockSymbol         string              `json:"stock_symbol"`
+	ActionType          CorporateActionType `json:"action_type"`
+	FractionalPolicy    FractionalPolicy    `json:"fractional_policy"`
+	StockPriceBefore    float64             `json:"stock_price_before"`
+	StockPriceAfter     float64             `json:"stock_price_after"`
+	Rows                []PreviewRow        `json:"rows"`
+	TotalUsers          int                 `json:"total_users"`
+	TotalQuantityBefore float64             `json:"total_quantity_before"`
+	TotalQuantityAfter  float64             `json:"total_quantity_after"`
+	TotalFractional     float64             `json:"total_fractional_quantity"`
+	TotalCash           float64             `json:"total_cash"`
+	TotalTDS            float64             `json:"total_tds"`
+	TotalRealizedPnL    float64             `json:"total_realized_pnl"`
+	GeneratedAt         time.Time           `json:"generated_at"`
+}
+
+type ReverseCorporateActionRequest struct {
+	ReversedBy string `json:"reversed_by" binding:"required"`
+	Reason     string `json:"reason" binding:"required"`
+}
+
+type ReversalResult struct {
+	CorporateActionID     int       `json:"corporate_action_id"`
+	Status                string    `json:"status"`
+	HoldingsRestored      int64     `json:"holdings_restored"`
+	LedgerEntriesReversed int64     `json:"ledger_entries_reversed"`
+	ReversedBy            string    `json:"reversed_by"`
+	ReversedAt            time.Time `json:"reversed_at"`
+}
+
+type SchedulerRunItem struct {
+	CorporateActionID int       `json:"corporate_action_id"`
+	Status            string    `json:"status"`
+	Attempts          int       `json:"attempts"`
+	Error             string    `json:"error,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type SchedulerRun struct {
+	ID               int                `json:"id"`
+	InstanceID       string             `json:"instance_id"`
+	TriggerType      string             `json:"trigger_type"`
+	Status           string             `json:"status"`
+	ActionsDue       int                `json:"actions_due"`
+	ActionsProcessed int                `json:"actions_processed"`
+	ActionsFailed    int                `json:"actions_failed"`
+	ActionsSkipped   int                `json:"actions_skipped"`
+	Error            string             `json:"error,omitempty"`
+	StartedAt        time.Time          `json:"started_at"`
+	FinishedAt       *time.Time         `json:"finished_at"`
+	Items            []SchedulerRunItem `json:"items,omitempty"`
+}
+
+type PaginatedSchedulerRunsResponse struct {
+	Data       []SchedulerRun `json:"data"`
+	Page       int            `json:"page"`
+	PageSize   int            `json:"page_size"`
+	TotalCount int            `json:"total_count"`
+	TotalPages int            `json:"total_pages"`
+}
@@ -0,0 +1,164 @@
+package corporate_action
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// PreviewCorporateAction runs the action exactly as processing would, reads
+// back the per-user outcome and the stock price change, then rolls the
+// transaction back so nothing is committed. Dividends are previewed even
+// before their payment date.
+func (s *CorporateActionService) PreviewCorporateAction(actionID int) (*CorporateActionPreview, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	action, err := loadCorporateAction(tx, actionID)
+	if err != nil {
+		return nil, err
+	}
+	if err = checkPending(action); err != nil {
+		return nil, err
+	}
+
+	preview := &CorporateActionPreview{
+		CorporateActionID: action.ID,
+		ActionType:        action.ActionType,
+		FractionalPolicy:  action.FractionalPolicy,
+		Rows:              []PreviewRow{},
+		GeneratedAt:       time.Now(),
+	}
+
+	err = tx.QueryRow(`SELECT symbol, current_price FROM stocks WHERE id = $1`, action.StockID).
+		Scan(&preview.StockSymbol, &preview.StockPriceBefore)
+	if err != nil {
+		logrus.Errorf("Failed to read stock for preview: %v", err)
+		return nil, err
+	}
+
+	if err = s.applyCorporateAction(tx, action); err != nil {
+		return nil, err
+	}
+
+	err = tx.QueryRow(`SELECT current_price FROM stocks WHERE id = $1`, action.StockID).Scan(&preview.StockPriceAfter)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT e.user_id, u.email, s.symbol, COALESCE(ts.symbol, s.symbol),
+		       e.quantity_before, e.average_price_before, e.entitled_quantity, e.fractional_quantity,
+		       e.quantity_after, e.average_price_after,
+		       e.cash_in_lieu_amount + e.settlement_amount, 0, e.realized_pnl
+		FROM corporate_action_entitlements e
+		JOIN users u ON e.user_id = u.id
+		JOIN stocks s ON e.stock_id = s.id
+		LEFT JOIN stocks ts ON e.to_stock_id = ts.id
+		WHERE e.corporate_action_id = $1
+		UNION ALL
+		SELECT de.user_id, u.email, s.symbol, s.symbol,
+		       de.record_quantity, COALESCE(h.average_price, 0), de.record_quantity, 0,
+		       de.record_quantity, COALESCE(h.average_price, 0),
+		       de.net_amount, de.tds_amount, 0
+		FROM dividend_entitlements de
+		JOIN users u ON de.user_id = u.id
+		JOIN stocks s ON de.stock_id = s.id
+		LEFT JOIN user_stock_holdings h ON h.user_id = de.user_id AND h.stock_id = de.stock_id
+		WHERE de.corporate_action_id = $1
+		ORDER BY 1
+	`, action.ID)
+	if err != nil {
+		logrus.Errorf("Failed to read preview results: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var row PreviewRow
+		err := rows.Scan(
+			&row.UserID, &row.UserEmail, &row.StockSymbol, &row.ToStockSymbol,
+			&row.QuantityBefore, &row.AveragePriceBefore, &row.EntitledQuantity, &row.FractionalQuantity,
+			&row.QuantityAfter, &row.AveragePriceAfter,
+			&row.CashAmount, &row.TDSAmount, &row.RealizedPnL,
+		)
+		if err != nil {
+			logrus.Errorf("Failed to scan preview row: %v", err)
+			return nil, err
+		}
+		preview.TotalQuantityBefore += row.QuantityBefore
+		preview.TotalQuantityAfter += row.QuantityAfter
+		preview.TotalFractional += row.FractionalQuantity
+		preview.TotalCash += row.CashAmount
+		preview.TotalTDS += row.TDSAmount
+		preview.TotalRealizedPnL += row.RealizedPnL
+		preview.Rows = append(preview.Rows, row)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	preview.TotalUsers = len(preview.Rows)
+	preview.TotalQuantityBefore = roundQuantity(preview.TotalQuantityBefore)
+	preview.TotalQuantityAfter = roundQuantity(preview.TotalQuantityAfter)
+	preview.TotalFractional = roundQuantity(preview.TotalFractional)
+	preview.TotalCash = roundAmount(preview.TotalCash)
+	preview.TotalTDS = roundAmount(preview.TotalTDS)
+	preview.TotalRealizedPnL = roundAmount(preview.TotalRealizedPnL)
+
+	return preview, nil
+}
+
+// WritePreviewCSV writes one line per user followed by a TOTAL line.
+func WritePreviewCSV(w io.Writer, preview *CorporateActionPreview) error {
+	writer := csv.NewWriter(w)
+
+	header := []string{
+		"user_id", "user_email", "stock_symbol", "to_stock_symbol",
+		"quantity_before", "average_price_before", "entitled_quantity", "fractional_quantity",
+		"quantity_after", "average_price_after", "cash_amount", "tds_amount", "realized_pnl",
+	}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, row := range preview.Rows {
+		record := []string{
+			strconv.Itoa(row.UserID), row.UserEmail, row.StockSymbol, row.ToStockSymbol,
+			formatQuantity(row.QuantityBefore), formatAmount(row.AveragePriceBefore),
+			formatQuantity(row.EntitledQuantity), formatQuantity(row.FractionalQuantity),
+			formatQuantity(row.QuantityAfter), formatAmount(row.AveragePriceAfter),
+			formatAmount(row.CashAmount), formatAmount(row.TDSAmount), formatAmount(row.RealizedPnL),
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+
+	total := []string{
+		"TOTAL", strconv.Itoa(preview.TotalUsers), preview.StockSymbol, "",
+		formatQuantity(preview.TotalQuantityBefore), "", "", formatQuantity(preview.TotalFractional),
+		formatQuantity(preview.TotalQuantityAfter), "",
+		formatAmount(preview.TotalCash), formatAmount(preview.TotalTDS), formatAmount(preview.TotalRealizedPnL),
+	}
+	if err := writer.Write(total); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func formatQuantity(quantity float64) string {
+	return strconv.FormatFloat(quantity, 'f', 6, 64)
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 4, 64)
+}
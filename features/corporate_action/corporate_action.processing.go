@@ -0,0 +1,318 @@
+package corporate_action
+
+import (
+	"database/sql"
+
+	"stocky-backend/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// positionStep applies an action to one user's record-date position.
+type positionStep func(tx *sql.Tx, position heldPosition) error
+
+// actionSteps is an action's effects split so that positions can be applied
+// in chunks: apply runs once per record-date position, finish once for the
+// stock after every position is done. Either may be nil.
+type actionSteps struct {
+	apply  positionStep
+	finish func(tx *sql.Tx) error
+}
+
+func (s *CorporateActionService) chunkSize() int {
+	if s.config.ProcessingChunkSize < 1 {
+		return 1
+	}
+	return s.config.ProcessingChunkSize
+}
+
+// applyChunk applies up to limit snapshotted positions after afterUserID and
+// returns how many it applied and the last user it reached.
+func applyChunk(tx *sql.Tx, action *CorporateAction, steps *actionSteps, afterUserID, limit int) (int, int, error) {
+	positions, err := recordPositions(tx, action.ID, afterUserID, limit)
+	if err != nil {
+		return 0, afterUserID, err
+	}
+
+	for _, position := range positions {
+		if err = steps.apply(tx, position); err != nil {
+			return 0, afterUserID, err
+		}
+	}
+
+	if len(positions) == 0 {
+		return 0, afterUserID, nil
+	}
+	return len(positions), positions[len(positions)-1].userID, nil
+}
+
+// startProcessing takes the consistent snapshot a chunked run works from
+// (record-date holdings, and the holdings and stock state reversal restores)
+// and moves the action to PROCESSING, which keeps rewards on its stock
+// blocked until the switchover. An action already PROCESSING is left as it
+// is so the run resumes.
+func (s *CorporateActionService) startProcessing(actionID int, processedBy string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	action, err := loadCorporateAction(tx, actionID)
+	if err != nil {
+		return err
+	}
+	if action.Status == "PROCESSING" {
+		logrus.Infof("Resuming corporate action %d", actionID)
+		return nil
+	}
+
+	if err = checkApproved(action); err != nil {
+		return err
+	}
+
+	if action.ActionType == ActionDividend {
+		if err = checkDividendPayable(tx, action); err != nil {
+			return err
+		}
+	}
+
+	steps, err := s.actionSteps(action)
+	if err != nil {
+		return err
+	}
+
+	if err = checkNoRunInProgress(tx, action); err != nil {
+		return err
+	}
+
+	if err = snapshotCorporateAction(tx, action); err != nil {
+		return err
+	}
+
+	var totalPositions int
+	if steps.apply != nil {
+		if err = snapshotRecordHoldings(tx, action); err != nil {
+			return err
+		}
+		err = tx.QueryRow(`SELECT COUNT(*) FROM corporate_action_record_holdings WHERE corporate_action_id = $1`, actionID).Scan(&totalPositions)
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO corporate_action_processing_progress (corporate_action_id, total_positions, started_by)
+		VALUES ($1, $2, $3)
+	`, actionID, totalPositions, nullString(processedBy))
+	if err != nil {
+		logrus.Errorf("Failed to record processing progress for corporate action %d: %v", actionID, err)
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE corporate_actions SET status = 'PROCESSING', updated_at = NOW() WHERE id = $1`, actionID)
+	if err != nil {
+		return err
+	}
+
+	if err = recordStatusChange(tx, actionID, action.Status, "PROCESSING", processedBy, "", nil); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	logrus.Infof("Corporate action %d started processing: %d positions", actionID, totalPositions)
+	return nil
+}
+
+// checkNoRunInProgress refuses to start while another action on the same
+// stocks is part-way through its chunks; the two would interleave.
+func checkNoRunInProgress(tx *sql.Tx, action *CorporateAction) error {
+	from, to := affectedStocks(action)
+
+	var otherID int
+	err := tx.QueryRow(`
+		SELECT id FROM corporate_actions
+		WHERE id <> $1 AND status = 'PROCESSING'
+		AND (stock_id IN ($2, $3) OR merger_to_stock_id IN ($2, $3) OR spinoff_stock_id IN ($2, $3))
+		LIMIT 1
+	`, action.ID, from, to).Scan(&otherID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return domain.Conflict(domain.CodeCorporateActionInProgress, "corporate action %d on the same stock is still processing", otherID).
+		WithMeta("corporate_action_id", otherID)
+}
+
+// processChunk applies the next chunk of positions in its own transaction
+// and advances the cursor in the same commit, so a failure only loses the
+// chunk in flight. Locking the action row keeps concurrent callers from
+// applying the same chunk twice. It reports whether a chunk was applied.
+func (s *CorporateActionService) processChunk(actionID int) (bool, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	action, err := loadCorporateAction(tx, actionID)
+	if err != nil {
+		return false, err
+	}
+	if err = checkProcessing(action); err != nil {
+		return false, err
+	}
+
+	steps, err := s.actionSteps(action)
+	if err != nil {
+		return false, err
+	}
+	if steps.apply == nil {
+		return false, nil
+	}
+
+	var afterUserID int
+	err = tx.QueryRow(`
+		SELECT last_user_id FROM corporate_action_processing_progress WHERE corporate_action_id = $1
+	`, actionID).Scan(&afterUserID)
+	if err != nil {
+		logrus.Errorf("Failed to read processing progress for corporate action %d: %v", actionID, err)
+		return false, err
+	}
+
+	applied, lastUserID, err := applyChunk(tx, action, steps, afterUserID, s.chunkSize())
+	if err != nil {
+		return false, err
+	}
+	if applied == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`
+		UPDATE corporate_action_processing_progress
+		SET processed_positions = processed_positions + $2, chunks_processed = chunks_processed + 1,
+		    last_user_id = $3, last_error = NULL, updated_at = NOW()
+		WHERE corporate_action_id = $1
+	`, actionID, applied, lastUserID)
+	if err != nil {
+		logrus.Errorf("Failed to advance processing progress for corporate action %d: %v", actionID, err)
+		return false, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, err
+	}
+
+	logrus.Debugf("Corporate action %d: applied %d positions up to user %d", actionID, applied, lastUserID)
+	return true, nil
+}
+
+// finishProcessing is the switchover: with every position applied it runs
+// the stock-level step, captures the result and completes the action in one
+// short transaction.
+func (s *CorporateActionService) finishProcessing(actionID int, processedBy string) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	action, err := loadCorporateAction(tx, actionID)
+	if err != nil {
+		return err
+	}
+	if err = checkProcessing(action); err != nil {
+		return err
+	}
+
+	steps, err := s.actionSteps(action)
+	if err != nil {
+		return err
+	}
+
+	var remaining int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM corporate_action_record_holdings r
+		JOIN corporate_action_processing_progress p ON p.corporate_action_id = r.corporate_action_id
+		WHERE r.corporate_action_id = $1 AND r.user_id > p.last_user_id
+	`, actionID).Scan(&remaining)
+	if err != nil {
+		return err
+	}
+	if steps.apply != nil && remaining > 0 {
+		return domain.Conflict(domain.CodeCorporateActionInProgress, "corporate action %d still has %d positions to apply", actionID, remaining).
+			WithMeta("remaining_positions", remaining)
+	}
+
+	if steps.finish != nil {
+		if err = steps.finish(tx); err != nil {
+			return err
+		}
+	}
+
+	var priceBefore float64
+	err = tx.QueryRow(`
+		SELECT current_price FROM corporate_action_stock_snapshots WHERE corporate_action_id = $1 AND stock_id = $2
+	`, actionID, action.StockID).Scan(&priceBefore)
+	if err != nil {
+		return err
+	}
+
+	if err = recordProcessingResult(tx, action, priceBefore); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE corporate_actions SET status = 'COMPLETED', processed_at = NOW(), updated_at = NOW() WHERE id = $1`, actionID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE corporate_action_processing_progress
+		SET completed_at = NOW(), last_error = NULL, updated_at = NOW()
+		WHERE corporate_action_id = $1
+	`, actionID)
+	if err != nil {
+		return err
+	}
+
+	if err = recordStatusChange(tx, actionID, action.Status, "COMPLETED", processedBy, "", nil); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	logrus.Infof("Corporate action processed successfully: ID %d, Type %s", actionID, action.ActionType)
+	return nil
+}
+
+func checkProcessing(action *CorporateAction) error {
+	switch action.Status {
+	case "PROCESSING":
+		return nil
+	case "COMPLETED":
+		return domain.Conflict(domain.CodeCorporateActionDone, "corporate action already processed")
+	}
+	return domain.Conflict(domain.CodeCorporateActionNotPending, "corporate action is %s, not PROCESSING", action.Status).
+		WithMeta("status", action.Status)
+}
+
+// recordProcessingError keeps the last failure on the progress row; the
+// failed transaction has already rolled back, so this runs on its own.
+func (s *CorporateActionService) recordProcessingError(actionID int, cause error) {
+	_, err := s.db.Exec(`
+		UPDATE corporate_action_processing_progress
+		SET last_error = $2, updated_at = NOW()
+		WHERE corporate_action_id = $1 AND completed_at IS NULL
+	`, actionID, cause.Error())
+	if err != nil {
+		logrus.Errorf("Failed to record processing error for corporate action %d: %v", actionID, err)
+	}
+}
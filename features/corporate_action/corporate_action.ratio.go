@@ -0,0 +1,57 @@
+package corporate_action
+
+import (
+	"database/sql"
+
+	"stocky-backend/domain"
+)
+
+// unitFactor turns a BONUS or REVERSE_SPLIT ratio pair into the multiplier
+// applied to each holding, kept as an integer fraction so the ratio is exact.
+//
+//	BONUS a:b          a bonus shares for every b held  -> (a+b)/b
+//	REVERSE_SPLIT a:b  a old shares become b new shares -> b/a
+//	SPINOFF a:b        a new-entity shares per b held   -> a/b
+func unitFactor(action *CorporateAction) (int64, int64, error) {
+	a, b := int64(action.RatioNumerator), int64(action.RatioDenominator)
+	if a <= 0 || b <= 0 {
+		return 0, 0, domain.Validation(domain.CodeInvalidCorporateAction, "%s %d requires a positive integer ratio", action.ActionType, action.ID)
+	}
+
+	switch action.ActionType {
+	case ActionBonus:
+		return a + b, b, nil
+	case ActionReverseSplit:
+		if a <= b {
+			return 0, 0, domain.Validation(domain.CodeInvalidCorporateAction, "reverse split ratio %d:%d must consolidate more shares than it issues", a, b)
+		}
+		return b, a, nil
+	case ActionSpinoff:
+		return a, b, nil
+	}
+	return 0, 0, domain.Validation(domain.CodeInvalidCorporateAction, "%s does not use a ratio pair", action.ActionType)
+}
+
+// ratioActionSteps applies a BONUS or REVERSE_SPLIT. Total cost is
+// preserved, so the average price moves inversely to the unit count.
+func ratioActionSteps(action *CorporateAction) (*actionSteps, error) {
+	num, den, err := unitFactor(action)
+	if err != nil {
+		return nil, err
+	}
+
+	apply, err := entitlementStep(action, action.StockID, float64(num)/float64(den))
+	if err != nil {
+		return nil, err
+	}
+
+	return &actionSteps{apply: apply, finish: func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE stocks
+			SET current_price = current_price * $1 / $2,
+			    updated_at = NOW()
+			WHERE id = $3
+		`, den, num, action.StockID)
+		return err
+	}}, nil
+}
@@ -0,0 +1,100 @@
+package corporate_action
+
+import (
+	"database/sql"
+	"math"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// recordDate is the date whose closing holdings an action applies to.
+// Actions created before every type carried one fall back to the effective
+// date.
+func recordDate(action *CorporateAction) time.Time {
+	if action.RecordDate != nil {
+		return *action.RecordDate
+	}
+	return action.EffectiveDate
+}
+
+// snapshotRecordHoldings locks every holding of the action's stock and
+// stores what each user held at the end of the record date, rebuilt by
+// rolling the current position back through later STOCK_UNITS movements.
+//
+// For dividends every later movement is rolled back, so the payout follows
+// the units actually held on the record date. Unit-changing actions skip
+// movements posted by other corporate actions, so the record quantity is
+// expressed in today's units (a split processed after the record date does
+// not shrink the entitlement).
+//
+// The rows are the consistent snapshot processing works from: positions are
+// read back with recordPositions rather than from the live holdings.
+func snapshotRecordHoldings(tx *sql.Tx, action *CorporateAction) error {
+	_, err := tx.Exec(`SELECT 1 FROM user_stock_holdings WHERE stock_id = $1 FOR UPDATE`, action.StockID)
+	if err != nil {
+		logrus.Errorf("Failed to lock holdings: %v", err)
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO corporate_action_record_holdings (corporate_action_id, user_id, stock_id, record_date,
+		                                              record_quantity, current_quantity, average_price)
+		SELECT $1, h.user_id, h.stock_id, $3::date,
+		       h.total_quantity - COALESCE((
+		           SELECT SUM(le.quantity) FROM ledger_entries le
+		           WHERE le.user_id = h.user_id AND le.stock_id = h.stock_id
+		           AND le.account_type = 'STOCK_UNITS'
+		           AND le.created_at >= $3::date + 1
+		           AND ($4 OR le.corporate_action_id IS NULL)
+		       ), 0),
+		       h.total_quantity, h.average_price
+		FROM user_stock_holdings h
+		WHERE h.stock_id = $2
+	`, action.ID, action.StockID, recordDate(action), action.ActionType == ActionDividend)
+	if err != nil {
+		logrus.Errorf("Failed to snapshot record date holdings for corporate action %d: %v", action.ID, err)
+	}
+	return err
+}
+
+// recordPositions reads up to limit snapshotted positions of users after
+// afterUserID, in user order.
+func recordPositions(tx *sql.Tx, actionID, afterUserID, limit int) ([]heldPosition, error) {
+	rows, err := tx.Query(`
+		SELECT r.user_id, r.record_quantity, r.current_quantity, r.average_price, u.pan IS NOT NULL
+		FROM corporate_action_record_holdings r
+		JOIN users u ON r.user_id = u.id
+		WHERE r.corporate_action_id = $1 AND r.user_id > $2
+		ORDER BY r.user_id
+		LIMIT $3
+	`, actionID, afterUserID, limit)
+	if err != nil {
+		logrus.Errorf("Failed to read record date holdings for corporate action %d: %v", actionID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var positions []heldPosition
+	for rows.Next() {
+		var position heldPosition
+		err := rows.Scan(&position.userID, &position.recordQuantity, &position.quantity, &position.averagePrice, &position.hasPAN)
+		if err != nil {
+			return nil, err
+		}
+		positions = append(positions, position)
+	}
+	return positions, rows.Err()
+}
+
+// entitledQuantity is the part of a position the action applies to. Units
+// acquired after the record date are not entitled and units given up since
+// (refunds) cannot be, so it is the record quantity capped at what is still
+// held. Mergers and delistings retire the stock, so they always take the
+// whole position.
+func entitledQuantity(action *CorporateAction, position heldPosition) float64 {
+	if action.ActionType == ActionMerger || action.ActionType == ActionDelisting {
+		return position.quantity
+	}
+	return math.Max(0, math.Min(position.recordQuantity, position.quantity))
+}
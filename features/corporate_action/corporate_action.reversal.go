@@ -0,0 +1,354 @@
+package corporate_action
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"stocky-backend/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+// affectedStocks returns the stocks whose holdings or state an action can
+// change. The pair is passed to IN ($n, $m) queries, so a single-stock action
+// repeats its own ID.
+func affectedStocks(action *CorporateAction) (int, int) {
+	if action.ActionType == ActionMerger && action.MergerToStockID != 0 {
+		return action.StockID, action.MergerToStockID
+	}
+	if action.ActionType == ActionSpinoff && action.SpinoffStockID != 0 {
+		return action.StockID, action.SpinoffStockID
+	}
+	return action.StockID, action.StockID
+}
+
+// snapshotCorporateAction records every holding, open tax lot and stock row
+// the action can touch, before it is applied.
+func snapshotCorporateAction(tx *sql.Tx, action *CorporateAction) error {
+	from, to := affectedStocks(action)
+
+	_, err := tx.Exec(`
+		INSERT INTO corporate_action_holding_snapshots (corporate_action_id, user_id, stock_id, total_quantity, average_price)
+		SELECT $1, user_id, stock_id, total_quantity, average_price
+		FROM user_stock_holdings
+		WHERE stock_id IN ($2, $3)
+	`, action.ID, from, to)
+	if err != nil {
+		logrus.Errorf("Failed to snapshot holdings for corporate action %d: %v", action.ID, err)
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO corporate_action_lot_snapshots (corporate_action_id, tax_lot_id, remaining_quantity, remaining_cost)
+		SELECT $1, id, remaining_quantity, remaining_cost
+		FROM tax_lots
+		WHERE stock_id IN ($2, $3) AND remaining_quantity > 0
+	`, action.ID, from, to)
+	if err != nil {
+		logrus.Errorf("Failed to snapshot tax lots for corporate action %d: %v", action.ID, err)
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE corporate_actions SET tax_lots_snapshotted = true WHERE id = $1`, action.ID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO corporate_action_stock_snapshots (corporate_action_id, stock_id, current_price, is_active, symbol)
+		SELECT $1, id, current_price, COALESCE(is_active, true), symbol
+		FROM stocks
+		WHERE id IN ($2, $3)
+	`, action.ID, from, to)
+	if err != nil {
+		logrus.Errorf("Failed to snapshot stocks for corporate action %d: %v", action.ID, err)
+	}
+	return err
+}
+
+// reversalBlockers explains why restoring the snapshot would overwrite
+// activity that happened after the action was processed.
+func reversalBlockers(tx *sql.Tx, action *CorporateAction) ([]string, error) {
+	from, to := affectedStocks(action)
+	var reasons []string
+
+	rows, err := tx.Query(`
+		SELECT id, action_type FROM corporate_actions
+		WHERE id <> $1 AND status = 'COMPLETED'
+		AND processed_at > (SELECT processed_at FROM corporate_actions WHERE id = $1)
+		AND (stock_id IN ($2, $3) OR merger_to_stock_id IN ($2, $3) OR spinoff_stock_id IN ($2, $3))
+		ORDER BY processed_at
+	`, action.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var laterID int
+		var laterType string
+		if err := rows.Scan(&laterID, &laterType); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		reasons = append(reasons, fmt.Sprintf("corporate action %d (%s) was processed on the same stock afterwards; reverse it first", laterID, laterType))
+	}
+	rows.Close()
+
+	// A dividend leaves units and prices alone, so only later corporate
+	// actions (which may have relied on its TDS aggregate) block it.
+	if action.ActionType == ActionDividend {
+		return reasons, nil
+	}
+
+	// A symbol change only needs its old symbol to be free again.
+	if action.ActionType == ActionSymbolChange {
+		var holder string
+		err = tx.QueryRow(`
+			SELECT s.symbol FROM stocks s
+			JOIN corporate_actions ca ON ca.id = $1
+			WHERE s.symbol = ca.old_symbol AND s.id <> ca.stock_id
+		`, action.ID).Scan(&holder)
+		if err == nil {
+			reasons = append(reasons, fmt.Sprintf("symbol %s has since been taken by another stock", holder))
+		} else if err != sql.ErrNoRows {
+			return nil, err
+		}
+		return reasons, nil
+	}
+
+	// The snapshot predates the switchover by the length of the chunked run,
+	// so movements count from when processing started.
+	var laterMovements int
+	err = tx.QueryRow(`
+		SELECT COUNT(*) FROM ledger_entries
+		WHERE stock_id IN ($2, $3) AND account_type = 'STOCK_UNITS'
+		AND created_at > COALESCE(
+		    (SELECT started_at FROM corporate_action_processing_progress WHERE corporate_action_id = $1),
+		    (SELECT processed_at FROM corporate_actions WHERE id = $1))
+		AND corporate_action_id IS DISTINCT FROM $1
+	`, action.ID, from, to).Scan(&laterMovements)
+	if err != nil {
+		return nil, err
+	}
+	if laterMovements > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d unit movements (rewards or adjustments) were posted on the stock after processing", laterMovements))
+	}
+
+	var lotsSnapshotted bool
+	err = tx.QueryRow(`SELECT tax_lots_snapshotted FROM corporate_actions WHERE id = $1`, action.ID).Scan(&lotsSnapshotted)
+	if err != nil {
+		return nil, err
+	}
+	if !lotsSnapshotted {
+		reasons = append(reasons, "it was processed before tax lots were tracked, so its effect on them cannot be undone")
+	}
+
+	rows, err = tx.Query(`
+		SELECT symbol FROM stocks
+		WHERE id IN ($2, $3)
+		AND updated_at > (SELECT processed_at FROM corporate_actions WHERE id = $1)
+	`, action.ID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var symbol string
+		if err := rows.Scan(&symbol); err != nil {
+			return nil, err
+		}
+		reasons = append(reasons, fmt.Sprintf("stock %s was updated after processing", symbol))
+	}
+
+	return reasons, rows.Err()
+}
+
+// ReverseCorporateAction undoes a processed action: holdings and stock state
+// go back to the snapshot taken at processing time, and every ledger entry
+// the action posted is mirrored by an opposite entry so the ledger nets to
+// zero while keeping the audit trail. Entitlement and payout rows stay as a
+// record of what was reversed. A symbol change only gets its old symbol back
+// and a dividend only has its payouts mirrored.
+func (s *CorporateActionService) ReverseCorporateAction(actionID int, req ReverseCorporateActionRequest) (*ReversalResult, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	action, err := loadCorporateAction(tx, actionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if action.Status != "COMPLETED" || action.ProcessedAt == nil {
+		return nil, domain.Conflict(domain.CodeCorporateActionNotReversible, "only processed corporate actions can be reversed; this one is %s", action.Status).
+			WithMeta("status", action.Status)
+	}
+
+	var snapshotted int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM corporate_action_stock_snapshots WHERE corporate_action_id = $1`, action.ID).Scan(&snapshotted)
+	if err != nil {
+		return nil, err
+	}
+	if snapshotted == 0 {
+		return nil, domain.BusinessRule(domain.CodeCorporateActionNotReversible, "corporate action %d was processed before snapshots were taken and cannot be reversed automatically", action.ID)
+	}
+
+	reasons, err := reversalBlockers(tx, action)
+	if err != nil {
+		logrus.Errorf("Failed to check reversal blockers: %v", err)
+		return nil, err
+	}
+	if len(reasons) > 0 {
+		return nil, domain.BusinessRule(domain.CodeCorporateActionNotReversible, "corporate action %d cannot be reversed cleanly: %s", action.ID, strings.Join(reasons, "; ")).
+			WithMeta("reasons", reasons)
+	}
+
+	// A dividend changed no units or prices, so there is nothing to restore:
+	// the snapshot would only roll back rewards and holders that came later.
+	var holdingsRestored int64
+	switch action.ActionType {
+	case ActionDividend:
+	case ActionSymbolChange:
+		err = restoreSymbol(tx, action)
+	default:
+		holdingsRestored, err = restoreSnapshot(tx, action)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := tx.Exec(`
+		INSERT INTO ledger_entries (corporate_action_id, user_id, entry_type, account_type, stock_id, quantity, amount, description)
+		SELECT corporate_action_id, user_id,
+		       CASE entry_type WHEN 'DEBIT' THEN 'CREDIT' ELSE 'DEBIT' END,
+		       account_type, stock_id, -quantity, amount,
+		       'Reversal: ' || COALESCE(description, '')
+		FROM ledger_entries
+		WHERE corporate_action_id = $1
+	`, action.ID)
+	if err != nil {
+		logrus.Errorf("Failed to post reversing ledger entries: %v", err)
+		return nil, err
+	}
+	ledgerReversed, _ := result.RowsAffected()
+
+	var reversedAt time.Time
+	err = tx.QueryRow(`
+		UPDATE corporate_actions
+		SET status = 'REVERSED', reversed_at = NOW(), reversed_by = $2, reversal_reason = $3, updated_at = NOW()
+		WHERE id = $1
+		RETURNING reversed_at
+	`, action.ID, req.ReversedBy, req.Reason).Scan(&reversedAt)
+	if err != nil {
+		logrus.Errorf("Failed to mark corporate action reversed: %v", err)
+		return nil, err
+	}
+
+	if err = recordStatusChange(tx, action.ID, action.Status, "REVERSED", req.ReversedBy, req.Reason, nil); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Corporate action %d reversed by %s", action.ID, req.ReversedBy)
+	return &ReversalResult{
+		CorporateActionID:     action.ID,
+		Status:                "REVERSED",
+		HoldingsRestored:      holdingsRestored,
+		LedgerEntriesReversed: ledgerReversed,
+		ReversedBy:            req.ReversedBy,
+		ReversedAt:            reversedAt,
+	}, nil
+}
+
+// restoreSnapshot puts holdings, tax lots and stock state back to the
+// snapshot taken when the action was processed, returning how many holdings
+// were restored.
+func restoreSnapshot(tx *sql.Tx, action *CorporateAction) (int64, error) {
+	from, to := affectedStocks(action)
+
+	result, err := tx.Exec(`
+		UPDATE user_stock_holdings h
+		SET total_quantity = snap.total_quantity,
+		    average_price = snap.average_price,
+		    updated_at = NOW()
+		FROM corporate_action_holding_snapshots snap
+		WHERE snap.corporate_action_id = $1
+		AND h.user_id = snap.user_id AND h.stock_id = snap.stock_id
+	`, action.ID)
+	if err != nil {
+		logrus.Errorf("Failed to restore holdings: %v", err)
+		return 0, err
+	}
+	holdingsRestored, _ := result.RowsAffected()
+
+	// Holdings the action created (e.g. a first position in a merger target)
+	// did not exist before it ran.
+	_, err = tx.Exec(`
+		UPDATE user_stock_holdings h
+		SET total_quantity = 0, average_price = 0, updated_at = NOW()
+		WHERE h.stock_id IN ($2, $3)
+		AND NOT EXISTS (
+			SELECT 1 FROM corporate_action_holding_snapshots snap
+			WHERE snap.corporate_action_id = $1 AND snap.user_id = h.user_id AND snap.stock_id = h.stock_id
+		)
+	`, action.ID, from, to)
+	if err != nil {
+		logrus.Errorf("Failed to clear holdings created by corporate action: %v", err)
+		return 0, err
+	}
+
+	// Lots the action opened are closed and the lots it consumed or
+	// converted get their remaining units and cost back. Its disposals stay
+	// on record but no longer count towards capital gains.
+	_, err = tx.Exec(`
+		UPDATE tax_lots
+		SET remaining_quantity = 0, remaining_cost = 0, updated_at = NOW()
+		WHERE corporate_action_id = $1
+	`, action.ID)
+	if err != nil {
+		logrus.Errorf("Failed to close tax lots opened by corporate action: %v", err)
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE tax_lots l
+		SET remaining_quantity = snap.remaining_quantity,
+		    remaining_cost = snap.remaining_cost,
+		    updated_at = NOW()
+		FROM corporate_action_lot_snapshots snap
+		WHERE snap.corporate_action_id = $1 AND l.id = snap.tax_lot_id
+	`, action.ID)
+	if err != nil {
+		logrus.Errorf("Failed to restore tax lots: %v", err)
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE tax_lot_disposals SET reversed_at = NOW()
+		WHERE corporate_action_id = $1 AND reversed_at IS NULL
+	`, action.ID)
+	if err != nil {
+		logrus.Errorf("Failed to mark disposals reversed: %v", err)
+		return 0, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE stocks s
+		SET current_price = snap.current_price,
+		    is_active = snap.is_active,
+		    updated_at = NOW()
+		FROM corporate_action_stock_snapshots snap
+		WHERE snap.corporate_action_id = $1 AND s.id = snap.stock_id
+	`, action.ID)
+	if err != nil {
+		logrus.Errorf("Failed to restore stocks: %v", err)
+		return 0, err
+	}
+	return holdingsRestored, nil
+}
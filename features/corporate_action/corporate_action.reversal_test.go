@@ -0,0 +1,161 @@
+package corporate_action
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"stocky-backend/config"
+	"stocky-backend/features/reward"
+
+	_ "github.com/lib/pq"
+)
+
+// testDB connects to the database named by TEST_DATABASE_URL, which must
+// have been migrated (make migrate). The tests add their own users and
+// stocks and leave them behind, so point it at a disposable database.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err = db.Ping(); err != nil {
+		t.Fatalf("ping database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+type positionState struct {
+	quantity     float64
+	averagePrice float64
+	openLots     int
+	lotQuantity  float64
+	lotCost      float64
+	stockPrice   float64
+}
+
+func readPosition(t *testing.T, db *sql.DB, userID, stockID int) positionState {
+	t.Helper()
+	var state positionState
+	err := db.QueryRow(`
+		SELECT h.total_quantity, h.average_price,
+		       (SELECT COUNT(*) FROM tax_lots l WHERE l.user_id = h.user_id AND l.stock_id = h.stock_id AND l.remaining_quantity > 0),
+		       (SELECT COALESCE(SUM(l.remaining_quantity), 0) FROM tax_lots l WHERE l.user_id = h.user_id AND l.stock_id = h.stock_id),
+		       (SELECT COALESCE(SUM(l.remaining_cost), 0) FROM tax_lots l WHERE l.user_id = h.user_id AND l.stock_id = h.stock_id),
+		       s.current_price
+		FROM user_stock_holdings h
+		JOIN stocks s ON s.id = h.stock_id
+		WHERE h.user_id = $1 AND h.stock_id = $2
+	`, userID, stockID).Scan(&state.quantity, &state.averagePrice, &state.openLots, &state.lotQuantity, &state.lotCost, &state.stockPrice)
+	if err != nil {
+		t.Fatalf("read position: %v", err)
+	}
+	return state
+}
+
+func TestReverseDividendKeepsLaterRewards(t *testing.T) {
+	db := testDB(t)
+	suffix := time.Now().UnixNano()
+
+	var userID int
+	err := db.QueryRow(`
+		INSERT INTO users (email, name, phone, is_active) VALUES ($1, 'Reversal Test', '9000000000', true) RETURNING id
+	`, fmt.Sprintf("reversal-test-%d@example.com", suffix)).Scan(&userID)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	symbol := fmt.Sprintf("REV%d", suffix%1000000000)
+	var stockID int
+	err = db.QueryRow(`
+		INSERT INTO stocks (symbol, name, exchange, current_price, is_active) VALUES ($1, 'Reversal Test Ltd', 'NSE', 100, true) RETURNING id
+	`, symbol).Scan(&stockID)
+	if err != nil {
+		t.Fatalf("create stock: %v", err)
+	}
+
+	rewards := reward.NewRewardService(db, &config.RewardConfig{KYCPolicy: config.KYCPolicyNone})
+	grant := func(quantity float64) {
+		t.Helper()
+		_, err := rewards.CreateReward(reward.CreateRewardRequest{
+			UserID:      userID,
+			StockSymbol: symbol,
+			Quantity:    quantity,
+			Force:       true,
+			RequestedBy: "reversal-test",
+		})
+		if err != nil {
+			t.Fatalf("create reward: %v", err)
+		}
+	}
+	grant(10)
+
+	service := NewCorporateActionService(db, &config.CorporateActionConfig{
+		DividendTDSRate:      0.10,
+		DividendTDSRateNoPAN: 0.20,
+		DividendTDSThreshold: 5000,
+		ProcessingChunkSize:  100,
+	})
+	today := time.Now()
+	dividend, err := service.CreateCorporateAction(CreateCorporateActionRequest{
+		StockSymbol:      symbol,
+		ActionType:       ActionDividend,
+		DividendPerShare: 5,
+		RecordDate:       today.AddDate(0, 0, -1).Format("2006-01-02"),
+		PaymentDate:      today.Format("2006-01-02"),
+		Description:      "reversal test dividend",
+		CreatedBy:        "reversal-maker",
+	})
+	if err != nil {
+		t.Fatalf("create dividend: %v", err)
+	}
+	if _, err = service.ApproveCorporateAction(dividend.ID, ApproveCorporateActionRequest{ApprovedBy: "reversal-checker"}); err != nil {
+		t.Fatalf("approve dividend: %v", err)
+	}
+	if err = service.ProcessCorporateAction(dividend.ID, "reversal-checker"); err != nil {
+		t.Fatalf("process dividend: %v", err)
+	}
+
+	grant(5)
+	before := readPosition(t, db, userID, stockID)
+
+	result, err := service.ReverseCorporateAction(dividend.ID, ReverseCorporateActionRequest{
+		ReversedBy: "reversal-checker",
+		Reason:     "reversal test",
+	})
+	if err != nil {
+		t.Fatalf("reverse dividend: %v", err)
+	}
+	if result.HoldingsRestored != 0 {
+		t.Errorf("holdings restored = %d, want 0", result.HoldingsRestored)
+	}
+
+	after := readPosition(t, db, userID, stockID)
+	if after != before {
+		t.Errorf("position after reversing the dividend = %+v, want %+v", after, before)
+	}
+	if after.quantity != 15 {
+		t.Errorf("holding = %v units, want 15", after.quantity)
+	}
+
+	var net float64
+	err = db.QueryRow(`
+		SELECT COALESCE(SUM(CASE entry_type WHEN 'DEBIT' THEN amount ELSE -amount END), 0)
+		FROM ledger_entries WHERE corporate_action_id = $1 AND account_type = 'DIVIDEND_CASH'
+	`, dividend.ID).Scan(&net)
+	if err != nil {
+		t.Fatalf("read dividend ledger: %v", err)
+	}
+	if net != 0 {
+		t.Errorf("dividend cash nets to %v after reversal, want 0", net)
+	}
+}
@@ -8,7 +8,19 @@ func RegisterRoutes(router *gin.RouterGroup, handler *CorporateActionHandler) {
 	corporateAction := router.Group("/corporate-action")
 	{
 		corporateAction.POST("", handler.CreateCorporateAction)
+		corporateAction.GET("/:id/preview", handler.PreviewCorporateAction)
+		corporateAction.PUT("/:id", handler.UpdateCorporateAction)
+		corporateAction.POST("/:id/approve", handler.ApproveCorporateAction)
+		corporateAction.POST("/:id/cancel", handler.CancelCorporateAction)
 		corporateAction.POST("/:id/process", handler.ProcessCorporateAction)
+		corporateAction.POST("/:id/reverse", handler.ReverseCorporateAction)
 		corporateAction.GET("", handler.GetAllCorporateActions)
+		corporateAction.GET("/:id", handler.GetCorporateAction)
+		corporateAction.GET("/:id/dividend-payouts", handler.GetDividendPayouts)
+		corporateAction.GET("/:id/entitlements", handler.GetEntitlements)
+		corporateAction.GET("/:id/history", handler.GetStatusHistory)
+		corporateAction.POST("/scheduler/run", handler.RunScheduler)
+		corporateAction.GET("/scheduler/runs", handler.GetSchedulerRuns)
+		corporateAction.GET("/scheduler/runs/:runId", handler.GetSchedulerRun)
 	}
 }
@@ -0,0 +1,385 @@
+package corporate_action
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"stocky-backend/config"
+	"stocky-backend/domain"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// schedulerLockKey is the Postgres advisory lock that elects the instance
+// allowed to run the scheduler. Any constant works as long as every instance
+// uses the same one.
+const schedulerLockKey int64 = 7_340_001
+
+const (
+	TriggerScheduled = "SCHEDULED"
+	TriggerManual    = "MANUAL"
+)
+
+// Scheduler processes approved corporate actions once their effective date
+// (and, for dividends, payment date) has arrived, so they stop blocking new
+// rewards without anyone calling the process endpoint.
+type Scheduler struct {
+	db         *sql.DB
+	service    *CorporateActionService
+	config     *config.CorporateActionConfig
+	instanceID string
+}
+
+func NewScheduler(db *sql.DB, service *CorporateActionService, cfg *config.CorporateActionConfig) *Scheduler {
+	hostname, _ := os.Hostname()
+	return &Scheduler{
+		db:         db,
+		service:    service,
+		config:     cfg,
+		instanceID: fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+	}
+}
+
+// Start runs the scheduler every configured interval until ctx is cancelled.
+// Every instance may start it; only the one holding the advisory lock does
+// any work on a given tick.
+func (s *Scheduler) Start(ctx context.Context) {
+	if !s.config.SchedulerEnabled {
+		logrus.Info("Corporate action scheduler disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(s.config.SchedulerInterval)
+		defer ticker.Stop()
+
+		logrus.Infof("Corporate action scheduler started on %s, interval %s", s.instanceID, s.config.SchedulerInterval)
+		for {
+			if _, err := s.RunOnce(ctx, TriggerScheduled); err != nil && !errors.Is(err, domain.ErrConflict) {
+				logrus.Errorf("Corporate action scheduler run failed: %v", err)
+			}
+
+			select {
+			case <-ctx.Done():
+				logrus.Info("Corporate action scheduler stopped")
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+type dueAction struct {
+	id            int
+	stockID       int
+	targetStockID sql.NullInt64 // merger target or spun-off stock
+}
+
+// RunOnce resumes actions left PROCESSING by an interrupted run, then
+// processes every due action in effective-date order. It returns a
+// SCHEDULER_BUSY conflict if another instance holds the lock. When an action
+// fails for good, later due actions on the same stocks are skipped so that
+// actions never apply out of order.
+func (s *Scheduler) RunOnce(ctx context.Context, trigger string) (*SchedulerRun, error) {
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err = conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, schedulerLockKey).Scan(&locked); err != nil {
+		return nil, err
+	}
+	if !locked {
+		logrus.Debug("Corporate action scheduler lock held by another instance, skipping run")
+		return nil, domain.Conflict(domain.CodeSchedulerBusy, "another scheduler run is in progress")
+	}
+	defer func() {
+		if _, err := conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1)`, schedulerLockKey); err != nil {
+			logrus.Errorf("Failed to release scheduler lock: %v", err)
+		}
+	}()
+
+	run := &SchedulerRun{InstanceID: s.instanceID, TriggerType: trigger, Status: "RUNNING"}
+	err = s.db.QueryRowContext(ctx, `
+		INSERT INTO corporate_action_scheduler_runs (instance_id, trigger_type)
+		VALUES ($1, $2)
+		RETURNING id, started_at
+	`, s.instanceID, trigger).Scan(&run.ID, &run.StartedAt)
+	if err != nil {
+		logrus.Errorf("Failed to record scheduler run: %v", err)
+		return nil, err
+	}
+
+	due, err := s.dueActions(ctx)
+	if err != nil {
+		run.Status = "FAILED"
+		run.Error = err.Error()
+		s.finishRun(run)
+		return run, err
+	}
+	run.ActionsDue = len(due)
+
+	blockedStocks := make(map[int]int)
+	for _, action := range due {
+		item := SchedulerRunItem{CorporateActionID: action.id}
+
+		if blockerID, blocked := blockedBy(blockedStocks, action); blocked {
+			item.Status = "SKIPPED"
+			item.Error = fmt.Sprintf("earlier corporate action %d on the same stock failed", blockerID)
+		} else {
+			item.Attempts, err = s.processWithRetry(ctx, action.id)
+			if err != nil {
+				item.Status = "FAILED"
+				item.Error = err.Error()
+				blockedStocks[action.stockID] = action.id
+				if action.targetStockID.Valid {
+					blockedStocks[int(action.targetStockID.Int64)] = action.id
+				}
+			} else {
+				item.Status = "PROCESSED"
+			}
+		}
+
+		switch item.Status {
+		case "PROCESSED":
+			run.ActionsProcessed++
+		case "FAILED":
+			run.ActionsFailed++
+		case "SKIPPED":
+			run.ActionsSkipped++
+		}
+		s.recordItem(run.ID, &item)
+		run.Items = append(run.Items, item)
+
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	switch {
+	case run.ActionsFailed == 0 && run.ActionsSkipped == 0:
+		run.Status = "SUCCEEDED"
+	case run.ActionsProcessed > 0:
+		run.Status = "PARTIAL"
+	default:
+		run.Status = "FAILED"
+	}
+	s.finishRun(run)
+
+	if run.ActionsDue > 0 {
+		logrus.Infof("Corporate action scheduler run %d: %d due, %d processed, %d failed, %d skipped",
+			run.ID, run.ActionsDue, run.ActionsProcessed, run.ActionsFailed, run.ActionsSkipped)
+	}
+	return run, nil
+}
+
+func (s *Scheduler) dueActions(ctx context.Context) ([]dueAction, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT id, stock_id, COALESCE(merger_to_stock_id, spinoff_stock_id)
+		FROM corporate_actions
+		WHERE status = 'PROCESSING'
+		OR (status = 'APPROVED'
+		    AND effective_date <= CURRENT_DATE
+		    AND (action_type <> 'DIVIDEND' OR payment_date <= CURRENT_DATE))
+		ORDER BY status = 'PROCESSING' DESC, effective_date, id
+	`)
+	if err != nil {
+		logrus.Errorf("Failed to query due corporate actions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []dueAction
+	for rows.Next() {
+		var action dueAction
+		if err := rows.Scan(&action.id, &action.stockID, &action.targetStockID); err != nil {
+			return nil, err
+		}
+		due = append(due, action)
+	}
+	return due, rows.Err()
+}
+
+func blockedBy(blockedStocks map[int]int, action dueAction) (int, bool) {
+	if id, ok := blockedStocks[action.stockID]; ok {
+		return id, true
+	}
+	if action.targetStockID.Valid {
+		if id, ok := blockedStocks[int(action.targetStockID.Int64)]; ok {
+			return id, true
+		}
+	}
+	return 0, false
+}
+
+// processWithRetry returns the number of attempts made and the last error.
+func (s *Scheduler) processWithRetry(ctx context.Context, actionID int) (int, error) {
+	maxAttempts := s.config.SchedulerMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	var err error
+	for attempt := 1; attempt <= maxAttempts; attempt++ {
+		err = s.service.ProcessCorporateAction(actionID, "scheduler:"+s.instanceID)
+		if err == nil || !isTransient(err) || attempt == maxAttempts {
+			return attempt, err
+		}
+
+		logrus.Warnf("Transient failure processing corporate action %d (attempt %d/%d): %v", actionID, attempt, maxAttempts, err)
+		select {
+		case <-ctx.Done():
+			return attempt, err
+		case <-time.After(s.config.SchedulerRetryBackoff * time.Duration(attempt)):
+		}
+	}
+	return maxAttempts, err
+}
+
+// isTransient reports whether retrying could succeed. Domain errors and data
+// errors are permanent; lost connections, serialization failures, deadlocks
+// and lock or resource exhaustion are worth another try.
+func isTransient(err error) bool {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code.Class() {
+		case "08", "40", "53", "55", "57":
+			return true
+		}
+		return false
+	}
+
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.Is(err, context.DeadlineExceeded)
+}
+
+func (s *Scheduler) recordItem(runID int, item *SchedulerRunItem) {
+	err := s.db.QueryRow(`
+		INSERT INTO corporate_action_scheduler_run_items (run_id, corporate_action_id, status, attempts, error)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING created_at
+	`, runID, item.CorporateActionID, item.Status, item.Attempts, nullString(item.Error)).Scan(&item.CreatedAt)
+	if err != nil {
+		logrus.Errorf("Failed to record scheduler run item for action %d: %v", item.CorporateActionID, err)
+	}
+}
+
+func (s *Scheduler) finishRun(run *SchedulerRun) {
+	err := s.db.QueryRow(`
+		UPDATE corporate_action_scheduler_runs
+		SET status = $2, actions_due = $3, actions_processed = $4, actions_failed = $5, actions_skipped = $6,
+		    error = $7, finished_at = NOW()
+		WHERE id = $1
+		RETURNING finished_at
+	`, run.ID, run.Status, run.ActionsDue, run.ActionsProcessed, run.ActionsFailed, run.ActionsSkipped,
+		nullString(run.Error)).Scan(&run.FinishedAt)
+	if err != nil {
+		logrus.Errorf("Failed to finish scheduler run %d: %v", run.ID, err)
+	}
+}
+
+const schedulerRunColumns = `
+	id, instance_id, trigger_type, status, actions_due, actions_processed, actions_failed, actions_skipped,
+	COALESCE(error, ''), started_at, finished_at
+`
+
+func scanSchedulerRun(row rowScanner, run *SchedulerRun) error {
+	return row.Scan(
+		&run.ID, &run.InstanceID, &run.TriggerType, &run.Status, &run.ActionsDue, &run.ActionsProcessed,
+		&run.ActionsFailed, &run.ActionsSkipped, &run.Error, &run.StartedAt, &run.FinishedAt,
+	)
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (s *Scheduler) GetRuns(page, pageSize int) (*PaginatedSchedulerRunsResponse, error) {
+	var totalCount int
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM corporate_action_scheduler_runs`).Scan(&totalCount); err != nil {
+		logrus.Errorf("Failed to count scheduler runs: %v", err)
+		return nil, err
+	}
+
+	offset := (page - 1) * pageSize
+	rows, err := s.db.Query(`
+		SELECT `+schedulerRunColumns+`
+		FROM corporate_action_scheduler_runs
+		ORDER BY started_at DESC, id DESC
+		LIMIT $1 OFFSET $2
+	`, pageSize, offset)
+	if err != nil {
+		logrus.Errorf("Failed to query scheduler runs: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := []SchedulerRun{}
+	for rows.Next() {
+		var run SchedulerRun
+		if err := scanSchedulerRun(rows, &run); err != nil {
+			logrus.Errorf("Failed to scan scheduler run: %v", err)
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	return &PaginatedSchedulerRunsResponse{
+		Data:       runs,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: totalCount,
+		TotalPages: (totalCount + pageSize - 1) / pageSize,
+	}, rows.Err()
+}
+
+func (s *Scheduler) GetRun(runID int) (*SchedulerRun, error) {
+	var run SchedulerRun
+	err := scanSchedulerRun(s.db.QueryRow(`
+		SELECT `+schedulerRunColumns+`
+		FROM corporate_action_scheduler_runs
+		WHERE id = $1
+	`, runID), &run)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeSchedulerRunNotFound, "scheduler run %d not found", runID)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to fetch scheduler run: %v", err)
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT corporate_action_id, status, attempts, COALESCE(error, ''), created_at
+		FROM corporate_action_scheduler_run_items
+		WHERE run_id = $1
+		ORDER BY id
+	`, runID)
+	if err != nil {
+		logrus.Errorf("Failed to query scheduler run items: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	run.Items = []SchedulerRunItem{}
+	for rows.Next() {
+		var item SchedulerRunItem
+		if err := rows.Scan(&item.CorporateActionID, &item.Status, &item.Attempts, &item.Error, &item.CreatedAt); err != nil {
+			return nil, err
+		}
+		run.Items = append(run.Items, item)
+	}
+
+	return &run, rows.Err()
+}
@@ -3,17 +3,22 @@ package corporate_action
 import (
 	"database/sql"
 	"fmt"
+	"strconv"
 	"time"
 
+	"stocky-backend/config"
+	"stocky-backend/domain"
+
 	"github.com/sirupsen/logrus"
 )
 
 type CorporateActionService struct {
-	db *sql.DB
+	db     *sql.DB
+	config *config.CorporateActionConfig
 }
 
-func NewCorporateActionService(db *sql.DB) *CorporateActionService {
-	return &CorporateActionService{db: db}
+func NewCorporateActionService(db *sql.DB, cfg *config.CorporateActionConfig) *CorporateActionService {
+	return &CorporateActionService{db: db, config: cfg}
 }
 
 func (s *CorporateActionService) CreateCorporateAction(req CreateCorporateActionRequest) (*CorporateActionResponse, error) {
@@ -24,37 +29,76 @@ func (s *CorporateActionService) CreateCorporateAction(req CreateCorporateAction
 	}
 	defer tx.Rollback()
 
-	var stockID int
-	err = tx.QueryRow(`SELECT id FROM stocks WHERE symbol = $1 AND is_active = true`, req.StockSymbol).Scan(&stockID)
+	ref, err := resolveActiveStock(tx, req.StockSymbol)
 	if err != nil {
-		return nil, fmt.Errorf("stock not found: %s", req.StockSymbol)
+		return nil, err
 	}
+	if ref == nil {
+		return nil, domain.NotFound(domain.CodeStockNotFound, "stock not found: %s", req.StockSymbol)
+	}
+	stockID := ref.ID
+	req.StockSymbol = ref.Symbol
 
-	var mergerToStockID *int
-	var mergerToSymbol string
-	if req.ActionType == ActionMerger && req.MergerToSymbol != "" {
-		var targetID int
-		err = tx.QueryRow(`SELECT id FROM stocks WHERE symbol = $1 AND is_active = true`, req.MergerToSymbol).Scan(&targetID)
-		if err != nil {
-			return nil, fmt.Errorf("merger target stock not found: %s", req.MergerToSymbol)
+	mergerToStockID, mergerToSymbol, spinoffStockID, err := resolveTargetStocks(tx, &req, stockID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.ActionType == ActionSymbolChange {
+		if req.NewSymbol == ref.Symbol {
+			return nil, domain.Validation(domain.CodeInvalidCorporateAction, "stock already trades as %s", req.NewSymbol)
 		}
-		mergerToStockID = &targetID
-		mergerToSymbol = req.MergerToSymbol
+		if err = checkSymbolAvailable(tx, req.NewSymbol, stockID); err != nil {
+			return nil, err
+		}
+	}
+
+	if req.FractionalPolicy == "" {
+		req.FractionalPolicy = FractionalKeep
+	}
+
+	if req.ActionType == ActionDelisting && req.DelistingMode == "" {
+		req.DelistingMode = DelistingWriteOff
+	}
+
+	// A dividend takes effect when it is paid, so it only becomes processable
+	// on its payment date.
+	if req.ActionType == ActionDividend && req.EffectiveDate == "" {
+		req.EffectiveDate = req.PaymentDate
+	}
+
+	// Other actions apply to holdings at the close of their effective date
+	// unless an earlier record date is given.
+	if req.ActionType != ActionDividend && req.RecordDate == "" {
+		req.RecordDate = req.EffectiveDate
 	}
 
 	var actionID int
 	var createdAt time.Time
 	err = tx.QueryRow(`
-		INSERT INTO corporate_actions (stock_id, action_type, split_ratio, merger_to_stock_id, merger_ratio, effective_date, description, status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, 'PENDING')
+		INSERT INTO corporate_actions (stock_id, action_type, split_ratio, merger_to_stock_id, merger_ratio,
+		                               ratio_numerator, ratio_denominator, spinoff_stock_id, cost_apportionment_pct,
+		                               fractional_policy, cash_in_lieu_price, delisting_mode, exit_price,
+		                               dividend_per_share, record_date, payment_date, effective_date, description, created_by,
+		                               new_symbol, status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, 'PENDING')
 		RETURNING id, created_at
-	`, stockID, req.ActionType, nullFloat64(req.SplitRatio), mergerToStockID, nullFloat64(req.MergerRatio), req.EffectiveDate, req.Description).Scan(&actionID, &createdAt)
-	
+	`, stockID, req.ActionType, nullFloat64(req.SplitRatio), mergerToStockID, nullFloat64(req.MergerRatio),
+		nullInt(req.RatioNumerator), nullInt(req.RatioDenominator), spinoffStockID, nullFloat64(req.CostApportionmentPct),
+		req.FractionalPolicy, nullFloat64(req.CashInLieuPrice),
+		nullString(string(req.DelistingMode)), nullFloat64(req.ExitPrice),
+		nullFloat64(req.DividendPerShare), nullString(req.RecordDate), nullString(req.PaymentDate),
+		req.EffectiveDate, req.Description, nullString(req.CreatedBy), nullString(req.NewSymbol)).Scan(&actionID, &createdAt)
+
 	if err != nil {
 		logrus.Errorf("Failed to create corporate action: %v", err)
 		return nil, err
 	}
 
+	if err = recordStatusChange(tx, actionID, "", "PENDING", req.CreatedBy, "", nil); err != nil {
+		return nil, err
+	}
+
 	var affectedUsers int
 	err = tx.QueryRow(`SELECT COUNT(DISTINCT user_id) FROM user_stock_holdings WHERE stock_id = $1 AND total_quantity > 0`, stockID).Scan(&affectedUsers)
 	if err != nil {
@@ -67,51 +111,91 @@ func (s *CorporateActionService) CreateCorporateAction(req CreateCorporateAction
 	}
 
 	return &CorporateActionResponse{
-		ID:             actionID,
-		StockSymbol:    req.StockSymbol,
-		ActionType:     req.ActionType,
-		SplitRatio:     req.SplitRatio,
-		MergerToSymbol: mergerToSymbol,
-		MergerRatio:    req.MergerRatio,
-		EffectiveDate:  req.EffectiveDate,
-		Status:         "PENDING",
-		Description:    req.Description,
-		AffectedUsers:  affectedUsers,
-		CreatedAt:      createdAt,
+		ID:                   actionID,
+		StockSymbol:          req.StockSymbol,
+		ActionType:           req.ActionType,
+		SplitRatio:           req.SplitRatio,
+		MergerToSymbol:       mergerToSymbol,
+		MergerRatio:          req.MergerRatio,
+		RatioNumerator:       req.RatioNumerator,
+		RatioDenominator:     req.RatioDenominator,
+		SpinoffSymbol:        req.SpinoffSymbol,
+		CostApportionmentPct: req.CostApportionmentPct,
+		NewSymbol:            req.NewSymbol,
+		FractionalPolicy:     req.FractionalPolicy,
+		CashInLieuPrice:      req.CashInLieuPrice,
+		DelistingMode:        req.DelistingMode,
+		ExitPrice:            req.ExitPrice,
+		DividendPerShare:     req.DividendPerShare,
+		RecordDate:           req.RecordDate,
+		PaymentDate:          req.PaymentDate,
+		EffectiveDate:        req.EffectiveDate,
+		Status:               "PENDING",
+		Description:          req.Description,
+		AffectedUsers:        affectedUsers,
+		CreatedBy:            req.CreatedBy,
+		CreatedAt:            createdAt,
 	}, nil
 }
 
-func (s *CorporateActionService) ProcessCorporateAction(actionID int) error {
-	tx, err := s.db.Begin()
-	if err != nil {
+// ProcessCorporateAction applies an APPROVED action in three phases: a
+// snapshot transaction that moves it to PROCESSING, one transaction per
+// chunk of holders, and a short switchover transaction that completes it.
+// Only the chunk being applied is locked, so rewards on other stocks are
+// never held up, and calling it again for a PROCESSING action resumes after
+// the last committed chunk. processedBy is recorded in the status history
+// and may be empty for unattended callers.
+func (s *CorporateActionService) ProcessCorporateAction(actionID int, processedBy string) error {
+	if err := s.startProcessing(actionID, processedBy); err != nil {
 		return err
 	}
-	defer tx.Rollback()
 
+	for {
+		more, err := s.processChunk(actionID)
+		if err != nil {
+			s.recordProcessingError(actionID, err)
+			return err
+		}
+		if !more {
+			break
+		}
+	}
+
+	if err := s.finishProcessing(actionID, processedBy); err != nil {
+		s.recordProcessingError(actionID, err)
+		return err
+	}
+	return nil
+}
+
+// loadCorporateAction reads and locks a corporate action for processing.
+func loadCorporateAction(tx *sql.Tx, actionID int) (*CorporateAction, error) {
 	var action CorporateAction
-	var splitRatio, mergerRatio sql.NullFloat64
-	var mergerToStockID sql.NullInt32
-	
-	var status string
-	err = tx.QueryRow(`
-		SELECT id, stock_id, action_type, split_ratio, merger_to_stock_id, merger_ratio, status
+	var splitRatio, mergerRatio, costApportionmentPct, cashInLieuPrice, exitPrice, dividendPerShare sql.NullFloat64
+	var mergerToStockID, ratioNumerator, ratioDenominator, spinoffStockID sql.NullInt32
+	var delistingMode, newSymbol sql.NullString
+	var recordDate, paymentDate sql.NullTime
+
+	err := tx.QueryRow(`
+		SELECT id, stock_id, action_type, split_ratio, merger_to_stock_id, merger_ratio,
+		       ratio_numerator, ratio_denominator, spinoff_stock_id, cost_apportionment_pct, fractional_policy, cash_in_lieu_price,
+		       delisting_mode, exit_price, dividend_per_share, record_date, payment_date, effective_date, status, processed_at,
+		       COALESCE(created_by, ''), COALESCE(updated_by, ''), new_symbol
 		FROM corporate_actions WHERE id = $1
-	`, actionID).Scan(&action.ID, &action.StockID, &action.ActionType, &splitRatio, &mergerToStockID, &mergerRatio, &status)
-	
+		FOR UPDATE
+	`, actionID).Scan(&action.ID, &action.StockID, &action.ActionType, &splitRatio, &mergerToStockID, &mergerRatio,
+		&ratioNumerator, &ratioDenominator, &spinoffStockID, &costApportionmentPct, &action.FractionalPolicy, &cashInLieuPrice,
+		&delistingMode, &exitPrice, &dividendPerShare, &recordDate, &paymentDate, &action.EffectiveDate, &action.Status, &action.ProcessedAt,
+		&action.CreatedBy, &action.UpdatedBy, &newSymbol)
+
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("corporate action not found")
+			return nil, domain.NotFound(domain.CodeCorporateActionNotFound, "corporate action not found")
 		}
 		logrus.Errorf("Failed to fetch corporate action: %v", err)
-		return fmt.Errorf("failed to fetch corporate action: %v", err)
-	}
-	
-	if status == "COMPLETED" {
-		return fmt.Errorf("corporate action already processed")
+		return nil, fmt.Errorf("failed to fetch corporate action: %v", err)
 	}
-	
-	action.Status = status
-	
+
 	if splitRatio.Valid {
 		action.SplitRatio = splitRatio.Float64
 	}
@@ -121,110 +205,163 @@ func (s *CorporateActionService) ProcessCorporateAction(actionID int) error {
 	if mergerToStockID.Valid {
 		action.MergerToStockID = int(mergerToStockID.Int32)
 	}
-
-	switch action.ActionType {
-	case ActionStockSplit:
-		err = s.processStockSplit(tx, action.StockID, action.SplitRatio)
-	case ActionMerger:
-		err = s.processMerger(tx, action.StockID, action.MergerToStockID, action.MergerRatio)
-	case ActionDelisting:
-		err = s.processDelisting(tx, action.StockID)
-	default:
-		return fmt.Errorf("unknown action type: %s", action.ActionType)
+	if ratioNumerator.Valid {
+		action.RatioNumerator = int(ratioNumerator.Int32)
 	}
-
-	if err != nil {
-		return err
+	if ratioDenominator.Valid {
+		action.RatioDenominator = int(ratioDenominator.Int32)
+	}
+	if spinoffStockID.Valid {
+		action.SpinoffStockID = int(spinoffStockID.Int32)
+	}
+	if costApportionmentPct.Valid {
+		action.CostApportionmentPct = costApportionmentPct.Float64
+	}
+	if newSymbol.Valid {
+		action.NewSymbol = newSymbol.String
+	}
+	if cashInLieuPrice.Valid {
+		action.CashInLieuPrice = cashInLieuPrice.Float64
+	}
+	if delistingMode.Valid {
+		action.DelistingMode = DelistingMode(delistingMode.String)
+	}
+	if exitPrice.Valid {
+		action.ExitPrice = exitPrice.Float64
+	}
+	if dividendPerShare.Valid {
+		action.DividendPerShare = dividendPerShare.Float64
+	}
+	if recordDate.Valid {
+		action.RecordDate = &recordDate.Time
+	}
+	if paymentDate.Valid {
+		action.PaymentDate = &paymentDate.Time
 	}
 
-	_, err = tx.Exec(`UPDATE corporate_actions SET status = 'COMPLETED', processed_at = NOW() WHERE id = $1`, actionID)
-	if err != nil {
-		return err
+	return &action, nil
+}
+
+// checkPending accepts actions that have not been processed or withdrawn,
+// whether or not they are approved yet.
+func checkPending(action *CorporateAction) error {
+	switch action.Status {
+	case "PENDING", "APPROVED":
+		return nil
+	case "PROCESSING":
+		return domain.Conflict(domain.CodeCorporateActionInProgress, "corporate action is being processed")
+	case "COMPLETED":
+		return domain.Conflict(domain.CodeCorporateActionDone, "corporate action already processed")
 	}
+	return domain.Conflict(domain.CodeCorporateActionNotPending, "corporate action is %s and cannot be processed", action.Status).
+		WithMeta("status", action.Status)
+}
 
-	if err = tx.Commit(); err != nil {
+func checkApproved(action *CorporateAction) error {
+	if err := checkPending(action); err != nil {
 		return err
 	}
-
-	logrus.Infof("Corporate action processed successfully: ID %d, Type %s", actionID, action.ActionType)
+	if action.Status != "APPROVED" {
+		return domain.BusinessRule(domain.CodeCorporateActionNotApproved, "corporate action must be approved by a second operator before it is processed")
+	}
 	return nil
 }
 
-func (s *CorporateActionService) processStockSplit(tx *sql.Tx, stockID int, splitRatio float64) error {
-	_, err := tx.Exec(`
-		UPDATE user_stock_holdings 
-		SET total_quantity = total_quantity * $1,
-		    average_price = average_price / $1,
-		    updated_at = NOW()
-		WHERE stock_id = $2 AND total_quantity > 0
-	`, splitRatio, stockID)
-
+// applyCorporateAction runs the action's effects inside tx in one go,
+// working through the record-date positions chunk by chunk exactly as a
+// chunked run does across transactions. It does not change the action's
+// status, so callers decide whether to commit.
+func (s *CorporateActionService) applyCorporateAction(tx *sql.Tx, action *CorporateAction) error {
+	steps, err := s.actionSteps(action)
 	if err != nil {
-		logrus.Errorf("Failed to process stock split: %v", err)
 		return err
 	}
 
-	_, err = tx.Exec(`
-		UPDATE stocks 
-		SET current_price = current_price / $1,
-		    updated_at = NOW()
-		WHERE id = $2
-	`, splitRatio, stockID)
+	if steps.apply != nil {
+		if err = snapshotRecordHoldings(tx, action); err != nil {
+			return err
+		}
+		afterUserID := 0
+		for {
+			applied, lastUserID, err := applyChunk(tx, action, steps, afterUserID, s.chunkSize())
+			if err != nil {
+				return err
+			}
+			if applied == 0 {
+				break
+			}
+			afterUserID = lastUserID
+		}
+	}
 
-	return err
+	if steps.finish != nil {
+		return steps.finish(tx)
+	}
+	return nil
 }
 
-func (s *CorporateActionService) processMerger(tx *sql.Tx, fromStockID int, toStockID int, mergerRatio float64) error {
-	_, err := tx.Exec(`
-		INSERT INTO user_stock_holdings (user_id, stock_id, total_quantity, average_price)
-		SELECT user_id, $2, total_quantity * $3, average_price / $3
-		FROM user_stock_holdings
-		WHERE stock_id = $1 AND total_quantity > 0
-		ON CONFLICT (user_id, stock_id) 
-		DO UPDATE SET 
-			total_quantity = user_stock_holdings.total_quantity + EXCLUDED.total_quantity,
-			average_price = ((user_stock_holdings.total_quantity * user_stock_holdings.average_price) + 
-			                (EXCLUDED.total_quantity * EXCLUDED.average_price)) / 
-			                (user_stock_holdings.total_quantity + EXCLUDED.total_quantity),
-			updated_at = NOW()
-	`, fromStockID, toStockID, mergerRatio)
-
-	if err != nil {
-		logrus.Errorf("Failed to process merger: %v", err)
-		return err
+// actionSteps validates the action's parameters and splits its effects into
+// a per-position step and a stock-level finish.
+func (s *CorporateActionService) actionSteps(action *CorporateAction) (*actionSteps, error) {
+	switch action.ActionType {
+	case ActionStockSplit:
+		return stockSplitSteps(action)
+	case ActionMerger:
+		return mergerSteps(action)
+	case ActionDelisting:
+		return delistingSteps(action)
+	case ActionDividend:
+		return s.dividendSteps(action)
+	case ActionBonus, ActionReverseSplit:
+		return ratioActionSteps(action)
+	case ActionSpinoff:
+		return spinoffSteps(action)
+	case ActionSymbolChange:
+		return &actionSteps{finish: func(tx *sql.Tx) error {
+			return processSymbolChange(tx, action)
+		}}, nil
 	}
+	return nil, domain.Validation(domain.CodeInvalidCorporateAction, "unknown action type: %s", action.ActionType)
+}
 
-	_, err = tx.Exec(`
-		UPDATE user_stock_holdings 
-		SET total_quantity = 0, updated_at = NOW()
-		WHERE stock_id = $1
-	`, fromStockID)
+func stockSplitSteps(action *CorporateAction) (*actionSteps, error) {
+	if action.SplitRatio <= 0 {
+		return nil, domain.Validation(domain.CodeInvalidCorporateAction, "stock split %d has no split ratio", action.ID)
+	}
 
+	apply, err := entitlementStep(action, action.StockID, action.SplitRatio)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	_, err = tx.Exec(`UPDATE stocks SET is_active = false, updated_at = NOW() WHERE id = $1`, fromStockID)
-	return err
+	return &actionSteps{apply: apply, finish: func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE stocks 
+			SET current_price = current_price / $1,
+			    updated_at = NOW()
+			WHERE id = $2
+		`, action.SplitRatio, action.StockID)
+		return err
+	}}, nil
 }
 
-func (s *CorporateActionService) processDelisting(tx *sql.Tx, stockID int) error {
-	_, err := tx.Exec(`
-		UPDATE user_stock_holdings 
-		SET total_quantity = 0, updated_at = NOW()
-		WHERE stock_id = $1 AND total_quantity > 0
-	`, stockID)
+func mergerSteps(action *CorporateAction) (*actionSteps, error) {
+	if action.MergerToStockID == 0 || action.MergerRatio <= 0 {
+		return nil, domain.Validation(domain.CodeInvalidCorporateAction, "merger %d has no target stock or ratio", action.ID)
+	}
 
+	apply, err := entitlementStep(action, action.MergerToStockID, action.MergerRatio)
 	if err != nil {
-		logrus.Errorf("Failed to process delisting: %v", err)
-		return err
+		return nil, err
 	}
 
-	_, err = tx.Exec(`UPDATE stocks SET is_active = false, updated_at = NOW() WHERE id = $1`, stockID)
-	return err
+	return &actionSteps{apply: apply, finish: func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE stocks SET is_active = false, updated_at = NOW() WHERE id = $1`, action.StockID)
+		return err
+	}}, nil
 }
 
-func (s *CorporateActionService) GetAllCorporateActions(page, pageSize int) (*PaginatedCorporateActionsResponse, error) {
+func (s *CorporateActionService) GetAllCorporateActions(filter CorporateActionFilter, page, pageSize int) (*PaginatedCorporateActionsResponse, error) {
 	if page < 1 {
 		page = 1
 	}
@@ -235,8 +372,13 @@ func (s *CorporateActionService) GetAllCorporateActions(page, pageSize int) (*Pa
 		pageSize = 100
 	}
 
+	where, args, err := buildCorporateActionFilter(s.db, filter)
+	if err != nil {
+		return nil, err
+	}
+
 	var totalCount int
-	err := s.db.QueryRow(`SELECT COUNT(*) FROM corporate_actions`).Scan(&totalCount)
+	err = s.db.QueryRow(`SELECT COUNT(*) FROM corporate_actions ca`+where, args...).Scan(&totalCount)
 	if err != nil {
 		logrus.Errorf("Failed to count corporate actions: %v", err)
 		return nil, err
@@ -244,52 +386,34 @@ func (s *CorporateActionService) GetAllCorporateActions(page, pageSize int) (*Pa
 
 	offset := (page - 1) * pageSize
 	totalPages := (totalCount + pageSize - 1) / pageSize
+	args = append(args, pageSize, offset)
 
-	query := `
-		SELECT 
-			ca.id, s.symbol, ca.action_type, ca.split_ratio, ca.merger_ratio,
-			COALESCE(s2.symbol, '') as merger_to_symbol,
-			TO_CHAR(ca.effective_date, 'YYYY-MM-DD') as effective_date,
-			ca.status, ca.description, ca.created_at, ca.processed_at,
-			(SELECT COUNT(DISTINCT user_id) FROM user_stock_holdings WHERE stock_id = ca.stock_id AND total_quantity > 0) as affected_users
-		FROM corporate_actions ca
-		JOIN stocks s ON ca.stock_id = s.id
-		LEFT JOIN stocks s2 ON ca.merger_to_stock_id = s2.id
+	rows, err := s.db.Query(`
+		SELECT `+corporateActionColumns+corporateActionJoins+where+`
 		ORDER BY ca.created_at DESC
-		LIMIT $1 OFFSET $2
-	`
-
-	rows, err := s.db.Query(query, pageSize, offset)
+		LIMIT $`+strconv.Itoa(len(args)-1)+` OFFSET $`+strconv.Itoa(len(args)), args...)
 	if err != nil {
 		logrus.Errorf("Failed to query corporate actions: %v", err)
 		return nil, err
 	}
 	defer rows.Close()
 
-	var actions []CorporateActionResponse
+	actions := []CorporateActionResponse{}
 	for rows.Next() {
 		var action CorporateActionResponse
-		var splitRatio, mergerRatio sql.NullFloat64
-		err := rows.Scan(
-			&action.ID, &action.StockSymbol, &action.ActionType,
-			&splitRatio, &mergerRatio, &action.MergerToSymbol,
-			&action.EffectiveDate, &action.Status, &action.Description,
-			&action.CreatedAt, &action.ProcessedAt, &action.AffectedUsers,
-		)
-		if err != nil {
+		if err := scanCorporateAction(rows, &action); err != nil {
 			logrus.Errorf("Failed to scan corporate action: %v", err)
 			return nil, err
 		}
-		
-		if splitRatio.Valid {
-			action.SplitRatio = splitRatio.Float64
-		}
-		if mergerRatio.Valid {
-			action.MergerRatio = mergerRatio.Float64
-		}
-		
 		actions = append(actions, action)
 	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err = fillCurrentHolders(s.db, actions); err != nil {
+		return nil, err
+	}
 
 	return &PaginatedCorporateActionsResponse{
 		Data:       actions,
@@ -300,9 +424,108 @@ func (s *CorporateActionService) GetAllCorporateActions(page, pageSize int) (*Pa
 	}, nil
 }
 
+const corporateActionColumns = `
+	ca.id, s.symbol, ca.action_type, ca.split_ratio, ca.merger_ratio,
+	COALESCE(s2.symbol, '') as merger_to_symbol,
+	COALESCE(ca.ratio_numerator, 0), COALESCE(ca.ratio_denominator, 0),
+	COALESCE(s3.symbol, '') as spinoff_symbol, ca.cost_apportionment_pct,
+	COALESCE(ca.old_symbol, ''), COALESCE(ca.new_symbol, ''),
+	ca.fractional_policy, ca.cash_in_lieu_price,
+	COALESCE(ca.delisting_mode, ''), ca.exit_price, ca.dividend_per_share,
+	COALESCE(TO_CHAR(ca.record_date, 'YYYY-MM-DD'), '') as record_date,
+	COALESCE(TO_CHAR(ca.payment_date, 'YYYY-MM-DD'), '') as payment_date,
+	TO_CHAR(ca.effective_date, 'YYYY-MM-DD') as effective_date,
+	ca.status, COALESCE(ca.description, ''),
+	COALESCE(ca.created_by, ''), COALESCE(ca.approved_by, ''), ca.approved_at,
+	COALESCE(ca.cancelled_by, ''), COALESCE(ca.cancellation_reason, ''),
+	ca.created_at, ca.processed_at,
+	COALESCE(r.users_affected, 0) as affected_users
+`
+
+// corporateActionJoins goes with corporateActionColumns. affected_users is
+// the count captured at processing time; unprocessed actions get their
+// stock's current holders from fillCurrentHolders.
+const corporateActionJoins = `
+	FROM corporate_actions ca
+	JOIN stocks s ON ca.stock_id = s.id
+	LEFT JOIN stocks s2 ON ca.merger_to_stock_id = s2.id
+	LEFT JOIN stocks s3 ON ca.spinoff_stock_id = s3.id
+	LEFT JOIN corporate_action_results r ON r.corporate_action_id = ca.id
+`
+
+func scanCorporateAction(row rowScanner, action *CorporateActionResponse) error {
+	var splitRatio, mergerRatio, costApportionmentPct, cashInLieuPrice, exitPrice, dividendPerShare sql.NullFloat64
+	err := row.Scan(
+		&action.ID, &action.StockSymbol, &action.ActionType,
+		&splitRatio, &mergerRatio, &action.MergerToSymbol,
+		&action.RatioNumerator, &action.RatioDenominator,
+		&action.SpinoffSymbol, &costApportionmentPct,
+		&action.OldSymbol, &action.NewSymbol,
+		&action.FractionalPolicy, &cashInLieuPrice,
+		&action.DelistingMode, &exitPrice, &dividendPerShare, &action.RecordDate, &action.PaymentDate,
+		&action.EffectiveDate, &action.Status, &action.Description,
+		&action.CreatedBy, &action.ApprovedBy, &action.ApprovedAt,
+		&action.CancelledBy, &action.CancellationReason,
+		&action.CreatedAt, &action.ProcessedAt, &action.AffectedUsers,
+	)
+	if err != nil {
+		return err
+	}
+
+	action.SplitRatio = splitRatio.Float64
+	action.MergerRatio = mergerRatio.Float64
+	action.CostApportionmentPct = costApportionmentPct.Float64
+	action.CashInLieuPrice = cashInLieuPrice.Float64
+	action.ExitPrice = exitPrice.Float64
+	action.DividendPerShare = dividendPerShare.Float64
+	return nil
+}
+
+// getCorporateAction reads one action in its API shape. q is the service's
+// db or an open transaction.
+func getCorporateAction(q queryer, actionID int) (*CorporateActionResponse, error) {
+	var action CorporateActionResponse
+	err := scanCorporateAction(q.QueryRow(`
+		SELECT `+corporateActionColumns+corporateActionJoins+`
+		WHERE ca.id = $1
+	`, actionID), &action)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeCorporateActionNotFound, "corporate action not found")
+	}
+	if err != nil {
+		logrus.Errorf("Failed to fetch corporate action: %v", err)
+		return nil, err
+	}
+
+	actions := []CorporateActionResponse{action}
+	if err = fillCurrentHolders(q, actions); err != nil {
+		return nil, err
+	}
+	return &actions[0], nil
+}
+
+type queryer interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
 func nullFloat64(f float64) *float64 {
 	if f == 0 {
 		return nil
 	}
 	return &f
 }
+
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func nullInt(i int) *int {
+	if i == 0 {
+		return nil
+	}
+	return &i
+}
@@ -0,0 +1,84 @@
+package corporate_action
+
+import (
+	"database/sql"
+
+	"stocky-backend/domain"
+	"stocky-backend/features/tax_lot"
+
+	"github.com/sirupsen/logrus"
+)
+
+// spinoffSteps credits units of the spun-off stock for every entitled
+// parent unit and moves cost_apportionment_pct percent of the entitled
+// units' cost basis with them. The parent holding keeps its units at a lower
+// average price, and the parent's market price drops by the same share.
+func spinoffSteps(action *CorporateAction) (*actionSteps, error) {
+	if action.SpinoffStockID == 0 || action.CostApportionmentPct <= 0 || action.CostApportionmentPct >= 100 {
+		return nil, domain.Validation(domain.CodeInvalidCorporateAction, "spin-off %d has no spin-off stock or cost apportionment", action.ID)
+	}
+	if err := validateFractionalPolicy(action); err != nil {
+		return nil, err
+	}
+
+	num, den, err := unitFactor(action)
+	if err != nil {
+		return nil, err
+	}
+	factor := float64(num) / float64(den)
+	apportioned := action.CostApportionmentPct / 100
+
+	apply := func(tx *sql.Tx, position heldPosition) error {
+		entitled := entitledQuantity(action, position)
+		if position.quantity <= 0 || entitled <= 0 {
+			return nil
+		}
+
+		// settleEntitlement spreads the cost it is given over the child
+		// units, so hand it the apportioned part of the parent's cost.
+		child := settleEntitlement(action, heldPosition{quantity: entitled, averagePrice: position.averagePrice * apportioned}, factor)
+
+		parentCost := position.quantity*position.averagePrice - entitled*position.averagePrice*apportioned
+		parentAveragePrice := roundPrice(parentCost / position.quantity)
+
+		_, err := tx.Exec(`
+			UPDATE user_stock_holdings
+			SET average_price = $1, updated_at = NOW()
+			WHERE user_id = $2 AND stock_id = $3
+		`, parentAveragePrice, position.userID, action.StockID)
+		if err != nil {
+			logrus.Errorf("Failed to apportion parent cost for user %d: %v", position.userID, err)
+			return err
+		}
+
+		if err = creditHolding(tx, action, position.userID, action.SpinoffStockID, child); err != nil {
+			return err
+		}
+		if err = convertLots(tx, action, position.userID, action.SpinoffStockID, tax_lot.ConvertDemerge, entitled, child, apportioned); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO corporate_action_entitlements (corporate_action_id, user_id, stock_id, to_stock_id, quantity_before, average_price_before,
+			                                           record_quantity, entitled_quantity, fractional_quantity, fractional_policy, cash_in_lieu_amount,
+			                                           quantity_after, average_price_after, source_average_price_after)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		`, action.ID, position.userID, action.StockID, action.SpinoffStockID, position.quantity, position.averagePrice,
+			position.recordQuantity, child.entitled, child.fraction, action.FractionalPolicy, child.cashInLieu,
+			child.quantity, child.averagePrice, parentAveragePrice)
+		if err != nil {
+			logrus.Errorf("Failed to record spin-off entitlement for user %d: %v", position.userID, err)
+		}
+		return err
+	}
+
+	return &actionSteps{apply: apply, finish: func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			UPDATE stocks
+			SET current_price = current_price * (1 - $1::numeric / 100),
+			    updated_at = NOW()
+			WHERE id = $2
+		`, action.CostApportionmentPct, action.StockID)
+		return err
+	}}, nil
+}
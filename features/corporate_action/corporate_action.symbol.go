@@ -0,0 +1,131 @@
+package corporate_action
+
+import (
+	"database/sql"
+
+	"stocky-backend/domain"
+	"stocky-backend/features/stock"
+
+	"github.com/sirupsen/logrus"
+)
+
+// resolveActiveStock looks a symbol up the way clients send it: a current
+// symbol, an ISIN, or a symbol retired by an earlier symbol change. It
+// returns nil when no active stock matches.
+func resolveActiveStock(q queryer, symbol string) (*stock.Ref, error) {
+	ref, err := stock.Resolve(q, symbol)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		logrus.Errorf("Failed to resolve stock %s: %v", symbol, err)
+		return nil, err
+	}
+	if !ref.IsActive {
+		return nil, nil
+	}
+	return ref, nil
+}
+
+// resolveTargetStocks resolves the merger target or spin-off stock of req,
+// rewriting the symbols in req to the stocks' current ones.
+func resolveTargetStocks(q queryer, req *CreateCorporateActionRequest, stockID int) (*int, string, *int, error) {
+	var mergerToStockID, spinoffStockID *int
+	var mergerToSymbol string
+
+	if req.ActionType == ActionMerger && req.MergerToSymbol != "" {
+		ref, err := resolveActiveStock(q, req.MergerToSymbol)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if ref == nil {
+			return nil, "", nil, domain.NotFound(domain.CodeStockNotFound, "merger target stock not found: %s", req.MergerToSymbol)
+		}
+		if ref.ID == stockID {
+			return nil, "", nil, domain.Validation(domain.CodeInvalidCorporateAction, "merger target %s is the stock being merged", req.MergerToSymbol)
+		}
+		mergerToStockID = &ref.ID
+		mergerToSymbol = ref.Symbol
+		req.MergerToSymbol = ref.Symbol
+	}
+
+	if req.ActionType == ActionSpinoff {
+		ref, err := resolveActiveStock(q, req.SpinoffSymbol)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		if ref == nil {
+			return nil, "", nil, domain.NotFound(domain.CodeStockNotFound, "spin-off stock not found: %s", req.SpinoffSymbol)
+		}
+		if ref.ID == stockID {
+			return nil, "", nil, domain.Validation(domain.CodeInvalidCorporateAction, "spin-off stock %s is the parent stock", req.SpinoffSymbol)
+		}
+		spinoffStockID = &ref.ID
+		req.SpinoffSymbol = ref.Symbol
+	}
+
+	return mergerToStockID, mergerToSymbol, spinoffStockID, nil
+}
+
+// checkSymbolAvailable rejects a new symbol another stock currently trades
+// under. Symbols retired by other stocks may be reused; the current symbol
+// wins when clients look one up.
+func checkSymbolAvailable(q queryer, symbol string, stockID int) error {
+	var holderID int
+	err := q.QueryRow(`SELECT id FROM stocks WHERE symbol = $1 AND id <> $2`, symbol, stockID).Scan(&holderID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		logrus.Errorf("Failed to check symbol %s: %v", symbol, err)
+		return err
+	}
+	return domain.Conflict(domain.CodeSymbolInUse, "symbol %s is already used by another stock", symbol).
+		WithMeta("stock_id", holderID)
+}
+
+// processSymbolChange renames the stock. Holdings, prices and history stay
+// on the same stock row, and the replaced symbol is kept on the action so
+// clients that cached it still resolve to the stock.
+func processSymbolChange(tx *sql.Tx, action *CorporateAction) error {
+	var oldSymbol string
+	err := tx.QueryRow(`SELECT symbol FROM stocks WHERE id = $1 FOR UPDATE`, action.StockID).Scan(&oldSymbol)
+	if err != nil {
+		return err
+	}
+	if oldSymbol == action.NewSymbol {
+		return domain.BusinessRule(domain.CodeInvalidCorporateAction, "stock already trades as %s", action.NewSymbol)
+	}
+	if err = checkSymbolAvailable(tx, action.NewSymbol, action.StockID); err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE stocks SET symbol = $1, updated_at = NOW() WHERE id = $2`, action.NewSymbol, action.StockID)
+	if err != nil {
+		logrus.Errorf("Failed to rename stock %d: %v", action.StockID, err)
+		return err
+	}
+
+	_, err = tx.Exec(`UPDATE corporate_actions SET old_symbol = $2 WHERE id = $1`, action.ID, oldSymbol)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Stock %d renamed from %s to %s", action.StockID, oldSymbol, action.NewSymbol)
+	return nil
+}
+
+// restoreSymbol reverses a symbol change. Only the symbol goes back: the
+// holdings and price may have moved since under the new symbol.
+func restoreSymbol(tx *sql.Tx, action *CorporateAction) error {
+	_, err := tx.Exec(`
+		UPDATE stocks s
+		SET symbol = snap.symbol, updated_at = NOW()
+		FROM corporate_action_stock_snapshots snap
+		WHERE snap.corporate_action_id = $1 AND s.id = snap.stock_id AND snap.symbol IS NOT NULL
+	`, action.ID)
+	if err != nil {
+		logrus.Errorf("Failed to restore symbol: %v", err)
+	}
+	return err
+}
@@ -0,0 +1,130 @@
+package corporate_action
+
+import (
+	"regexp"
+	"time"
+
+	"stocky-backend/domain"
+)
+
+var symbolPattern = regexp.MustCompile(`^[A-Z0-9&-]{1,50}$`)
+
+// validateCorporateActionRequest checks that an action carries the
+// parameters its type needs. It runs on create and again on the merged
+// result of an update.
+func validateCorporateActionRequest(req *CreateCorporateActionRequest) error {
+	switch req.ActionType {
+	case ActionStockSplit, ActionMerger, ActionDelisting, ActionDividend, ActionBonus, ActionReverseSplit, ActionSpinoff, ActionSymbolChange:
+	default:
+		return domain.Validation(domain.CodeInvalidCorporateAction, "unknown action type: %s", req.ActionType)
+	}
+
+	if req.ActionType == ActionStockSplit && req.SplitRatio <= 0 {
+		return domain.Validation(domain.CodeInvalidCorporateAction, "split_ratio is required and must be greater than 0 for stock split")
+	}
+
+	if req.ActionType == ActionMerger {
+		if req.MergerToSymbol == "" || req.MergerRatio <= 0 {
+			return domain.Validation(domain.CodeInvalidCorporateAction, "merger_to_symbol and merger_ratio are required for merger")
+		}
+	}
+
+	if req.ActionType == ActionBonus || req.ActionType == ActionReverseSplit || req.ActionType == ActionSpinoff {
+		if req.RatioNumerator <= 0 || req.RatioDenominator <= 0 {
+			return domain.Validation(domain.CodeInvalidCorporateAction, "ratio_numerator and ratio_denominator are required positive integers for bonus, reverse split and spin-off")
+		}
+		if req.ActionType == ActionReverseSplit && req.RatioNumerator <= req.RatioDenominator {
+			return domain.Validation(domain.CodeInvalidCorporateAction, "reverse split ratio_numerator must be greater than ratio_denominator")
+		}
+	}
+
+	if req.ActionType == ActionSpinoff {
+		if req.SpinoffSymbol == "" || req.SpinoffSymbol == req.StockSymbol {
+			return domain.Validation(domain.CodeInvalidCorporateAction, "spinoff_symbol is required for spin-off and must differ from stock_symbol")
+		}
+		if req.CostApportionmentPct <= 0 || req.CostApportionmentPct >= 100 {
+			return domain.Validation(domain.CodeInvalidCorporateAction, "cost_apportionment_pct is required for spin-off and must be between 0 and 100")
+		}
+	} else if req.SpinoffSymbol != "" || req.CostApportionmentPct != 0 {
+		return domain.Validation(domain.CodeInvalidCorporateAction, "spinoff_symbol and cost_apportionment_pct only apply to spin-off")
+	}
+
+	if req.ActionType == ActionSymbolChange {
+		if !symbolPattern.MatchString(req.NewSymbol) || req.NewSymbol == req.StockSymbol {
+			return domain.Validation(domain.CodeInvalidCorporateAction, "new_symbol is required for symbol change, must differ from stock_symbol and use A-Z, 0-9, & or -")
+		}
+	} else if req.NewSymbol != "" {
+		return domain.Validation(domain.CodeInvalidCorporateAction, "new_symbol only applies to symbol change")
+	}
+
+	switch req.FractionalPolicy {
+	case "", FractionalKeep, FractionalRoundHalfUp:
+	case FractionalRoundDownCash:
+		if req.CashInLieuPrice <= 0 {
+			return domain.Validation(domain.CodeInvalidCorporateAction, "cash_in_lieu_price is required and must be greater than 0 for ROUND_DOWN_CASH")
+		}
+	default:
+		return domain.Validation(domain.CodeInvalidCorporateAction, "fractional_policy must be KEEP, ROUND_DOWN_CASH or ROUND_HALF_UP")
+	}
+	if req.FractionalPolicy != "" && req.FractionalPolicy != FractionalKeep && !changesUnits(req.ActionType) {
+		return domain.Validation(domain.CodeInvalidCorporateAction, "fractional_policy only applies to stock split, merger, bonus, reverse split and spin-off")
+	}
+
+	switch req.DelistingMode {
+	case "", DelistingWriteOff:
+	case DelistingCashSettlement:
+		if req.ExitPrice <= 0 {
+			return domain.Validation(domain.CodeInvalidCorporateAction, "exit_price is required and must be greater than 0 for CASH_SETTLEMENT")
+		}
+	default:
+		return domain.Validation(domain.CodeInvalidCorporateAction, "delisting_mode must be CASH_SETTLEMENT or WRITE_OFF")
+	}
+	if req.DelistingMode != "" && req.ActionType != ActionDelisting {
+		return domain.Validation(domain.CodeInvalidCorporateAction, "delisting_mode only applies to delisting")
+	}
+
+	if req.ActionType == ActionDividend {
+		if req.DividendPerShare <= 0 {
+			return domain.Validation(domain.CodeInvalidCorporateAction, "dividend_per_share is required and must be greater than 0 for dividend")
+		}
+		recordDate, err := time.Parse("2006-01-02", req.RecordDate)
+		if err != nil {
+			return domain.Validation(domain.CodeInvalidCorporateAction, "record_date is required for dividend (YYYY-MM-DD)")
+		}
+		paymentDate, err := time.Parse("2006-01-02", req.PaymentDate)
+		if err != nil {
+			return domain.Validation(domain.CodeInvalidCorporateAction, "payment_date is required for dividend (YYYY-MM-DD)")
+		}
+		if paymentDate.Before(recordDate) {
+			return domain.Validation(domain.CodeInvalidCorporateAction, "payment_date cannot be before record_date")
+		}
+	} else if req.EffectiveDate == "" {
+		return domain.Validation(domain.CodeInvalidCorporateAction, "effective_date is required")
+	}
+
+	if req.EffectiveDate != "" {
+		effectiveDate, err := time.Parse("2006-01-02", req.EffectiveDate)
+		if err != nil {
+			return domain.Validation(domain.CodeInvalidCorporateAction, "effective_date must be a date (YYYY-MM-DD)")
+		}
+		if req.ActionType != ActionDividend && req.RecordDate != "" {
+			recordDate, err := time.Parse("2006-01-02", req.RecordDate)
+			if err != nil {
+				return domain.Validation(domain.CodeInvalidCorporateAction, "record_date must be a date (YYYY-MM-DD)")
+			}
+			if recordDate.After(effectiveDate) {
+				return domain.Validation(domain.CodeInvalidCorporateAction, "record_date cannot be after effective_date")
+			}
+		}
+	}
+
+	return nil
+}
+
+func changesUnits(actionType CorporateActionType) bool {
+	switch actionType {
+	case ActionStockSplit, ActionMerger, ActionBonus, ActionReverseSplit, ActionSpinoff:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,226 @@
+package fee
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"stocky-backend/domain"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// Compute evaluates a version's rules for a transaction on exchange and
+// returns the fees to book, each fee after the fees it is computed on.
+// Fees that do not apply or come to zero are left out.
+func Compute(q queryer, versionID int, exchange string, transactionValue float64) ([]Charge, error) {
+	ordered, err := Rules(q, versionID)
+	if err != nil {
+		return nil, err
+	}
+	return evaluate(ordered, exchange, transactionValue), nil
+}
+
+// loadRules reads the rules, with their slabs, of every listed version.
+func loadRules(q queryer, versionIDs []int64) (map[int][]Rate, error) {
+	rows, err := q.Query(`
+		SELECT id, fee_version_id, fee_type, COALESCE(name, fee_type), COALESCE(account_type, fee_type || '_FEE'),
+		       percentage, flat_amount, min_amount, max_amount, base_fee_types, exchanges, COALESCE(description, '')
+		FROM fee_rates
+		WHERE fee_version_id = ANY($1)
+		ORDER BY fee_version_id, fee_type
+	`, pq.Array(versionIDs))
+	if err != nil {
+		logrus.Errorf("Failed to query fee rates: %v", err)
+		return nil, err
+	}
+
+	type ratePosition struct {
+		versionID int
+		index     int
+	}
+
+	rules := make(map[int][]Rate, len(versionIDs))
+	positions := make(map[int]ratePosition)
+	var rateIDs []int64
+	for rows.Next() {
+		var rateID, versionID int
+		var rate Rate
+		err := rows.Scan(&rateID, &versionID, &rate.FeeType, &rate.Name, &rate.AccountType,
+			&rate.Percentage, &rate.FlatAmount, &rate.MinAmount, &rate.MaxAmount,
+			pq.Array(&rate.BaseFeeTypes), pq.Array(&rate.Exchanges), &rate.Description)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		positions[rateID] = ratePosition{versionID: versionID, index: len(rules[versionID])}
+		rules[versionID] = append(rules[versionID], rate)
+		rateIDs = append(rateIDs, int64(rateID))
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(rateIDs) == 0 {
+		return rules, nil
+	}
+
+	rows, err = q.Query(`
+		SELECT fee_rate_id, from_value, to_value, percentage, flat_amount
+		FROM fee_rate_slabs
+		WHERE fee_rate_id = ANY($1)
+		ORDER BY fee_rate_id, from_value
+	`, pq.Array(rateIDs))
+	if err != nil {
+		logrus.Errorf("Failed to query fee rate slabs: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var rateID int
+		var slab Slab
+		if err := rows.Scan(&rateID, &slab.FromValue, &slab.ToValue, &slab.Percentage, &slab.FlatAmount); err != nil {
+			return nil, err
+		}
+		position := positions[rateID]
+		rate := &rules[position.versionID][position.index]
+		rate.Slabs = append(rate.Slabs, slab)
+	}
+	return rules, rows.Err()
+}
+
+// orderRules sorts rules so every fee comes after the fees it is computed
+// on, and rejects references to fee types the version lacks and cycles.
+func orderRules(rules []Rate) ([]Rate, error) {
+	byType := make(map[string]Rate, len(rules))
+	types := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		byType[rule.FeeType] = rule
+		types = append(types, rule.FeeType)
+	}
+	sort.Strings(types)
+
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(rules))
+	ordered := make([]Rate, 0, len(rules))
+
+	var visit func(feeType string, path []string) error
+	visit = func(feeType string, path []string) error {
+		switch state[feeType] {
+		case done:
+			return nil
+		case visiting:
+			return domain.Validation(domain.CodeInvalidFeeVersion, "fees computed on each other form a cycle: %s",
+				strings.Join(append(path, feeType), " -> "))
+		}
+
+		state[feeType] = visiting
+		rule := byType[feeType]
+		for _, baseType := range rule.BaseFeeTypes {
+			if _, ok := byType[baseType]; !ok {
+				return domain.Validation(domain.CodeInvalidFeeVersion, "fee %s is computed on %s, which the version does not define", feeType, baseType)
+			}
+			if err := visit(baseType, append(path, feeType)); err != nil {
+				return err
+			}
+		}
+		state[feeType] = done
+		ordered = append(ordered, rule)
+		return nil
+	}
+
+	for _, feeType := range types {
+		if err := visit(feeType, nil); err != nil {
+			return nil, err
+		}
+	}
+	return ordered, nil
+}
+
+// evaluate computes ordered rules for one transaction. A fee computed on
+// other fees is skipped when none of them was charged.
+func evaluate(ordered []Rate, exchange string, transactionValue float64) []Charge {
+	amounts := make(map[string]float64, len(ordered))
+	charges := []Charge{}
+
+	for _, rule := range ordered {
+		if !appliesOn(rule, exchange) {
+			continue
+		}
+
+		base := transactionValue
+		if len(rule.BaseFeeTypes) > 0 {
+			base = 0
+			for _, baseType := range rule.BaseFeeTypes {
+				base += amounts[baseType]
+			}
+		}
+		if base <= 0 {
+			continue
+		}
+
+		percentage, flatAmount := rule.Percentage, rule.FlatAmount
+		if len(rule.Slabs) > 0 {
+			slab := slabFor(rule.Slabs, transactionValue)
+			if slab == nil {
+				continue
+			}
+			percentage, flatAmount = slab.Percentage, slab.FlatAmount
+		}
+
+		amount := base*percentage + flatAmount
+		if rule.MinAmount != nil && amount < *rule.MinAmount {
+			amount = *rule.MinAmount
+		}
+		if rule.MaxAmount != nil && amount > *rule.MaxAmount {
+			amount = *rule.MaxAmount
+		}
+		amount = roundAmount(amount)
+		if amount <= 0 {
+			continue
+		}
+
+		amounts[rule.FeeType] = amount
+		charges = append(charges, Charge{
+			FeeType:     rule.FeeType,
+			Name:        rule.Name,
+			AccountType: rule.AccountType,
+			Base:        roundAmount(base),
+			Amount:      amount,
+		})
+	}
+	return charges
+}
+
+func appliesOn(rule Rate, exchange string) bool {
+	if len(rule.Exchanges) == 0 {
+		return true
+	}
+	for _, listed := range rule.Exchanges {
+		if strings.EqualFold(listed, exchange) {
+			return true
+		}
+	}
+	return false
+}
+
+func slabFor(slabs []Slab, transactionValue float64) *Slab {
+	for i := range slabs {
+		slab := &slabs[i]
+		if transactionValue >= slab.FromValue && (slab.ToValue == nil || transactionValue < *slab.ToValue) {
+			return slab
+		}
+	}
+	return nil
+}
+
+// roundAmount rounds to the 4 decimal places ledger amounts are stored
+// with, so fees computed on other fees use the booked amounts.
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*10000) / 10000
+}
@@ -0,0 +1,258 @@
+package fee
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"stocky-backend/domain"
+)
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func feeTypes(rules []Rate) []string {
+	types := make([]string, 0, len(rules))
+	for _, rule := range rules {
+		types = append(types, rule.FeeType)
+	}
+	return types
+}
+
+func TestOrderRules(t *testing.T) {
+	tests := []struct {
+		name    string
+		rules   []Rate
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "independent fees sort by type",
+			rules: []Rate{
+				{FeeType: "STT"},
+				{FeeType: "BROKERAGE"},
+				{FeeType: "STAMP_DUTY"},
+			},
+			want: []string{"BROKERAGE", "STAMP_DUTY", "STT"},
+		},
+		{
+			name: "fee follows the fees it is computed on",
+			rules: []Rate{
+				{FeeType: "GST", BaseFeeTypes: []string{"EXCHANGE", "BROKERAGE"}},
+				{FeeType: "EXCHANGE"},
+				{FeeType: "BROKERAGE"},
+			},
+			want: []string{"BROKERAGE", "EXCHANGE", "GST"},
+		},
+		{
+			name: "chained bases",
+			rules: []Rate{
+				{FeeType: "A", BaseFeeTypes: []string{"B"}},
+				{FeeType: "B", BaseFeeTypes: []string{"C"}},
+				{FeeType: "C"},
+			},
+			want: []string{"C", "B", "A"},
+		},
+		{
+			name: "missing base fee",
+			rules: []Rate{
+				{FeeType: "GST", BaseFeeTypes: []string{"BROKERAGE"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "cycle",
+			rules: []Rate{
+				{FeeType: "A", BaseFeeTypes: []string{"B"}},
+				{FeeType: "B", BaseFeeTypes: []string{"A"}},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fee computed on itself",
+			rules: []Rate{
+				{FeeType: "A", BaseFeeTypes: []string{"A"}},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, err := orderRules(tt.rules)
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrValidation) {
+					t.Fatalf("orderRules() error = %v, want a validation error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("orderRules() error = %v", err)
+			}
+			if got := feeTypes(ordered); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("orderRules() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSlabFor(t *testing.T) {
+	slabs := []Slab{
+		{FromValue: 0, ToValue: floatPtr(1000), FlatAmount: 5},
+		{FromValue: 1000, ToValue: floatPtr(10000), FlatAmount: 10},
+		{FromValue: 10000, FlatAmount: 20},
+	}
+
+	tests := []struct {
+		name  string
+		slabs []Slab
+		value float64
+		want  float64
+		found bool
+	}{
+		{name: "first slab", slabs: slabs, value: 0, want: 5, found: true},
+		{name: "just below a bound", slabs: slabs, value: 999.99, want: 5, found: true},
+		{name: "bound belongs to the next slab", slabs: slabs, value: 1000, want: 10, found: true},
+		{name: "last bounded slab", slabs: slabs, value: 9999.99, want: 10, found: true},
+		{name: "unbounded slab", slabs: slabs, value: 1e9, want: 20, found: true},
+		{name: "below every slab", slabs: []Slab{{FromValue: 100, FlatAmount: 1}}, value: 50},
+		{name: "gap between slabs", slabs: []Slab{{FromValue: 0, ToValue: floatPtr(10)}, {FromValue: 20}}, value: 15},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			slab := slabFor(tt.slabs, tt.value)
+			if !tt.found {
+				if slab != nil {
+					t.Fatalf("slabFor(%v) = %+v, want none", tt.value, *slab)
+				}
+				return
+			}
+			if slab == nil {
+				t.Fatalf("slabFor(%v) = none, want flat amount %v", tt.value, tt.want)
+			}
+			if slab.FlatAmount != tt.want {
+				t.Errorf("slabFor(%v) flat amount = %v, want %v", tt.value, slab.FlatAmount, tt.want)
+			}
+		})
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	brokerage := Rate{FeeType: "BROKERAGE", AccountType: "FEE_BROKERAGE", Percentage: 0.0003, MinAmount: floatPtr(5), MaxAmount: floatPtr(20)}
+	gst := Rate{FeeType: "GST", AccountType: "TAX_GST", Percentage: 0.18, BaseFeeTypes: []string{"BROKERAGE"}}
+
+	tests := []struct {
+		name     string
+		rules    []Rate
+		exchange string
+		value    float64
+		want     map[string]float64
+	}{
+		{
+			name:     "percentage fee",
+			rules:    []Rate{{FeeType: "STT", Percentage: 0.001}},
+			exchange: "NSE",
+			value:    10000,
+			want:     map[string]float64{"STT": 10},
+		},
+		{
+			name:     "minimum applies",
+			rules:    []Rate{brokerage},
+			exchange: "NSE",
+			value:    1000,
+			want:     map[string]float64{"BROKERAGE": 5},
+		},
+		{
+			name:     "maximum applies",
+			rules:    []Rate{brokerage},
+			exchange: "NSE",
+			value:    1000000,
+			want:     map[string]float64{"BROKERAGE": 20},
+		},
+		{
+			name:     "between the caps",
+			rules:    []Rate{brokerage},
+			exchange: "NSE",
+			value:    50000,
+			want:     map[string]float64{"BROKERAGE": 15},
+		},
+		{
+			name:     "GST on brokerage",
+			rules:    []Rate{brokerage, gst},
+			exchange: "NSE",
+			value:    50000,
+			want:     map[string]float64{"BROKERAGE": 15, "GST": 2.7},
+		},
+		{
+			name:     "GST on capped brokerage",
+			rules:    []Rate{brokerage, gst},
+			exchange: "NSE",
+			value:    1000000,
+			want:     map[string]float64{"BROKERAGE": 20, "GST": 3.6},
+		},
+		{
+			name: "GST skipped when its base is not charged",
+			rules: []Rate{
+				{FeeType: "BROKERAGE", Exchanges: []string{"BSE"}, FlatAmount: 10},
+				gst,
+			},
+			exchange: "NSE",
+			value:    50000,
+			want:     map[string]float64{},
+		},
+		{
+			name: "exchange filter",
+			rules: []Rate{
+				{FeeType: "NSE_TXN", Exchanges: []string{"NSE"}, FlatAmount: 1},
+				{FeeType: "BSE_TXN", Exchanges: []string{"BSE"}, FlatAmount: 2},
+				{FeeType: "STAMP_DUTY", FlatAmount: 3},
+			},
+			exchange: "nse",
+			value:    1000,
+			want:     map[string]float64{"NSE_TXN": 1, "STAMP_DUTY": 3},
+		},
+		{
+			name: "slab rate",
+			rules: []Rate{{FeeType: "DP", Slabs: []Slab{
+				{FromValue: 0, ToValue: floatPtr(1000), FlatAmount: 5},
+				{FromValue: 1000, Percentage: 0.01},
+			}}},
+			exchange: "NSE",
+			value:    2000,
+			want:     map[string]float64{"DP": 20},
+		},
+		{
+			name:     "value outside every slab",
+			rules:    []Rate{{FeeType: "DP", Slabs: []Slab{{FromValue: 1000, FlatAmount: 5}}}},
+			exchange: "NSE",
+			value:    500,
+			want:     map[string]float64{},
+		},
+		{
+			name:     "amounts round to 4 places",
+			rules:    []Rate{{FeeType: "STT", Percentage: 0.00001}},
+			exchange: "NSE",
+			value:    123.4567,
+			want:     map[string]float64{"STT": 0.0012},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ordered, err := orderRules(tt.rules)
+			if err != nil {
+				t.Fatalf("orderRules() error = %v", err)
+			}
+
+			got := make(map[string]float64)
+			for _, charge := range evaluate(ordered, tt.exchange, tt.value) {
+				got[charge.FeeType] = charge.Amount
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("evaluate(%v on %s) = %v, want %v", tt.value, tt.exchange, got, tt.want)
+			}
+		})
+	}
+}
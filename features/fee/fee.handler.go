@@ -0,0 +1,139 @@
+package fee
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"stocky-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type FeeHandler struct {
+	service *FeeService
+}
+
+func NewFeeHandler(service *FeeService) *FeeHandler {
+	return &FeeHandler{service: service}
+}
+
+func (h *FeeHandler) GetAllVersions(c *gin.Context) {
+	versions, err := h.service.GetAllVersions()
+	if err != nil {
+		logrus.Errorf("Error getting fee versions: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve fee versions"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": versions})
+}
+
+func (h *FeeHandler) GetVersion(c *gin.Context) {
+	versionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid fee version ID", err.Error()))
+		return
+	}
+
+	version, err := h.service.GetVersion(versionID)
+	if err != nil {
+		logrus.Errorf("Error getting fee version: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve fee version"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": version})
+}
+
+// GetEffectiveVersion accepts an optional ?at= timestamp (RFC 3339).
+func (h *FeeHandler) GetEffectiveVersion(c *gin.Context) {
+	var at *time.Time
+	if value := c.Query("at"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.Error(middleware.BadRequestError("Invalid at timestamp", err.Error()))
+			return
+		}
+		at = &parsed
+	}
+
+	version, err := h.service.GetEffectiveVersion(at)
+	if err != nil {
+		logrus.Errorf("Error getting effective fee version: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve fee version"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": version})
+}
+
+// Quote takes ?transaction_value=, an optional ?exchange= and an optional
+// ?at= timestamp (RFC 3339).
+func (h *FeeHandler) Quote(c *gin.Context) {
+	transactionValue, err := strconv.ParseFloat(c.Query("transaction_value"), 64)
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid transaction_value", err.Error()))
+		return
+	}
+
+	var at *time.Time
+	if value := c.Query("at"); value != "" {
+		parsed, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			c.Error(middleware.BadRequestError("Invalid at timestamp", err.Error()))
+			return
+		}
+		at = &parsed
+	}
+
+	quote, err := h.service.Quote(transactionValue, c.Query("exchange"), at)
+	if err != nil {
+		logrus.Errorf("Error quoting fees: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to compute fees"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": quote})
+}
+
+func (h *FeeHandler) ScheduleVersion(c *gin.Context) {
+	var req ScheduleVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	version, err := h.service.ScheduleVersion(req)
+	if err != nil {
+		logrus.Errorf("Error scheduling fee version: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to schedule fee version"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": version})
+}
+
+func (h *FeeHandler) CancelVersion(c *gin.Context) {
+	versionID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid fee version ID", err.Error()))
+		return
+	}
+
+	var req CancelVersionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	version, err := h.service.CancelVersion(versionID, req)
+	if err != nil {
+		logrus.Errorf("Error cancelling fee version: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to cancel fee version"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": version})
+}
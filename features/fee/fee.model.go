@@ -0,0 +1,109 @@
+package fee
+
+import (
+	"time"
+)
+
+const (
+	VersionStatusScheduled  = "SCHEDULED"
+	VersionStatusActive     = "ACTIVE"
+	VersionStatusSuperseded = "SUPERSEDED"
+	VersionStatusCancelled  = "CANCELLED"
+)
+
+// Rate is one fee rule of a version. The fee is base × Percentage +
+// FlatAmount, clamped to MinAmount/MaxAmount, where the base is the
+// transaction value or, with BaseFeeTypes, the sum of those fees. Slabs,
+// when present, replace Percentage and FlatAmount by transaction value.
+type Rate struct {
+	FeeType      string   `json:"fee_type"`
+	Name         string   `json:"name"`
+	AccountType  string   `json:"account_type"`
+	Percentage   float64  `json:"percentage"`
+	FlatAmount   float64  `json:"flat_amount"`
+	MinAmount    *float64 `json:"min_amount,omitempty"`
+	MaxAmount    *float64 `json:"max_amount,omitempty"`
+	BaseFeeTypes []string `json:"base_fee_types,omitempty"`
+	Exchanges    []string `json:"exchanges,omitempty"`
+	Slabs        []Slab   `json:"slabs,omitempty"`
+	Description  string   `json:"description,omitempty"`
+}
+
+// Slab covers transaction values from FromValue up to, but excluding,
+// ToValue (unbounded when nil).
+type Slab struct {
+	FromValue  float64  `json:"from_value"`
+	ToValue    *float64 `json:"to_value,omitempty"`
+	Percentage float64  `json:"percentage"`
+	FlatAmount float64  `json:"flat_amount"`
+}
+
+// Charge is one fee computed for a transaction.
+type Charge struct {
+	FeeType     string  `json:"fee_type"`
+	Name        string  `json:"name"`
+	AccountType string  `json:"account_type"`
+	Base        float64 `json:"base"`
+	Amount      float64 `json:"amount"`
+}
+
+type Quote struct {
+	FeeVersionID     int      `json:"fee_version_id"`
+	TransactionValue float64  `json:"transaction_value"`
+	Exchange         string   `json:"exchange"`
+	Charges          []Charge `json:"charges"`
+	TotalFees        float64  `json:"total_fees"`
+}
+
+// Version is a complete fee schedule. It applies to rewards created from
+// EffectiveFrom until the next version takes over (EffectiveTo).
+type Version struct {
+	ID                 int        `json:"id"`
+	EffectiveFrom      time.Time  `json:"effective_from"`
+	EffectiveTo        *time.Time `json:"effective_to,omitempty"`
+	Status             string     `json:"status"`
+	Description        string     `json:"description,omitempty"`
+	CreatedBy          string     `json:"created_by,omitempty"`
+	CancelledBy        string     `json:"cancelled_by,omitempty"`
+	CancelledAt        *time.Time `json:"cancelled_at,omitempty"`
+	CancellationReason string     `json:"cancellation_reason,omitempty"`
+	RewardCount        int        `json:"reward_count"`
+	Rates              []Rate     `json:"rates"`
+	CreatedAt          time.Time  `json:"created_at"`
+}
+
+type RateRequest struct {
+	FeeType      string        `json:"fee_type" binding:"required"`
+	Name         string        `json:"name"`
+	AccountType  string        `json:"account_type"`
+	Percentage   *float64      `json:"percentage" binding:"omitempty,gte=0,lte=1"`
+	FlatAmount   float64       `json:"flat_amount" binding:"gte=0"`
+	MinAmount    *float64      `json:"min_amount" binding:"omitempty,gte=0"`
+	MaxAmount    *float64      `json:"max_amount" binding:"omitempty,gte=0"`
+	BaseFeeTypes []string      `json:"base_fee_types"`
+	Exchanges    []string      `json:"exchanges"`
+	Slabs        []SlabRequest `json:"slabs" binding:"dive"`
+	Description  string        `json:"description"`
+}
+
+type SlabRequest struct {
+	FromValue  float64  `json:"from_value" binding:"gte=0"`
+	ToValue    *float64 `json:"to_value"`
+	Percentage float64  `json:"percentage" binding:"gte=0,lte=1"`
+	FlatAmount float64  `json:"flat_amount" binding:"gte=0"`
+}
+
+// ScheduleVersionRequest creates a version from EffectiveFrom (now when
+// omitted). Fee types it does not list keep the rate of the version it
+// replaces.
+type ScheduleVersionRequest struct {
+	EffectiveFrom *time.Time    `json:"effective_from"`
+	Description   string        `json:"description"`
+	CreatedBy     string        `json:"created_by" binding:"required"`
+	Rates         []RateRequest `json:"rates" binding:"required,min=1,dive"`
+}
+
+type CancelVersionRequest struct {
+	CancelledBy string `json:"cancelled_by" binding:"required"`
+	Reason      string `json:"reason" binding:"required"`
+}
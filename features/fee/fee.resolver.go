@@ -0,0 +1,56 @@
+package fee
+
+import (
+	"database/sql"
+	"time"
+
+	"stocky-backend/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// CurrentVersionID returns the version in effect at the database's NOW(),
+// which inside a transaction is the transaction's start time, the same
+// instant a reward created in it is stamped with.
+func CurrentVersionID(q queryer) (int, error) {
+	return versionID(q, `NOW()`)
+}
+
+// VersionIDAt returns the version in effect at the instant t. It is bound as
+// timestamptz so its offset is kept; effective_from is compared in the
+// session time zone it was stored in, as with NOW().
+func VersionIDAt(q queryer, t time.Time) (int, error) {
+	return versionID(q, `$1::timestamptz`, t)
+}
+
+func versionID(q queryer, at string, args ...interface{}) (int, error) {
+	var id int
+	err := q.QueryRow(`
+		SELECT id FROM fee_versions
+		WHERE cancelled_at IS NULL AND effective_from <= `+at+`
+		ORDER BY effective_from DESC
+		LIMIT 1
+	`, args...).Scan(&id)
+	if err == sql.ErrNoRows {
+		return 0, domain.NotFound(domain.CodeFeeVersionNotFound, "no fee version is in effect")
+	}
+	if err != nil {
+		logrus.Errorf("Failed to resolve fee version: %v", err)
+		return 0, err
+	}
+	return id, nil
+}
+
+// Rules returns a version's fee rules, each after the fees it is computed on.
+func Rules(q queryer, versionID int) ([]Rate, error) {
+	rules, err := loadRules(q, []int64{int64(versionID)})
+	if err != nil {
+		return nil, err
+	}
+	return orderRules(rules[versionID])
+}
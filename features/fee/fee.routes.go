@@ -0,0 +1,17 @@
+package fee
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(router *gin.RouterGroup, handler *FeeHandler) {
+	fees := router.Group("/fees")
+	{
+		fees.GET("/versions", handler.GetAllVersions)
+		fees.GET("/versions/effective", handler.GetEffectiveVersion)
+		fees.GET("/versions/:id", handler.GetVersion)
+		fees.POST("/versions", handler.ScheduleVersion)
+		fees.POST("/versions/:id/cancel", handler.CancelVersion)
+		fees.GET("/quote", handler.Quote)
+	}
+}
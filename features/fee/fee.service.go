@@ -0,0 +1,448 @@
+package fee
+
+import (
+	"database/sql"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"stocky-backend/domain"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+var feeTypePattern = regexp.MustCompile(`^[A-Z][A-Z0-9_]{0,49}$`)
+
+type FeeService struct {
+	db *sql.DB
+}
+
+func NewFeeService(db *sql.DB) *FeeService {
+	return &FeeService{db: db}
+}
+
+// versionQuery reads versions with their derived window and status. A
+// version is ACTIVE from its effective_from until the next live version
+// takes over; cancelled versions never take part.
+const versionQuery = `
+	WITH live AS (
+		SELECT id, LEAD(effective_from) OVER (ORDER BY effective_from) AS effective_to
+		FROM fee_versions
+		WHERE cancelled_at IS NULL
+	)
+	SELECT v.id, v.effective_from, l.effective_to,
+	       CASE WHEN v.cancelled_at IS NOT NULL THEN 'CANCELLED'
+	            WHEN v.effective_from > NOW() THEN 'SCHEDULED'
+	            WHEN l.effective_to IS NULL OR l.effective_to > NOW() THEN 'ACTIVE'
+	            ELSE 'SUPERSEDED' END,
+	       COALESCE(v.description, ''), COALESCE(v.created_by, ''), COALESCE(v.cancelled_by, ''),
+	       v.cancelled_at, COALESCE(v.cancellation_reason, ''),
+	       (SELECT COUNT(*) FROM reward_events re WHERE re.fee_version_id = v.id),
+	       v.created_at
+	FROM fee_versions v
+	LEFT JOIN live l ON l.id = v.id
+`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanVersion(row rowScanner, version *Version) error {
+	return row.Scan(&version.ID, &version.EffectiveFrom, &version.EffectiveTo, &version.Status,
+		&version.Description, &version.CreatedBy, &version.CancelledBy, &version.CancelledAt,
+		&version.CancellationReason, &version.RewardCount, &version.CreatedAt)
+}
+
+// GetAllVersions lists every version, newest effective_from first.
+func (s *FeeService) GetAllVersions() ([]Version, error) {
+	rows, err := s.db.Query(versionQuery + ` ORDER BY v.effective_from DESC, v.id DESC`)
+	if err != nil {
+		logrus.Errorf("Failed to query fee versions: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := []Version{}
+	for rows.Next() {
+		var version Version
+		if err := scanVersion(rows, &version); err != nil {
+			logrus.Errorf("Failed to scan fee version: %v", err)
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if err = fillRates(s.db, versions); err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+func (s *FeeService) GetVersion(versionID int) (*Version, error) {
+	return getVersion(s.db, versionID)
+}
+
+// GetEffectiveVersion returns the version a reward created at at would use,
+// or the one in effect now when at is nil.
+func (s *FeeService) GetEffectiveVersion(at *time.Time) (*Version, error) {
+	var versionID int
+	var err error
+	if at == nil {
+		versionID, err = CurrentVersionID(s.db)
+	} else {
+		versionID, err = VersionIDAt(s.db, *at)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return getVersion(s.db, versionID)
+}
+
+// Quote computes the fees a transaction of transactionValue on exchange
+// would be charged under the version in effect at at (now when nil).
+func (s *FeeService) Quote(transactionValue float64, exchange string, at *time.Time) (*Quote, error) {
+	if transactionValue <= 0 {
+		return nil, domain.Validation(domain.CodeInvalidFeeVersion, "transaction_value must be greater than zero")
+	}
+
+	var versionID int
+	var err error
+	if at == nil {
+		versionID, err = CurrentVersionID(s.db)
+	} else {
+		versionID, err = VersionIDAt(s.db, *at)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	charges, err := Compute(s.db, versionID, exchange, transactionValue)
+	if err != nil {
+		return nil, err
+	}
+
+	quote := &Quote{
+		FeeVersionID:     versionID,
+		TransactionValue: transactionValue,
+		Exchange:         strings.ToUpper(exchange),
+		Charges:          charges,
+	}
+	for _, charge := range charges {
+		quote.TotalFees += charge.Amount
+	}
+	quote.TotalFees = roundAmount(quote.TotalFees)
+	return quote, nil
+}
+
+func getVersion(q queryer, versionID int) (*Version, error) {
+	var version Version
+	err := scanVersion(q.QueryRow(versionQuery+` WHERE v.id = $1`, versionID), &version)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeFeeVersionNotFound, "fee version %d not found", versionID)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to fetch fee version: %v", err)
+		return nil, err
+	}
+
+	versions := []Version{version}
+	if err = fillRates(q, versions); err != nil {
+		return nil, err
+	}
+	return &versions[0], nil
+}
+
+// fillRates loads the rules of every listed version.
+func fillRates(q queryer, versions []Version) error {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, len(versions))
+	for i := range versions {
+		ids[i] = int64(versions[i].ID)
+	}
+
+	rules, err := loadRules(q, ids)
+	if err != nil {
+		return err
+	}
+	for i := range versions {
+		versions[i].Rates = rules[versions[i].ID]
+		if versions[i].Rates == nil {
+			versions[i].Rates = []Rate{}
+		}
+	}
+	return nil
+}
+
+// ScheduleVersion adds a version effective from req.EffectiveFrom, or
+// immediately. It cannot start in the past: rewards already created keep
+// the rates they were charged. Fee types the request leaves out are carried
+// over from the version in effect at that moment.
+func (s *FeeService) ScheduleVersion(req ScheduleVersionRequest) (*Version, error) {
+	rules := make([]Rate, 0, len(req.Rates))
+	seen := make(map[string]bool, len(req.Rates))
+	for _, rateReq := range req.Rates {
+		rule, err := ruleFromRequest(rateReq)
+		if err != nil {
+			return nil, err
+		}
+		if seen[rule.FeeType] {
+			return nil, domain.Validation(domain.CodeInvalidFeeVersion, "fee_type %s is listed more than once", rule.FeeType)
+		}
+		seen[rule.FeeType] = true
+		rules = append(rules, rule)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		logrus.Errorf("Failed to begin transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if req.EffectiveFrom != nil {
+		var inPast bool
+		if err = tx.QueryRow(`SELECT $1::timestamptz < NOW()`, *req.EffectiveFrom).Scan(&inPast); err != nil {
+			return nil, err
+		}
+		if inPast {
+			return nil, domain.Validation(domain.CodeInvalidFeeVersion, "effective_from %s is in the past; fees already charged cannot be changed",
+				req.EffectiveFrom.Format(time.RFC3339))
+		}
+	}
+
+	// effective_from is stored in the session time zone, like NOW(); the
+	// request's instant keeps its offset until it is converted.
+	var versionID int
+	var effectiveFrom time.Time
+	err = tx.QueryRow(`
+		INSERT INTO fee_versions (effective_from, description, created_by)
+		VALUES (COALESCE($1::timestamptz, NOW()), NULLIF($2, ''), $3)
+		RETURNING id, effective_from
+	`, req.EffectiveFrom, req.Description, req.CreatedBy).Scan(&versionID, &effectiveFrom)
+	if isUniqueViolation(err) {
+		return nil, domain.Conflict(domain.CodeFeeVersionExists, "another fee version already takes effect at the same time")
+	}
+	if err != nil {
+		logrus.Errorf("Failed to create fee version: %v", err)
+		return nil, err
+	}
+
+	// Carry over from the predecessor: the latest live version starting
+	// before this one.
+	var predecessorID int
+	err = tx.QueryRow(`
+		SELECT id FROM fee_versions
+		WHERE cancelled_at IS NULL AND effective_from < $1
+		ORDER BY effective_from DESC
+		LIMIT 1
+	`, effectiveFrom).Scan(&predecessorID)
+	if err != nil && err != sql.ErrNoRows {
+		logrus.Errorf("Failed to find preceding fee version: %v", err)
+		return nil, err
+	}
+	if err == nil {
+		predecessorRules, err := loadRules(tx, []int64{int64(predecessorID)})
+		if err != nil {
+			return nil, err
+		}
+		for _, rule := range predecessorRules[predecessorID] {
+			if !seen[rule.FeeType] {
+				rules = append(rules, rule)
+			}
+		}
+	}
+
+	if _, err = orderRules(rules); err != nil {
+		return nil, err
+	}
+
+	for _, rule := range rules {
+		if err = insertRule(tx, versionID, rule); err != nil {
+			return nil, err
+		}
+	}
+
+	version, err := getVersion(tx, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Fee version %d scheduled from %s by %s", versionID, effectiveFrom.Format(time.RFC3339), req.CreatedBy)
+	return version, nil
+}
+
+// ruleFromRequest validates one requested rule and fills in its defaults:
+// the name falls back to the fee type and the ledger account to
+// <FEE_TYPE>_FEE.
+func ruleFromRequest(req RateRequest) (Rate, error) {
+	rule := Rate{
+		FeeType:     req.FeeType,
+		Name:        strings.TrimSpace(req.Name),
+		AccountType: req.AccountType,
+		FlatAmount:  req.FlatAmount,
+		MinAmount:   req.MinAmount,
+		MaxAmount:   req.MaxAmount,
+		Description: req.Description,
+	}
+	if req.Percentage != nil {
+		rule.Percentage = *req.Percentage
+	}
+	if rule.Name == "" {
+		rule.Name = rule.FeeType
+	}
+	if rule.AccountType == "" {
+		rule.AccountType = rule.FeeType + "_FEE"
+	}
+
+	if !feeTypePattern.MatchString(rule.FeeType) {
+		return rule, domain.Validation(domain.CodeInvalidFeeVersion, "fee_type %q must be upper case letters, digits or underscores", rule.FeeType)
+	}
+	if !feeTypePattern.MatchString(rule.AccountType) {
+		return rule, domain.Validation(domain.CodeInvalidFeeVersion, "account_type %q of fee %s must be upper case letters, digits or underscores, at most 50 long", rule.AccountType, rule.FeeType)
+	}
+	if len(rule.Name) > 100 {
+		return rule, domain.Validation(domain.CodeInvalidFeeVersion, "name of fee %s is longer than 100 characters", rule.FeeType)
+	}
+	if rule.MinAmount != nil && rule.MaxAmount != nil && *rule.MinAmount > *rule.MaxAmount {
+		return rule, domain.Validation(domain.CodeInvalidFeeVersion, "min_amount of fee %s exceeds its max_amount", rule.FeeType)
+	}
+
+	for _, baseType := range req.BaseFeeTypes {
+		if baseType == rule.FeeType {
+			return rule, domain.Validation(domain.CodeInvalidFeeVersion, "fee %s cannot be computed on itself", rule.FeeType)
+		}
+		rule.BaseFeeTypes = append(rule.BaseFeeTypes, baseType)
+	}
+
+	for _, exchange := range req.Exchanges {
+		exchange = strings.ToUpper(strings.TrimSpace(exchange))
+		if exchange == "" {
+			return rule, domain.Validation(domain.CodeInvalidFeeVersion, "exchanges of fee %s contain an empty entry", rule.FeeType)
+		}
+		rule.Exchanges = append(rule.Exchanges, exchange)
+	}
+
+	for _, slabReq := range req.Slabs {
+		if slabReq.ToValue != nil && *slabReq.ToValue <= slabReq.FromValue {
+			return rule, domain.Validation(domain.CodeInvalidFeeVersion, "slab of fee %s ends at or before it starts", rule.FeeType)
+		}
+		rule.Slabs = append(rule.Slabs, Slab{
+			FromValue:  slabReq.FromValue,
+			ToValue:    slabReq.ToValue,
+			Percentage: slabReq.Percentage,
+			FlatAmount: slabReq.FlatAmount,
+		})
+	}
+	sort.Slice(rule.Slabs, func(i, j int) bool { return rule.Slabs[i].FromValue < rule.Slabs[j].FromValue })
+	for i := 1; i < len(rule.Slabs); i++ {
+		previous := rule.Slabs[i-1]
+		if previous.ToValue == nil || *previous.ToValue > rule.Slabs[i].FromValue {
+			return rule, domain.Validation(domain.CodeInvalidFeeVersion, "slabs of fee %s overlap at %.4f", rule.FeeType, rule.Slabs[i].FromValue)
+		}
+	}
+
+	return rule, nil
+}
+
+func insertRule(tx *sql.Tx, versionID int, rule Rate) error {
+	var rateID int
+	err := tx.QueryRow(`
+		INSERT INTO fee_rates (fee_version_id, fee_type, name, account_type, percentage, flat_amount,
+		                       min_amount, max_amount, base_fee_types, exchanges, description)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''))
+		RETURNING id
+	`, versionID, rule.FeeType, rule.Name, rule.AccountType, rule.Percentage, rule.FlatAmount,
+		rule.MinAmount, rule.MaxAmount, nullArray(rule.BaseFeeTypes), nullArray(rule.Exchanges), rule.Description).Scan(&rateID)
+	if err != nil {
+		logrus.Errorf("Failed to create fee rate %s: %v", rule.FeeType, err)
+		return err
+	}
+
+	for _, slab := range rule.Slabs {
+		_, err = tx.Exec(`
+			INSERT INTO fee_rate_slabs (fee_rate_id, from_value, to_value, percentage, flat_amount)
+			VALUES ($1, $2, $3, $4, $5)
+		`, rateID, slab.FromValue, slab.ToValue, slab.Percentage, slab.FlatAmount)
+		if err != nil {
+			logrus.Errorf("Failed to create slab for fee rate %s: %v", rule.FeeType, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// nullArray stores an empty list as NULL, which the engine reads as "no
+// restriction".
+func nullArray(values []string) interface{} {
+	if len(values) == 0 {
+		return nil
+	}
+	return pq.Array(values)
+}
+
+// CancelVersion withdraws a version that has not taken effect yet. Rewards
+// can only reference versions already in effect, so none are affected.
+func (s *FeeService) CancelVersion(versionID int, req CancelVersionRequest) (*Version, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		logrus.Errorf("Failed to begin transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var scheduled, cancelled bool
+	err = tx.QueryRow(`
+		SELECT effective_from > NOW(), cancelled_at IS NOT NULL
+		FROM fee_versions WHERE id = $1
+		FOR UPDATE
+	`, versionID).Scan(&scheduled, &cancelled)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeFeeVersionNotFound, "fee version %d not found", versionID)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to fetch fee version: %v", err)
+		return nil, err
+	}
+	if cancelled || !scheduled {
+		return nil, domain.Conflict(domain.CodeFeeVersionNotScheduled, "only scheduled fee versions can be cancelled; version %d is already cancelled or in effect", versionID)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE fee_versions
+		SET cancelled_by = $2, cancelled_at = NOW(), cancellation_reason = $3
+		WHERE id = $1
+	`, versionID, req.CancelledBy, req.Reason)
+	if err != nil {
+		logrus.Errorf("Failed to cancel fee version: %v", err)
+		return nil, err
+	}
+
+	version, err := getVersion(tx, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Fee version %d cancelled by %s", versionID, req.CancelledBy)
+	return version, nil
+}
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
@@ -0,0 +1,127 @@
+package invoice
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"stocky-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type InvoiceHandler struct {
+	service *InvoiceService
+}
+
+func NewInvoiceHandler(service *InvoiceService) *InvoiceHandler {
+	return &InvoiceHandler{service: service}
+}
+
+func (h *InvoiceHandler) GenerateRewardInvoice(c *gin.Context) {
+	rewardID, err := strconv.Atoi(c.Param("rewardId"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid reward ID", err.Error()))
+		return
+	}
+
+	var req GenerateRewardInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	invoice, err := h.service.GenerateRewardInvoice(rewardID, req)
+	if err != nil {
+		logrus.Errorf("Error generating reward invoice: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to generate invoice"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": invoice})
+}
+
+func (h *InvoiceHandler) GenerateDailyInvoice(c *gin.Context) {
+	var req GenerateDailyInvoiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	invoice, err := h.service.GenerateDailyInvoice(req)
+	if err != nil {
+		logrus.Errorf("Error generating daily invoice: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to generate invoice"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": invoice})
+}
+
+// GetInvoices lists invoice records, optionally for one ?user_id=.
+func (h *InvoiceHandler) GetInvoices(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	userID := 0
+	if value := c.Query("user_id"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+			return
+		}
+		userID = parsed
+	}
+
+	response, err := h.service.GetInvoices(userID, page, pageSize)
+	if err != nil {
+		logrus.Errorf("Error getting invoices: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve invoices"))
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *InvoiceHandler) GetInvoice(c *gin.Context) {
+	invoiceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid invoice ID", err.Error()))
+		return
+	}
+
+	document, err := h.service.GetDocument(invoiceID)
+	if err != nil {
+		logrus.Errorf("Error getting invoice: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve invoice"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": document})
+}
+
+func (h *InvoiceHandler) GetInvoicePDF(c *gin.Context) {
+	invoiceID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid invoice ID", err.Error()))
+		return
+	}
+
+	number, pdf, err := h.service.GetPDF(invoiceID)
+	if err != nil {
+		logrus.Errorf("Error getting invoice PDF: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve invoice"))
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="invoice-%s.pdf"`, strings.ReplaceAll(number, "/", "-")))
+	c.Data(http.StatusOK, "application/pdf", pdf)
+}
@@ -0,0 +1,100 @@
+package invoice
+
+import (
+	"time"
+)
+
+const (
+	ScopeReward = "REWARD"
+	ScopeDaily  = "DAILY"
+)
+
+// Invoice is the stored record of a generated invoice. The rendered
+// document and PDF are served from storage, never re-rendered.
+type Invoice struct {
+	ID             int       `json:"id"`
+	InvoiceNumber  string    `json:"invoice_number"`
+	FinancialYear  string    `json:"financial_year"`
+	Scope          string    `json:"scope"`
+	UserID         int       `json:"user_id"`
+	InvoiceDate    string    `json:"invoice_date"`
+	PeriodDate     string    `json:"period_date"`
+	SupplierGSTIN  string    `json:"supplier_gstin"`
+	RecipientGSTIN string    `json:"recipient_gstin,omitempty"`
+	PlaceOfSupply  string    `json:"place_of_supply"`
+	TaxableValue   float64   `json:"taxable_value"`
+	CGSTAmount     float64   `json:"cgst_amount"`
+	SGSTAmount     float64   `json:"sgst_amount"`
+	IGSTAmount     float64   `json:"igst_amount"`
+	TotalAmount    float64   `json:"total_amount"`
+	RewardEventIDs []int     `json:"reward_event_ids"`
+	GeneratedBy    string    `json:"generated_by"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type Party struct {
+	Name      string `json:"name"`
+	GSTIN     string `json:"gstin,omitempty"`
+	Address   string `json:"address,omitempty"`
+	Email     string `json:"email,omitempty"`
+	StateCode string `json:"state_code"`
+}
+
+// Line is one taxable fee charged on a reward.
+type Line struct {
+	RewardEventID int     `json:"reward_event_id"`
+	Date          string  `json:"date"`
+	StockSymbol   string  `json:"stock_symbol"`
+	Description   string  `json:"description"`
+	SACCode       string  `json:"sac_code"`
+	TaxableValue  float64 `json:"taxable_value"`
+}
+
+type Tax struct {
+	Tax    string  `json:"tax"`
+	Rate   float64 `json:"rate"`
+	Amount float64 `json:"amount"`
+}
+
+// Document is the invoice as rendered at generation time. Fees that GST is
+// not charged on (STT, stamp duty) are not a taxable supply and are left
+// out.
+type Document struct {
+	InvoiceNumber string    `json:"invoice_number"`
+	InvoiceDate   string    `json:"invoice_date"`
+	FinancialYear string    `json:"financial_year"`
+	Scope         string    `json:"scope"`
+	PeriodDate    string    `json:"period_date"`
+	Supplier      Party     `json:"supplier"`
+	Recipient     Party     `json:"recipient"`
+	PlaceOfSupply string    `json:"place_of_supply"`
+	ReverseCharge bool      `json:"reverse_charge"`
+	Lines         []Line    `json:"lines"`
+	TaxableValue  float64   `json:"taxable_value"`
+	Taxes         []Tax     `json:"taxes"`
+	TotalTax      float64   `json:"total_tax"`
+	TotalAmount   float64   `json:"total_amount"`
+	GeneratedAt   time.Time `json:"generated_at"`
+}
+
+type GenerateRewardInvoiceRequest struct {
+	GeneratedBy    string `json:"generated_by" binding:"required"`
+	RecipientGSTIN string `json:"recipient_gstin"`
+}
+
+// GenerateDailyInvoiceRequest invoices every reward of the user posted on
+// Date (YYYY-MM-DD) that is not on an invoice yet.
+type GenerateDailyInvoiceRequest struct {
+	UserID         int    `json:"user_id" binding:"required"`
+	Date           string `json:"date" binding:"required"`
+	GeneratedBy    string `json:"generated_by" binding:"required"`
+	RecipientGSTIN string `json:"recipient_gstin"`
+}
+
+type PaginatedInvoicesResponse struct {
+	Data       []Invoice `json:"data"`
+	Page       int       `json:"page"`
+	PageSize   int       `json:"page_size"`
+	TotalCount int       `json:"total_count"`
+	TotalPages int       `json:"total_pages"`
+}
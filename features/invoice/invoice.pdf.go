@@ -0,0 +1,179 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The PDF is plain text on A4 in the standard Courier fonts, which every
+// reader ships, so no font is embedded and columns line up.
+const (
+	pageWidth    = 595
+	pageHeight   = 842
+	marginLeft   = 40
+	marginTop    = 50
+	lineHeight   = 13
+	fontSize     = 9
+	linesPerPage = (pageHeight - 2*marginTop) / lineHeight
+)
+
+type pdfLine struct {
+	text string
+	bold bool
+}
+
+// renderPDF lays the document out as text lines and writes them as a PDF.
+// The output depends only on doc, so it is stored once and served as is.
+func renderPDF(doc *Document) []byte {
+	var lines []pdfLine
+	add := func(bold bool, format string, args ...interface{}) {
+		lines = append(lines, pdfLine{text: fmt.Sprintf(format, args...), bold: bold})
+	}
+
+	add(true, "TAX INVOICE")
+	add(false, "")
+	add(false, "Invoice No: %-24s Invoice Date: %s", doc.InvoiceNumber, doc.InvoiceDate)
+	add(false, "Financial Year: %-20s Place of Supply: %s", doc.FinancialYear, doc.PlaceOfSupply)
+	add(false, "Period: %-28s Reverse Charge: %s", doc.PeriodDate, yesNo(doc.ReverseCharge))
+	add(false, "")
+	add(true, "Supplier")
+	addParty(add, doc.Supplier)
+	add(false, "")
+	add(true, "Recipient")
+	addParty(add, doc.Recipient)
+	add(false, "")
+
+	add(true, "%-8s %-10s %-12s %-28s %-8s %14s", "Reward", "Date", "Stock", "Description", "SAC", "Taxable (INR)")
+	add(false, "%s", strings.Repeat("-", 85))
+	for _, line := range doc.Lines {
+		add(false, "%-8d %-10s %-12s %-28s %-8s %14s", line.RewardEventID, line.Date, truncate(line.StockSymbol, 12),
+			truncate(line.Description, 28), line.SACCode, formatPaise(line.TaxableValue))
+	}
+	add(false, "%s", strings.Repeat("-", 85))
+	add(false, "%70s %14s", "Taxable value", formatPaise(doc.TaxableValue))
+	for _, tax := range doc.Taxes {
+		add(false, "%70s %14s", fmt.Sprintf("%s @ %s%%", tax.Tax, strconv.FormatFloat(tax.Rate*100, 'f', -1, 64)), formatPaise(tax.Amount))
+	}
+	add(false, "%70s %14s", "Total tax", formatPaise(doc.TotalTax))
+	add(true, "%70s %14s", "Invoice total (INR)", formatPaise(doc.TotalAmount))
+	add(false, "")
+	add(false, "Generated %s. This is a computer generated invoice.", doc.GeneratedAt.Format("2006-01-02 15:04:05 MST"))
+
+	return writePDF(lines)
+}
+
+func addParty(add func(bool, string, ...interface{}), party Party) {
+	add(false, "  %s", party.Name)
+	if party.Address != "" {
+		add(false, "  %s", party.Address)
+	}
+	if party.Email != "" {
+		add(false, "  %s", party.Email)
+	}
+	gstin := party.GSTIN
+	if gstin == "" {
+		gstin = "Unregistered"
+	}
+	add(false, "  GSTIN: %-20s State Code: %s", gstin, party.StateCode)
+}
+
+// writePDF writes lines top to bottom, starting a new page when one is full.
+func writePDF(lines []pdfLine) []byte {
+	var pages [][]pdfLine
+	for start := 0; start < len(lines); start += linesPerPage {
+		end := start + linesPerPage
+		if end > len(lines) {
+			end = len(lines)
+		}
+		pages = append(pages, lines[start:end])
+	}
+
+	// Objects: 1 catalog, 2 page tree, 3 and 4 fonts, then a page and its
+	// content stream per page.
+	var objects []string
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 5+2*i)
+	}
+	objects = append(objects,
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Courier-Bold /Encoding /WinAnsiEncoding >>",
+	)
+
+	for i, page := range pages {
+		var content bytes.Buffer
+		content.WriteString("BT\n")
+		fmt.Fprintf(&content, "%d TL\n", lineHeight)
+		fmt.Fprintf(&content, "%d %d Td\n", marginLeft, pageHeight-marginTop)
+		for _, line := range page {
+			font := "F1"
+			if line.bold {
+				font = "F2"
+			}
+			fmt.Fprintf(&content, "/%s %d Tf\n(%s) Tj T*\n", font, fontSize, escapePDF(line.text))
+		}
+		content.WriteString("ET")
+
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+				pageWidth, pageHeight, 6+2*i),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.String()),
+		)
+	}
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return out.Bytes()
+}
+
+// escapePDF escapes a PDF string literal and replaces characters outside
+// printable ASCII, which the standard fonts cannot show reliably.
+func escapePDF(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r < 0x20 || r > 0x7e:
+			b.WriteByte('?')
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func truncate(text string, width int) string {
+	if len(text) <= width {
+		return text
+	}
+	return text[:width-1] + "~"
+}
+
+func formatPaise(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func yesNo(value bool) string {
+	if value {
+		return "Yes"
+	}
+	return "No"
+}
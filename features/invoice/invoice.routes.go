@@ -0,0 +1,16 @@
+package invoice
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(router *gin.RouterGroup, handler *InvoiceHandler) {
+	invoices := router.Group("/invoices")
+	{
+		invoices.GET("", handler.GetInvoices)
+		invoices.POST("/reward/:rewardId", handler.GenerateRewardInvoice)
+		invoices.POST("/daily", handler.GenerateDailyInvoice)
+		invoices.GET("/:id", handler.GetInvoice)
+		invoices.GET("/:id/pdf", handler.GetInvoicePDF)
+	}
+}
@@ -0,0 +1,555 @@
+package invoice
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"time"
+
+	"stocky-backend/config"
+	"stocky-backend/domain"
+	"stocky-backend/features/fee"
+	"stocky-backend/features/reward"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+// gstFeeType is the fee rule whose ledger lines are the GST an invoice
+// reports; the fees it is computed on are the taxable value.
+const gstFeeType = "GST"
+
+var gstinPattern = regexp.MustCompile(`^[0-9]{2}[A-Z]{5}[0-9]{4}[A-Z][1-9A-Z]Z[0-9A-Z]$`)
+
+type InvoiceService struct {
+	db     *sql.DB
+	config *config.InvoiceConfig
+}
+
+func NewInvoiceService(db *sql.DB, cfg *config.InvoiceConfig) *InvoiceService {
+	return &InvoiceService{db: db, config: cfg}
+}
+
+const invoiceColumns = `i.id, i.invoice_number, i.financial_year, i.scope, i.user_id,
+	TO_CHAR(i.invoice_date, 'YYYY-MM-DD'), TO_CHAR(i.period_date, 'YYYY-MM-DD'),
+	i.supplier_gstin, COALESCE(i.recipient_gstin, ''), i.place_of_supply,
+	i.taxable_value, i.cgst_amount, i.sgst_amount, i.igst_amount, i.total_amount,
+	ARRAY(SELECT ir.reward_event_id FROM invoice_rewards ir WHERE ir.invoice_id = i.id ORDER BY ir.reward_event_id),
+	i.generated_by, i.created_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanInvoice(row rowScanner, invoice *Invoice) error {
+	var rewardIDs pq.Int64Array
+	err := row.Scan(&invoice.ID, &invoice.InvoiceNumber, &invoice.FinancialYear, &invoice.Scope, &invoice.UserID,
+		&invoice.InvoiceDate, &invoice.PeriodDate, &invoice.SupplierGSTIN, &invoice.RecipientGSTIN, &invoice.PlaceOfSupply,
+		&invoice.TaxableValue, &invoice.CGSTAmount, &invoice.SGSTAmount, &invoice.IGSTAmount, &invoice.TotalAmount,
+		&rewardIDs, &invoice.GeneratedBy, &invoice.CreatedAt)
+	if err != nil {
+		return err
+	}
+	invoice.RewardEventIDs = make([]int, len(rewardIDs))
+	for i, id := range rewardIDs {
+		invoice.RewardEventIDs[i] = int(id)
+	}
+	return nil
+}
+
+// GenerateRewardInvoice invoices the fees of one posted reward.
+func (s *InvoiceService) GenerateRewardInvoice(rewardID int, req GenerateRewardInvoiceRequest) (*Invoice, error) {
+	if err := s.checkRequest(req.RecipientGSTIN); err != nil {
+		return nil, err
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		logrus.Errorf("Failed to begin transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var userID int
+	var eventType, status string
+	err = tx.QueryRow(`SELECT user_id, event_type, status FROM reward_events WHERE id = $1`, rewardID).
+		Scan(&userID, &eventType, &status)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeRewardNotFound, "reward event not found")
+	}
+	if err != nil {
+		logrus.Errorf("Failed to fetch reward event: %v", err)
+		return nil, err
+	}
+	if eventType != reward.EventTypeStockReward || status != reward.RewardStatusCompleted {
+		return nil, domain.BusinessRule(domain.CodeNothingToInvoice, "reward %d is a %s in status %s and has no posted fees", rewardID, eventType, status)
+	}
+
+	recipient, err := s.lockRecipient(tx, userID, req.RecipientGSTIN)
+	if err != nil {
+		return nil, err
+	}
+
+	var invoiceID int
+	err = tx.QueryRow(`SELECT invoice_id FROM invoice_rewards WHERE reward_event_id = $1`, rewardID).Scan(&invoiceID)
+	if err == nil {
+		return nil, domain.Conflict(domain.CodeInvoiceExists, "reward %d is already on invoice %d", rewardID, invoiceID).
+			WithMeta("invoice_id", invoiceID)
+	}
+	if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	var postedOn time.Time
+	err = tx.QueryRow(`
+		SELECT DATE(MIN(created_at)) FROM ledger_entries
+		WHERE reward_event_id = $1 AND account_type = 'INR_CASH' AND entry_type = 'CREDIT'
+	`, rewardID).Scan(&postedOn)
+	if err != nil {
+		logrus.Errorf("Failed to find posting date of reward %d: %v", rewardID, err)
+		return nil, err
+	}
+
+	invoice, err := s.generate(tx, ScopeReward, userID, recipient, postedOn, []int64{int64(rewardID)}, req.GeneratedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Invoice %s generated for reward %d by %s", invoice.InvoiceNumber, rewardID, req.GeneratedBy)
+	return invoice, nil
+}
+
+// GenerateDailyInvoice invoices, in one document, the fees of every reward
+// posted to the user on a past day that no invoice covers yet.
+func (s *InvoiceService) GenerateDailyInvoice(req GenerateDailyInvoiceRequest) (*Invoice, error) {
+	if err := s.checkRequest(req.RecipientGSTIN); err != nil {
+		return nil, err
+	}
+
+	day, err := time.Parse("2006-01-02", req.Date)
+	if err != nil {
+		return nil, domain.Validation(domain.CodeInvalidInvoice, "date %q must be YYYY-MM-DD", req.Date)
+	}
+	now := time.Now()
+	if !day.Before(time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)) {
+		return nil, domain.Validation(domain.CodeInvalidInvoice, "daily invoices can only be generated for days that have ended; %s has not", req.Date)
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		logrus.Errorf("Failed to begin transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	recipient, err := s.lockRecipient(tx, req.UserID, req.RecipientGSTIN)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(`
+		SELECT re.id FROM reward_events re
+		WHERE re.user_id = $1 AND re.event_type = $3 AND re.status = $4
+		AND EXISTS (
+			SELECT 1 FROM ledger_entries le
+			WHERE le.reward_event_id = re.id AND le.account_type = 'INR_CASH' AND le.entry_type = 'CREDIT'
+			AND DATE(le.created_at) = $2
+		)
+		AND NOT EXISTS (SELECT 1 FROM invoice_rewards ir WHERE ir.reward_event_id = re.id)
+		ORDER BY re.id
+	`, req.UserID, day, reward.EventTypeStockReward, reward.RewardStatusCompleted)
+	if err != nil {
+		logrus.Errorf("Failed to find rewards to invoice: %v", err)
+		return nil, err
+	}
+	var rewardIDs []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		rewardIDs = append(rewardIDs, id)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	if len(rewardIDs) == 0 {
+		return nil, domain.BusinessRule(domain.CodeNothingToInvoice, "user %d has no uninvoiced rewards posted on %s", req.UserID, req.Date)
+	}
+
+	invoice, err := s.generate(tx, ScopeDaily, req.UserID, recipient, day, rewardIDs, req.GeneratedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("Invoice %s generated for user %d, %d rewards on %s, by %s",
+		invoice.InvoiceNumber, req.UserID, len(rewardIDs), req.Date, req.GeneratedBy)
+	return invoice, nil
+}
+
+func (s *InvoiceService) checkRequest(recipientGSTIN string) error {
+	if s.config.SupplierGSTIN == "" {
+		return domain.BusinessRule(domain.CodeInvalidInvoice, "INVOICE_SUPPLIER_GSTIN is not configured; invoices cannot be issued")
+	}
+	if recipientGSTIN != "" && !gstinPattern.MatchString(recipientGSTIN) {
+		return domain.Validation(domain.CodeInvalidInvoice, "recipient_gstin %q is not a valid GSTIN", recipientGSTIN)
+	}
+	return nil
+}
+
+// lockRecipient locks the user row, which serialises invoice generation per
+// user, and returns the recipient printed on the invoice.
+func (s *InvoiceService) lockRecipient(tx *sql.Tx, userID int, gstin string) (Party, error) {
+	var recipient Party
+	var erased bool
+	err := tx.QueryRow(`
+		SELECT name, email, erased_at IS NOT NULL FROM users WHERE id = $1 FOR UPDATE
+	`, userID).Scan(&recipient.Name, &recipient.Email, &erased)
+	if err == sql.ErrNoRows {
+		return recipient, domain.NotFound(domain.CodeUserNotFound, "user %d not found", userID)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to fetch invoice recipient: %v", err)
+		return recipient, err
+	}
+	if erased {
+		return recipient, domain.BusinessRule(domain.CodeUserErased, "user %d has been erased", userID)
+	}
+
+	// Services to an unregistered recipient whose address is unknown are
+	// supplied where the supplier is.
+	recipient.GSTIN = gstin
+	recipient.StateCode = s.config.SupplierStateCode
+	if gstin != "" {
+		recipient.StateCode = gstin[:2]
+	}
+	return recipient, nil
+}
+
+// taxRule is how a fee version taxes fees: the GST account, its rate and
+// the accounts it is charged on.
+type taxRule struct {
+	gstAccount      string
+	rate            float64
+	taxableAccounts map[string]bool
+}
+
+func loadTaxRule(tx *sql.Tx, versionID int) (*taxRule, error) {
+	rules, err := fee.Rules(tx, versionID)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make(map[string]string, len(rules))
+	for _, rule := range rules {
+		accounts[rule.FeeType] = rule.AccountType
+	}
+	for _, rule := range rules {
+		if rule.FeeType != gstFeeType {
+			continue
+		}
+		tax := &taxRule{gstAccount: rule.AccountType, rate: rule.Percentage, taxableAccounts: make(map[string]bool)}
+		for _, baseType := range rule.BaseFeeTypes {
+			tax.taxableAccounts[accounts[baseType]] = true
+		}
+		return tax, nil
+	}
+	return nil, nil
+}
+
+// generate builds the document from the rewards' booked fee lines, numbers
+// it, renders it and stores it with the rewards it covers.
+func (s *InvoiceService) generate(tx *sql.Tx, scope string, userID int, recipient Party, periodDate time.Time,
+	rewardIDs []int64, generatedBy string) (*Invoice, error) {
+	rows, err := tx.Query(`
+		SELECT re.id, re.fee_version_id, s.symbol, le.account_type, COALESCE(le.description, le.account_type),
+		       le.amount, TO_CHAR(le.created_at, 'YYYY-MM-DD')
+		FROM reward_events re
+		JOIN stocks s ON s.id = re.stock_id
+		JOIN ledger_entries le ON le.reward_event_id = re.id
+		WHERE re.id = ANY($1) AND le.entry_type = 'CREDIT' AND le.amount IS NOT NULL AND le.account_type <> 'INR_CASH'
+		ORDER BY re.id, le.id
+	`, pq.Array(rewardIDs))
+	if err != nil {
+		logrus.Errorf("Failed to query fee lines to invoice: %v", err)
+		return nil, err
+	}
+
+	type feeLine struct {
+		rewardID    int
+		versionID   sql.NullInt64
+		symbol      string
+		account     string
+		description string
+		amount      float64
+		date        string
+	}
+	var feeLines []feeLine
+	for rows.Next() {
+		var line feeLine
+		err := rows.Scan(&line.rewardID, &line.versionID, &line.symbol, &line.account, &line.description, &line.amount, &line.date)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		feeLines = append(feeLines, line)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	taxRules := make(map[int64]*taxRule)
+	lines := []Line{}
+	taxByRate := make(map[float64]float64)
+	for _, line := range feeLines {
+		if !line.versionID.Valid {
+			continue
+		}
+		tax, cached := taxRules[line.versionID.Int64]
+		if !cached {
+			if tax, err = loadTaxRule(tx, int(line.versionID.Int64)); err != nil {
+				return nil, err
+			}
+			taxRules[line.versionID.Int64] = tax
+		}
+		if tax == nil {
+			continue
+		}
+
+		switch {
+		case line.account == tax.gstAccount:
+			taxByRate[tax.rate] += line.amount
+		case tax.taxableAccounts[line.account]:
+			lines = append(lines, Line{
+				RewardEventID: line.rewardID,
+				Date:          line.date,
+				StockSymbol:   line.symbol,
+				Description:   line.description,
+				SACCode:       s.config.SACCode,
+				TaxableValue:  roundPaise(line.amount),
+			})
+		}
+	}
+	if len(lines) == 0 {
+		return nil, domain.BusinessRule(domain.CodeNothingToInvoice, "no GST was charged on the fees of rewards %v", rewardIDs)
+	}
+
+	invoiceDate := time.Now()
+	fyStart := financialYearStart(invoiceDate)
+	financialYear := fmt.Sprintf("%d-%02d", fyStart.Year(), (fyStart.Year()+1)%100)
+
+	var sequence int
+	err = tx.QueryRow(`
+		INSERT INTO invoice_sequences (financial_year, last_number) VALUES ($1, 1)
+		ON CONFLICT (financial_year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+		RETURNING last_number
+	`, financialYear).Scan(&sequence)
+	if err != nil {
+		logrus.Errorf("Failed to allocate invoice number: %v", err)
+		return nil, err
+	}
+
+	doc := Document{
+		InvoiceNumber: fmt.Sprintf("%s/%02d%02d/%06d", s.config.NumberPrefix, fyStart.Year()%100, (fyStart.Year()+1)%100, sequence),
+		InvoiceDate:   invoiceDate.Format("2006-01-02"),
+		FinancialYear: financialYear,
+		Scope:         scope,
+		PeriodDate:    periodDate.Format("2006-01-02"),
+		Supplier: Party{
+			Name:      s.config.SupplierName,
+			GSTIN:     s.config.SupplierGSTIN,
+			Address:   s.config.SupplierAddress,
+			StateCode: s.config.SupplierStateCode,
+		},
+		Recipient:     recipient,
+		PlaceOfSupply: recipient.StateCode,
+		Lines:         lines,
+		Taxes:         []Tax{},
+		GeneratedAt:   invoiceDate.UTC().Truncate(time.Second),
+	}
+	for _, line := range lines {
+		doc.TaxableValue += line.TaxableValue
+	}
+	doc.TaxableValue = roundPaise(doc.TaxableValue)
+
+	var cgst, sgst, igst float64
+	rates := make([]float64, 0, len(taxByRate))
+	for rate := range taxByRate {
+		rates = append(rates, rate)
+	}
+	sort.Float64s(rates)
+	intraState := doc.PlaceOfSupply == doc.Supplier.StateCode
+	for _, rate := range rates {
+		amount := roundPaise(taxByRate[rate])
+		if intraState {
+			half := roundPaise(amount / 2)
+			doc.Taxes = append(doc.Taxes,
+				Tax{Tax: "CGST", Rate: rate / 2, Amount: half},
+				Tax{Tax: "SGST", Rate: rate / 2, Amount: roundPaise(amount - half)})
+			cgst += half
+			sgst += roundPaise(amount - half)
+		} else {
+			doc.Taxes = append(doc.Taxes, Tax{Tax: "IGST", Rate: rate, Amount: amount})
+			igst += amount
+		}
+		doc.TotalTax += amount
+	}
+	doc.TotalTax = roundPaise(doc.TotalTax)
+	doc.TotalAmount = roundPaise(doc.TaxableValue + doc.TotalTax)
+
+	document, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	pdf := renderPDF(&doc)
+
+	var invoiceID int
+	err = tx.QueryRow(`
+		INSERT INTO invoices (invoice_number, financial_year, sequence_number, scope, user_id, invoice_date, period_date,
+		                      supplier_gstin, recipient_gstin, place_of_supply, taxable_value,
+		                      cgst_amount, sgst_amount, igst_amount, total_amount, document, pdf, generated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NULLIF($9, ''), $10, $11, $12, $13, $14, $15, $16, $17, $18)
+		RETURNING id
+	`, doc.InvoiceNumber, financialYear, sequence, scope, userID, doc.InvoiceDate, doc.PeriodDate,
+		doc.Supplier.GSTIN, recipient.GSTIN, doc.PlaceOfSupply, doc.TaxableValue,
+		roundPaise(cgst), roundPaise(sgst), roundPaise(igst), doc.TotalAmount, string(document), pdf, generatedBy).Scan(&invoiceID)
+	if err != nil {
+		logrus.Errorf("Failed to store invoice: %v", err)
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO invoice_rewards (invoice_id, reward_event_id)
+		SELECT $1, UNNEST($2::int[])
+	`, invoiceID, pq.Array(rewardIDs))
+	if isUniqueViolation(err) {
+		return nil, domain.Conflict(domain.CodeInvoiceExists, "a reward in this invoice was invoiced concurrently")
+	}
+	if err != nil {
+		logrus.Errorf("Failed to link invoice rewards: %v", err)
+		return nil, err
+	}
+
+	return getInvoice(tx, invoiceID)
+}
+
+func (s *InvoiceService) GetInvoices(userID, page, pageSize int) (*PaginatedInvoicesResponse, error) {
+	var totalCount int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM invoices WHERE $1 = 0 OR user_id = $1`, userID).Scan(&totalCount)
+	if err != nil {
+		logrus.Errorf("Failed to count invoices: %v", err)
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT `+invoiceColumns+`
+		FROM invoices i
+		WHERE $1 = 0 OR i.user_id = $1
+		ORDER BY i.id DESC
+		LIMIT $2 OFFSET $3
+	`, userID, pageSize, (page-1)*pageSize)
+	if err != nil {
+		logrus.Errorf("Failed to query invoices: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	invoices := []Invoice{}
+	for rows.Next() {
+		var invoice Invoice
+		if err := scanInvoice(rows, &invoice); err != nil {
+			logrus.Errorf("Failed to scan invoice: %v", err)
+			return nil, err
+		}
+		invoices = append(invoices, invoice)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return &PaginatedInvoicesResponse{
+		Data:       invoices,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: totalCount,
+		TotalPages: (totalCount + pageSize - 1) / pageSize,
+	}, nil
+}
+
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func getInvoice(q queryer, invoiceID int) (*Invoice, error) {
+	var invoice Invoice
+	err := scanInvoice(q.QueryRow(`SELECT `+invoiceColumns+` FROM invoices i WHERE i.id = $1`, invoiceID), &invoice)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeInvoiceNotFound, "invoice %d not found", invoiceID)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to fetch invoice: %v", err)
+		return nil, err
+	}
+	return &invoice, nil
+}
+
+// GetDocument returns the invoice JSON exactly as stored at generation.
+func (s *InvoiceService) GetDocument(invoiceID int) (json.RawMessage, error) {
+	var document string
+	err := s.db.QueryRow(`SELECT document FROM invoices WHERE id = $1`, invoiceID).Scan(&document)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeInvoiceNotFound, "invoice %d not found", invoiceID)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to fetch invoice document: %v", err)
+		return nil, err
+	}
+	return json.RawMessage(document), nil
+}
+
+// GetPDF returns the stored PDF and the invoice number to name it by.
+func (s *InvoiceService) GetPDF(invoiceID int) (string, []byte, error) {
+	var number string
+	var pdf []byte
+	err := s.db.QueryRow(`SELECT invoice_number, pdf FROM invoices WHERE id = $1`, invoiceID).Scan(&number, &pdf)
+	if err == sql.ErrNoRows {
+		return "", nil, domain.NotFound(domain.CodeInvoiceNotFound, "invoice %d not found", invoiceID)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to fetch invoice PDF: %v", err)
+		return "", nil, err
+	}
+	return number, pdf, nil
+}
+
+// financialYearStart returns 1 April of the Indian financial year t falls in.
+func financialYearStart(t time.Time) time.Time {
+	year := t.Year()
+	if t.Month() < time.April {
+		year--
+	}
+	return time.Date(year, time.April, 1, 0, 0, 0, 0, time.UTC)
+}
+
+func roundPaise(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
@@ -0,0 +1,96 @@
+package invoice
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"stocky-backend/config"
+	"stocky-backend/features/reward"
+
+	_ "github.com/lib/pq"
+)
+
+// testDB connects to the database named by TEST_DATABASE_URL, which must
+// have been migrated (make migrate). The tests add their own users and
+// stocks and leave them behind, so point it at a disposable database.
+func testDB(t *testing.T) *sql.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	db, err := sql.Open("postgres", url)
+	if err != nil {
+		t.Fatalf("open database: %v", err)
+	}
+	if err = db.Ping(); err != nil {
+		t.Fatalf("ping database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestGenerateRewardInvoiceForCreatedReward(t *testing.T) {
+	db := testDB(t)
+	suffix := time.Now().UnixNano()
+
+	var userID int
+	err := db.QueryRow(`
+		INSERT INTO users (email, name, phone, is_active) VALUES ($1, 'Invoice Test', '9000000000', true) RETURNING id
+	`, fmt.Sprintf("invoice-test-%d@example.com", suffix)).Scan(&userID)
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+
+	symbol := fmt.Sprintf("INV%d", suffix%1000000000)
+	_, err = db.Exec(`
+		INSERT INTO stocks (symbol, name, exchange, current_price, is_active) VALUES ($1, 'Invoice Test Ltd', 'NSE', 2500, true)
+	`, symbol)
+	if err != nil {
+		t.Fatalf("create stock: %v", err)
+	}
+
+	rewards := reward.NewRewardService(db, &config.RewardConfig{KYCPolicy: config.KYCPolicyNone})
+	created, err := rewards.CreateReward(reward.CreateRewardRequest{
+		UserID:      userID,
+		StockSymbol: symbol,
+		Quantity:    2,
+		Description: "invoice test",
+		Force:       true,
+		RequestedBy: "invoice-test",
+	})
+	if err != nil {
+		t.Fatalf("create reward: %v", err)
+	}
+	if created.Status != reward.RewardStatusCompleted {
+		t.Fatalf("reward status = %s, want %s", created.Status, reward.RewardStatusCompleted)
+	}
+
+	invoices := NewInvoiceService(db, &config.InvoiceConfig{
+		SupplierName:      "Stocky Technologies Private Limited",
+		SupplierGSTIN:     "29ABCDE1234F1Z5",
+		SupplierStateCode: "29",
+		NumberPrefix:      "TST",
+		SACCode:           "997152",
+	})
+	invoice, err := invoices.GenerateRewardInvoice(created.ID, GenerateRewardInvoiceRequest{GeneratedBy: "invoice-test"})
+	if err != nil {
+		t.Fatalf("invoice reward %d: %v", created.ID, err)
+	}
+
+	if invoice.Scope != ScopeReward || invoice.UserID != userID {
+		t.Errorf("invoice scope/user = %s/%d, want %s/%d", invoice.Scope, invoice.UserID, ScopeReward, userID)
+	}
+	if len(invoice.RewardEventIDs) != 1 || invoice.RewardEventIDs[0] != created.ID {
+		t.Errorf("invoice rewards = %v, want [%d]", invoice.RewardEventIDs, created.ID)
+	}
+
+	_, err = invoices.GenerateRewardInvoice(created.ID, GenerateRewardInvoiceRequest{GeneratedBy: "invoice-test"})
+	if err == nil {
+		t.Errorf("invoicing reward %d twice succeeded", created.ID)
+	}
+}
@@ -0,0 +1,157 @@
+package kyc
+
+import (
+	"net/http"
+	"strconv"
+
+	"stocky-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type KYCHandler struct {
+	service *KYCService
+}
+
+func NewKYCHandler(service *KYCService) *KYCHandler {
+	return &KYCHandler{service: service}
+}
+
+func (h *KYCHandler) GetKYCDetails(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+		return
+	}
+
+	details, err := h.service.GetKYCDetails(userID)
+	if err != nil {
+		logrus.Errorf("Error getting KYC details: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve KYC details"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": details})
+}
+
+func (h *KYCHandler) SubmitKYC(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+		return
+	}
+
+	var req SubmitKYCRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	details, err := h.service.SubmitKYC(userID, req)
+	if err != nil {
+		logrus.Errorf("Error submitting KYC: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to submit KYC"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "KYC submitted for verification",
+		"data":    details,
+	})
+}
+
+func (h *KYCHandler) VerifyKYC(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+		return
+	}
+
+	var req VerifyKYCRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	details, err := h.service.VerifyKYC(userID, req)
+	if err != nil {
+		logrus.Errorf("Error verifying KYC: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to verify KYC"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "KYC verified successfully",
+		"data":    details,
+	})
+}
+
+func (h *KYCHandler) RejectKYC(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+		return
+	}
+
+	var req RejectKYCRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	details, err := h.service.RejectKYC(userID, req)
+	if err != nil {
+		logrus.Errorf("Error rejecting KYC: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to reject KYC"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "KYC rejected",
+		"data":    details,
+	})
+}
+
+func (h *KYCHandler) GetDematAccounts(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+		return
+	}
+
+	accounts, err := h.service.GetDematAccounts(userID)
+	if err != nil {
+		logrus.Errorf("Error getting demat accounts: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve demat accounts"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": accounts})
+}
+
+func (h *KYCHandler) LinkDematAccount(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+		return
+	}
+
+	var req LinkDematAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	account, err := h.service.LinkDematAccount(userID, req)
+	if err != nil {
+		logrus.Errorf("Error linking demat account: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to link demat account"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Demat account linked successfully",
+		"data":    account,
+	})
+}
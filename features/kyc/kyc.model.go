@@ -0,0 +1,58 @@
+package kyc
+
+import (
+	"time"
+)
+
+const (
+	StatusNotStarted  = "NOT_STARTED"
+	StatusPending     = "PENDING"
+	StatusVerified    = "KYC_VERIFIED"
+	StatusRejected    = "KYC_REJECTED"
+	DepositoryNSDL    = "NSDL"
+	DepositoryCDSL    = "CDSL"
+	DematStatusActive = "ACTIVE"
+)
+
+type KYCDetails struct {
+	UserID          int            `json:"user_id"`
+	PAN             string         `json:"pan,omitempty"`
+	KYCStatus       string         `json:"kyc_status"`
+	SubmittedAt     *time.Time     `json:"kyc_submitted_at,omitempty"`
+	VerifiedAt      *time.Time     `json:"kyc_verified_at,omitempty"`
+	ReviewedBy      string         `json:"kyc_reviewed_by,omitempty"`
+	RejectionReason string         `json:"kyc_rejection_reason,omitempty"`
+	DematAccounts   []DematAccount `json:"demat_accounts"`
+	ReleasedRewards int            `json:"released_rewards,omitempty"`
+}
+
+type DematAccount struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"user_id"`
+	Depository string    `json:"depository"`
+	DPID       string    `json:"dp_id"`
+	ClientID   string    `json:"client_id"`
+	IsPrimary  bool      `json:"is_primary"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+type SubmitKYCRequest struct {
+	PAN string `json:"pan" binding:"required"`
+}
+
+type VerifyKYCRequest struct {
+	VerifiedBy string `json:"verified_by" binding:"required"`
+}
+
+type RejectKYCRequest struct {
+	RejectedBy string `json:"rejected_by" binding:"required"`
+	Reason     string `json:"reason" binding:"required"`
+}
+
+type LinkDematAccountRequest struct {
+	Depository string `json:"depository" binding:"required,oneof=NSDL CDSL"`
+	DPID       string `json:"dp_id" binding:"required"`
+	ClientID   string `json:"client_id" binding:"required"`
+}
@@ -0,0 +1,17 @@
+package kyc
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(router *gin.RouterGroup, handler *KYCHandler) {
+	users := router.Group("/users/:id")
+	{
+		users.GET("/kyc", handler.GetKYCDetails)
+		users.POST("/kyc", handler.SubmitKYC)
+		users.POST("/kyc/verify", handler.VerifyKYC)
+		users.POST("/kyc/reject", handler.RejectKYC)
+		users.GET("/demat-accounts", handler.GetDematAccounts)
+		users.POST("/demat-accounts", handler.LinkDematAccount)
+	}
+}
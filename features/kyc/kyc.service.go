@@ -0,0 +1,307 @@
+package kyc
+
+import (
+	"database/sql"
+	"regexp"
+	"strings"
+
+	"stocky-backend/domain"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+var (
+	panPattern          = regexp.MustCompile(`^[A-Z]{5}[0-9]{4}[A-Z]$`)
+	nsdlDPIDPattern     = regexp.MustCompile(`^IN[0-9]{6}$`)
+	nsdlClientIDPattern = regexp.MustCompile(`^[0-9]{8}$`)
+	cdslDPIDPattern     = regexp.MustCompile(`^[0-9]{8}$`)
+	cdslClientIDPattern = regexp.MustCompile(`^[0-9]{8}$`)
+)
+
+// RewardReleaser posts rewards that were parked as PENDING_KYC once the user
+// is verified. It is implemented by the reward service.
+type RewardReleaser interface {
+	ReleasePendingKYCRewards(userID int) (int, error)
+}
+
+type KYCService struct {
+	db       *sql.DB
+	releaser RewardReleaser
+}
+
+func NewKYCService(db *sql.DB, releaser RewardReleaser) *KYCService {
+	return &KYCService{db: db, releaser: releaser}
+}
+
+func (s *KYCService) GetKYCDetails(userID int) (*KYCDetails, error) {
+	var details KYCDetails
+	err := s.db.QueryRow(`
+		SELECT id, COALESCE(pan, ''), kyc_status, kyc_submitted_at, kyc_verified_at,
+		       COALESCE(kyc_reviewed_by, ''), COALESCE(kyc_rejection_reason, '')
+		FROM users WHERE id = $1
+	`, userID).Scan(
+		&details.UserID, &details.PAN, &details.KYCStatus, &details.SubmittedAt,
+		&details.VerifiedAt, &details.ReviewedBy, &details.RejectionReason,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeUserNotFound, "user %d not found", userID)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to query KYC details: %v", err)
+		return nil, err
+	}
+
+	details.DematAccounts, err = s.GetDematAccounts(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &details, nil
+}
+
+func (s *KYCService) SubmitKYC(userID int, req SubmitKYCRequest) (*KYCDetails, error) {
+	pan := strings.ToUpper(strings.TrimSpace(req.PAN))
+	if !panPattern.MatchString(pan) {
+		return nil, domain.Validation(domain.CodeInvalidPAN, "invalid PAN %q: expected format AAAAA9999A", pan)
+	}
+
+	from := []string{StatusNotStarted, StatusRejected}
+	if err := s.transition(userID, from, StatusPending); err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.Exec(`
+		UPDATE users
+		SET pan = $1, kyc_status = $2, kyc_submitted_at = NOW(), kyc_verified_at = NULL,
+		    kyc_reviewed_by = NULL, kyc_rejection_reason = NULL, updated_at = NOW()
+		WHERE id = $3 AND kyc_status = ANY($4)
+	`, pan, StatusPending, userID, pq.Array(from))
+	if isUniqueViolation(err) {
+		return nil, domain.Conflict(domain.CodePANAlreadyExists, "PAN %s is already registered to another user", pan)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to submit KYC: %v", err)
+		return nil, err
+	}
+	if err = s.checkTransitioned(result, userID, from, StatusPending); err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("KYC submitted for user %d", userID)
+	return s.GetKYCDetails(userID)
+}
+
+// VerifyKYC marks the user KYC_VERIFIED and then releases any rewards parked
+// while verification was pending. A failed release is logged but does not undo
+// the verification; it can be retried through the reward API. Only the request
+// that changed the status releases, so concurrent verifications post each
+// parked reward once.
+func (s *KYCService) VerifyKYC(userID int, req VerifyKYCRequest) (*KYCDetails, error) {
+	from := []string{StatusPending}
+	if err := s.transition(userID, from, StatusVerified); err != nil {
+		return nil, err
+	}
+
+	var hasDemat bool
+	err := s.db.QueryRow(`
+		SELECT EXISTS(SELECT 1 FROM demat_accounts WHERE user_id = $1 AND status = $2)
+	`, userID, DematStatusActive).Scan(&hasDemat)
+	if err != nil {
+		logrus.Errorf("Failed to check demat accounts: %v", err)
+		return nil, err
+	}
+	if !hasDemat {
+		return nil, domain.BusinessRule(domain.CodeDematAccountRequired,
+			"user %d must link an active demat account before KYC can be verified", userID)
+	}
+
+	result, err := s.db.Exec(`
+		UPDATE users
+		SET kyc_status = $1, kyc_verified_at = NOW(), kyc_reviewed_by = $2, updated_at = NOW()
+		WHERE id = $3 AND kyc_status = ANY($4)
+	`, StatusVerified, req.VerifiedBy, userID, pq.Array(from))
+	if err != nil {
+		logrus.Errorf("Failed to verify KYC: %v", err)
+		return nil, err
+	}
+	if err = s.checkTransitioned(result, userID, from, StatusVerified); err != nil {
+		return nil, err
+	}
+	logrus.Infof("KYC verified for user %d by %s", userID, req.VerifiedBy)
+
+	released, err := s.releaser.ReleasePendingKYCRewards(userID)
+	if err != nil {
+		logrus.Errorf("Failed to release rewards parked for KYC for user %d: %v", userID, err)
+	}
+
+	details, err := s.GetKYCDetails(userID)
+	if err != nil {
+		return nil, err
+	}
+	details.ReleasedRewards = released
+	return details, nil
+}
+
+func (s *KYCService) RejectKYC(userID int, req RejectKYCRequest) (*KYCDetails, error) {
+	from := []string{StatusPending}
+	if err := s.transition(userID, from, StatusRejected); err != nil {
+		return nil, err
+	}
+
+	result, err := s.db.Exec(`
+		UPDATE users
+		SET kyc_status = $1, kyc_reviewed_by = $2, kyc_rejection_reason = $3, updated_at = NOW()
+		WHERE id = $4 AND kyc_status = ANY($5)
+	`, StatusRejected, req.RejectedBy, req.Reason, userID, pq.Array(from))
+	if err != nil {
+		logrus.Errorf("Failed to reject KYC: %v", err)
+		return nil, err
+	}
+	if err = s.checkTransitioned(result, userID, from, StatusRejected); err != nil {
+		return nil, err
+	}
+
+	logrus.Infof("KYC rejected for user %d by %s", userID, req.RejectedBy)
+	return s.GetKYCDetails(userID)
+}
+
+func (s *KYCService) GetDematAccounts(userID int) ([]DematAccount, error) {
+	rows, err := s.db.Query(`
+		SELECT id, user_id, depository, dp_id, client_id, is_primary, status, created_at, updated_at
+		FROM demat_accounts
+		WHERE user_id = $1
+		ORDER BY is_primary DESC, created_at
+	`, userID)
+	if err != nil {
+		logrus.Errorf("Failed to query demat accounts: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []DematAccount{}
+	for rows.Next() {
+		var account DematAccount
+		err := rows.Scan(
+			&account.ID, &account.UserID, &account.Depository, &account.DPID, &account.ClientID,
+			&account.IsPrimary, &account.Status, &account.CreatedAt, &account.UpdatedAt,
+		)
+		if err != nil {
+			logrus.Errorf("Failed to scan demat account: %v", err)
+			return nil, err
+		}
+		accounts = append(accounts, account)
+	}
+
+	return accounts, nil
+}
+
+func (s *KYCService) LinkDematAccount(userID int, req LinkDematAccountRequest) (*DematAccount, error) {
+	dpID := strings.ToUpper(strings.TrimSpace(req.DPID))
+	clientID := strings.TrimSpace(req.ClientID)
+	if err := validateDematAccount(req.Depository, dpID, clientID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.getKYCStatus(userID); err != nil {
+		return nil, err
+	}
+
+	var account DematAccount
+	err := s.db.QueryRow(`
+		INSERT INTO demat_accounts (user_id, depository, dp_id, client_id, is_primary)
+		VALUES ($1, $2, $3, $4, NOT EXISTS(
+			SELECT 1 FROM demat_accounts WHERE user_id = $1 AND is_primary AND status = 'ACTIVE'
+		))
+		RETURNING id, user_id, depository, dp_id, client_id, is_primary, status, created_at, updated_at
+	`, userID, req.Depository, dpID, clientID).Scan(
+		&account.ID, &account.UserID, &account.Depository, &account.DPID, &account.ClientID,
+		&account.IsPrimary, &account.Status, &account.CreatedAt, &account.UpdatedAt,
+	)
+	if isUniqueViolation(err) {
+		return nil, domain.Conflict(domain.CodeDematAccountExists,
+			"demat account %s/%s/%s is already linked", req.Depository, dpID, clientID)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to link demat account: %v", err)
+		return nil, err
+	}
+
+	logrus.Infof("Demat account %d linked to user %d", account.ID, userID)
+	return &account, nil
+}
+
+// transition checks that the user's current KYC status allows moving to the
+// target status.
+func (s *KYCService) transition(userID int, from []string, to string) error {
+	current, err := s.getKYCStatus(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, status := range from {
+		if current == status {
+			return nil
+		}
+	}
+	return domain.Conflict(domain.CodeInvalidKYCTransition,
+		"cannot move KYC for user %d from %s to %s", userID, current, to)
+}
+
+// checkTransitioned reports a conflict when a status UPDATE guarded by from
+// changed no row: another request moved the status after transition checked
+// it.
+func (s *KYCService) checkTransitioned(result sql.Result, userID int, from []string, to string) error {
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected > 0 {
+		return nil
+	}
+	if err = s.transition(userID, from, to); err != nil {
+		return err
+	}
+	return domain.Conflict(domain.CodeInvalidKYCTransition,
+		"KYC for user %d changed while moving it to %s", userID, to)
+}
+
+func (s *KYCService) getKYCStatus(userID int) (string, error) {
+	var status string
+	var erased bool
+	err := s.db.QueryRow(`SELECT kyc_status, erased_at IS NOT NULL FROM users WHERE id = $1`, userID).Scan(&status, &erased)
+	if err == sql.ErrNoRows {
+		return "", domain.NotFound(domain.CodeUserNotFound, "user %d not found", userID)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to query KYC status: %v", err)
+		return "", err
+	}
+	if erased {
+		return "", domain.BusinessRule(domain.CodeUserErased, "user %d has been erased", userID)
+	}
+	return status, nil
+}
+
+// validateDematAccount checks depository-specific identifier formats: NSDL DP
+// IDs are "IN" plus 6 digits, CDSL DP IDs are 8 digits, and both use 8-digit
+// client IDs.
+func validateDematAccount(depository, dpID, clientID string) error {
+	var valid bool
+	switch depository {
+	case DepositoryNSDL:
+		valid = nsdlDPIDPattern.MatchString(dpID) && nsdlClientIDPattern.MatchString(clientID)
+	case DepositoryCDSL:
+		valid = cdslDPIDPattern.MatchString(dpID) && cdslClientIDPattern.MatchString(clientID)
+	}
+	if !valid {
+		return domain.Validation(domain.CodeInvalidDematAccount,
+			"invalid %s demat account: dp_id %q, client_id %q", depository, dpID, clientID)
+	}
+	return nil
+}
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
@@ -0,0 +1,80 @@
+package privacy
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"stocky-backend/domain"
+	"stocky-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type PrivacyHandler struct {
+	service *PrivacyService
+}
+
+func NewPrivacyHandler(service *PrivacyService) *PrivacyHandler {
+	return &PrivacyHandler{service: service}
+}
+
+func (h *PrivacyHandler) ExportUserData(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+		return
+	}
+
+	format := c.DefaultQuery("format", ExportFormatJSON)
+	if format != ExportFormatJSON && format != ExportFormatZIP {
+		c.Error(domain.Validation(domain.CodeInvalidExportFormat, "unsupported export format %q: use json or zip", format))
+		return
+	}
+
+	export, err := h.service.ExportUserData(userID)
+	if err != nil {
+		logrus.Errorf("Error exporting user data: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to export user data"))
+		return
+	}
+
+	if format == ExportFormatJSON {
+		c.JSON(http.StatusOK, gin.H{"data": export})
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-export.zip"`, userID))
+	c.Status(http.StatusOK)
+	if err := WriteExportZIP(c.Writer, export); err != nil {
+		logrus.Errorf("Error writing user data export: %v", err)
+	}
+}
+
+func (h *PrivacyHandler) EraseUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+		return
+	}
+
+	var req EraseUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	result, err := h.service.EraseUser(userID, req)
+	if err != nil {
+		logrus.Errorf("Error erasing user: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to erase user"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User personal data erased; financial records retained",
+		"data":    result,
+	})
+}
@@ -0,0 +1,89 @@
+package privacy
+
+import (
+	"time"
+)
+
+const (
+	ExportFormatJSON = "json"
+	ExportFormatZIP  = "zip"
+)
+
+type UserDataExport struct {
+	GeneratedAt   time.Time           `json:"generated_at"`
+	Profile       ExportProfile       `json:"profile"`
+	DematAccounts []ExportDemat       `json:"demat_accounts"`
+	Rewards       []ExportReward      `json:"rewards"`
+	LedgerEntries []ExportLedgerEntry `json:"ledger_entries"`
+	Holdings      []ExportHolding     `json:"holdings"`
+}
+
+type ExportProfile struct {
+	ID             int        `json:"id"`
+	Email          string     `json:"email"`
+	Name           string     `json:"name"`
+	Phone          string     `json:"phone"`
+	PAN            string     `json:"pan,omitempty"`
+	KYCStatus      string     `json:"kyc_status"`
+	KYCSubmittedAt *time.Time `json:"kyc_submitted_at,omitempty"`
+	KYCVerifiedAt  *time.Time `json:"kyc_verified_at,omitempty"`
+	IsActive       bool       `json:"is_active"`
+	DeactivatedAt  *time.Time `json:"deactivated_at,omitempty"`
+	ErasedAt       *time.Time `json:"erased_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+type ExportDemat struct {
+	Depository string    `json:"depository"`
+	DPID       string    `json:"dp_id"`
+	ClientID   string    `json:"client_id"`
+	IsPrimary  bool      `json:"is_primary"`
+	Status     string    `json:"status"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type ExportReward struct {
+	ID          int       `json:"id"`
+	StockSymbol string    `json:"stock_symbol"`
+	Quantity    float64   `json:"quantity"`
+	StockPrice  float64   `json:"stock_price"`
+	TotalValue  float64   `json:"total_value"`
+	EventType   string    `json:"event_type"`
+	Status      string    `json:"status"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ExportLedgerEntry struct {
+	ID                int       `json:"id"`
+	RewardEventID     *int64    `json:"reward_event_id,omitempty"`
+	CorporateActionID *int64    `json:"corporate_action_id,omitempty"`
+	EntryType         string    `json:"entry_type"`
+	AccountType       string    `json:"account_type"`
+	StockSymbol       string    `json:"stock_symbol,omitempty"`
+	Quantity          *float64  `json:"quantity,omitempty"`
+	Amount            *float64  `json:"amount,omitempty"`
+	Description       string    `json:"description"`
+	CreatedAt         time.Time `json:"created_at"`
+}
+
+type ExportHolding struct {
+	StockSymbol   string    `json:"stock_symbol"`
+	TotalQuantity float64   `json:"total_quantity"`
+	AveragePrice  float64   `json:"average_price"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+type EraseUserRequest struct {
+	RequestedBy string `json:"requested_by" binding:"required"`
+}
+
+type EraseUserResponse struct {
+	UserID           int       `json:"user_id"`
+	ErasedAt         time.Time `json:"erased_at"`
+	RejectedRewards  int       `json:"rejected_rewards"`
+	RetainedRewards  int       `json:"retained_rewards"`
+	RetainedLedger   int       `json:"retained_ledger_entries"`
+	RetainedHoldings int       `json:"retained_holdings"`
+}
@@ -0,0 +1,13 @@
+package privacy
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(router *gin.RouterGroup, handler *PrivacyHandler) {
+	users := router.Group("/users/:id")
+	{
+		users.GET("/export", handler.ExportUserData)
+		users.POST("/erase", handler.EraseUser)
+	}
+}
@@ -0,0 +1,286 @@
+package privacy
+
+import (
+	"archive/zip"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"stocky-backend/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+type PrivacyService struct {
+	db *sql.DB
+}
+
+func NewPrivacyService(db *sql.DB) *PrivacyService {
+	return &PrivacyService{db: db}
+}
+
+func (s *PrivacyService) ExportUserData(userID int) (*UserDataExport, error) {
+	export := &UserDataExport{
+		GeneratedAt:   time.Now().UTC(),
+		DematAccounts: []ExportDemat{},
+		Rewards:       []ExportReward{},
+		LedgerEntries: []ExportLedgerEntry{},
+		Holdings:      []ExportHolding{},
+	}
+
+	profile := &export.Profile
+	err := s.db.QueryRow(`
+		SELECT id, email, name, COALESCE(phone, ''), COALESCE(pan, ''), kyc_status, kyc_submitted_at,
+		       kyc_verified_at, is_active, deactivated_at, erased_at, created_at, updated_at
+		FROM users WHERE id = $1
+	`, userID).Scan(
+		&profile.ID, &profile.Email, &profile.Name, &profile.Phone, &profile.PAN, &profile.KYCStatus,
+		&profile.KYCSubmittedAt, &profile.KYCVerifiedAt, &profile.IsActive, &profile.DeactivatedAt,
+		&profile.ErasedAt, &profile.CreatedAt, &profile.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeUserNotFound, "user %d not found", userID)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to query user profile for export: %v", err)
+		return nil, err
+	}
+
+	rows, err := s.db.Query(`
+		SELECT depository, dp_id, client_id, is_primary, status, created_at
+		FROM demat_accounts WHERE user_id = $1 ORDER BY created_at
+	`, userID)
+	if err != nil {
+		logrus.Errorf("Failed to query demat accounts for export: %v", err)
+		return nil, err
+	}
+	for rows.Next() {
+		var demat ExportDemat
+		if err := rows.Scan(&demat.Depository, &demat.DPID, &demat.ClientID, &demat.IsPrimary, &demat.Status, &demat.CreatedAt); err != nil {
+			rows.Close()
+			logrus.Errorf("Failed to scan demat account: %v", err)
+			return nil, err
+		}
+		export.DematAccounts = append(export.DematAccounts, demat)
+	}
+	rows.Close()
+
+	rows, err = s.db.Query(`
+		SELECT re.id, s.symbol, re.quantity, re.stock_price, re.total_value, re.event_type,
+		       re.status, COALESCE(re.description, ''), re.created_at
+		FROM reward_events re
+		JOIN stocks s ON re.stock_id = s.id
+		WHERE re.user_id = $1
+		ORDER BY re.created_at
+	`, userID)
+	if err != nil {
+		logrus.Errorf("Failed to query rewards for export: %v", err)
+		return nil, err
+	}
+	for rows.Next() {
+		var reward ExportReward
+		err := rows.Scan(
+			&reward.ID, &reward.StockSymbol, &reward.Quantity, &reward.StockPrice, &reward.TotalValue,
+			&reward.EventType, &reward.Status, &reward.Description, &reward.CreatedAt,
+		)
+		if err != nil {
+			rows.Close()
+			logrus.Errorf("Failed to scan reward: %v", err)
+			return nil, err
+		}
+		export.Rewards = append(export.Rewards, reward)
+	}
+	rows.Close()
+
+	rows, err = s.db.Query(`
+		SELECT le.id, le.reward_event_id, le.corporate_action_id, le.entry_type, le.account_type,
+		       COALESCE(s.symbol, ''), le.quantity, le.amount, COALESCE(le.description, ''), le.created_at
+		FROM ledger_entries le
+		LEFT JOIN stocks s ON le.stock_id = s.id
+		WHERE le.user_id = $1
+		ORDER BY le.created_at, le.id
+	`, userID)
+	if err != nil {
+		logrus.Errorf("Failed to query ledger entries for export: %v", err)
+		return nil, err
+	}
+	for rows.Next() {
+		var entry ExportLedgerEntry
+		var rewardEventID, corporateActionID sql.NullInt64
+		var quantity, amount sql.NullFloat64
+		err := rows.Scan(
+			&entry.ID, &rewardEventID, &corporateActionID, &entry.EntryType, &entry.AccountType,
+			&entry.StockSymbol, &quantity, &amount, &entry.Description, &entry.CreatedAt,
+		)
+		if err != nil {
+			rows.Close()
+			logrus.Errorf("Failed to scan ledger entry: %v", err)
+			return nil, err
+		}
+		if rewardEventID.Valid {
+			entry.RewardEventID = &rewardEventID.Int64
+		}
+		if corporateActionID.Valid {
+			entry.CorporateActionID = &corporateActionID.Int64
+		}
+		if quantity.Valid {
+			entry.Quantity = &quantity.Float64
+		}
+		if amount.Valid {
+			entry.Amount = &amount.Float64
+		}
+		export.LedgerEntries = append(export.LedgerEntries, entry)
+	}
+	rows.Close()
+
+	rows, err = s.db.Query(`
+		SELECT s.symbol, ush.total_quantity, ush.average_price, ush.updated_at
+		FROM user_stock_holdings ush
+		JOIN stocks s ON ush.stock_id = s.id
+		WHERE ush.user_id = $1
+		ORDER BY s.symbol
+	`, userID)
+	if err != nil {
+		logrus.Errorf("Failed to query holdings for export: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var holding ExportHolding
+		if err := rows.Scan(&holding.StockSymbol, &holding.TotalQuantity, &holding.AveragePrice, &holding.UpdatedAt); err != nil {
+			logrus.Errorf("Failed to scan holding: %v", err)
+			return nil, err
+		}
+		export.Holdings = append(export.Holdings, holding)
+	}
+
+	return export, nil
+}
+
+// WriteExportZIP writes the export as a ZIP bundle with one JSON document per
+// section.
+func WriteExportZIP(w io.Writer, export *UserDataExport) error {
+	archive := zip.NewWriter(w)
+
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"demat_accounts.json", export.DematAccounts},
+		{"rewards.json", export.Rewards},
+		{"ledger_entries.json", export.LedgerEntries},
+		{"holdings.json", export.Holdings},
+		{"manifest.json", map[string]interface{}{
+			"user_id":      export.Profile.ID,
+			"generated_at": export.GeneratedAt,
+		}},
+	}
+
+	for _, file := range files {
+		f, err := archive.Create(file.name)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// EraseUser pseudonymises the user's personal data. Rewards, ledger entries
+// and holdings are kept for statutory retention and stay linked by user ID.
+// Rewards still awaiting approval or KYC are rejected.
+func (s *PrivacyService) EraseUser(userID int, req EraseUserRequest) (*EraseUserResponse, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		logrus.Errorf("Failed to begin transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var erasedAt sql.NullTime
+	err = tx.QueryRow(`SELECT erased_at FROM users WHERE id = $1 FOR UPDATE`, userID).Scan(&erasedAt)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeUserNotFound, "user %d not found", userID)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to lock user for erasure: %v", err)
+		return nil, err
+	}
+	if erasedAt.Valid {
+		return nil, domain.Conflict(domain.CodeUserErased, "user %d was already erased", userID)
+	}
+
+	response := &EraseUserResponse{UserID: userID}
+	err = tx.QueryRow(`
+		UPDATE users
+		SET email = $1,
+		    name = 'Erased User',
+		    phone = NULL,
+		    pan = NULL,
+		    is_active = false,
+		    deactivated_at = COALESCE(deactivated_at, NOW()),
+		    deactivation_reason = 'Personal data erased',
+		    erased_at = NOW(),
+		    erasure_requested_by = $2,
+		    updated_at = NOW()
+		WHERE id = $3
+		RETURNING erased_at
+	`, fmt.Sprintf("erased-user-%d@erased.invalid", userID), req.RequestedBy, userID).Scan(&response.ErasedAt)
+	if err != nil {
+		logrus.Errorf("Failed to pseudonymise user: %v", err)
+		return nil, err
+	}
+
+	_, err = tx.Exec(`
+		UPDATE demat_accounts
+		SET client_id = 'ERASED' || LPAD(id::text, 10, '0'),
+		    status = 'CLOSED',
+		    is_primary = false,
+		    updated_at = NOW()
+		WHERE user_id = $1
+	`, userID)
+	if err != nil {
+		logrus.Errorf("Failed to pseudonymise demat accounts: %v", err)
+		return nil, err
+	}
+
+	result, err := tx.Exec(`
+		UPDATE reward_events
+		SET status = 'REJECTED', rejection_reason = 'User data erased', reviewed_at = NOW(), updated_at = NOW()
+		WHERE user_id = $1 AND status IN ('PENDING_APPROVAL', 'PENDING_KYC')
+	`, userID)
+	if err != nil {
+		logrus.Errorf("Failed to reject pending rewards: %v", err)
+		return nil, err
+	}
+	rejected, _ := result.RowsAffected()
+	response.RejectedRewards = int(rejected)
+
+	err = tx.QueryRow(`
+		SELECT
+			(SELECT COUNT(*) FROM reward_events WHERE user_id = $1),
+			(SELECT COUNT(*) FROM ledger_entries WHERE user_id = $1),
+			(SELECT COUNT(*) FROM user_stock_holdings WHERE user_id = $1)
+	`, userID).Scan(&response.RetainedRewards, &response.RetainedLedger, &response.RetainedHoldings)
+	if err != nil {
+		logrus.Errorf("Failed to count retained records: %v", err)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.Errorf("Failed to commit erasure transaction: %v", err)
+		return nil, err
+	}
+
+	logrus.Infof("User %d erased at the request of %s; %d rewards and %d ledger entries retained",
+		userID, req.RequestedBy, response.RetainedRewards, response.RetainedLedger)
+	return response, nil
+}
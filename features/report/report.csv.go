@@ -0,0 +1,61 @@
+package report
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+)
+
+// WriteCostReportCSV writes one line per day (per group when grouped)
+// followed by a TOTAL line, with a column per fee account.
+func WriteCostReportCSV(w io.Writer, report *CostReport) error {
+	writer := csv.NewWriter(w)
+	grouped := report.GroupBy != ""
+
+	header := []string{"date"}
+	if grouped {
+		header = append(header, report.GroupBy)
+	}
+	header = append(header, "reward_count", "stock_cost", "refunds", "net_stock_cost")
+	header = append(header, report.FeeAccounts...)
+	header = append(header, "total_fees", "total_cost")
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, day := range report.Days {
+		record := []string{day.Date}
+		if grouped {
+			record = append(record, day.Group)
+		}
+		if err := writer.Write(append(record, totalsRecord(day.CostTotals, report.FeeAccounts)...)); err != nil {
+			return err
+		}
+	}
+
+	total := []string{"TOTAL"}
+	if grouped {
+		total = append(total, "")
+	}
+	if err := writer.Write(append(total, totalsRecord(report.Totals, report.FeeAccounts)...)); err != nil {
+		return err
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+func totalsRecord(totals CostTotals, feeAccounts []string) []string {
+	record := []string{
+		strconv.Itoa(totals.RewardCount), formatAmount(totals.StockCost),
+		formatAmount(totals.Refunds), formatAmount(totals.NetStockCost),
+	}
+	for _, account := range feeAccounts {
+		record = append(record, formatAmount(totals.Fees[account]))
+	}
+	return append(record, formatAmount(totals.TotalFees), formatAmount(totals.TotalCost))
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 4, 64)
+}
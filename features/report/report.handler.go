@@ -0,0 +1,53 @@
+package report
+
+import (
+	"fmt"
+	"net/http"
+
+	"stocky-backend/domain"
+	"stocky-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type ReportHandler struct {
+	service *ReportService
+}
+
+func NewReportHandler(service *ReportService) *ReportHandler {
+	return &ReportHandler{service: service}
+}
+
+func (h *ReportHandler) GetCostReport(c *gin.Context) {
+	format := c.DefaultQuery("format", FormatJSON)
+	if format != FormatJSON && format != FormatCSV {
+		c.Error(domain.Validation(domain.CodeInvalidExportFormat, "unsupported report format %q: use json or csv", format))
+		return
+	}
+
+	var filter CostReportFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.Error(middleware.BadRequestError("Invalid query parameters", err.Error()))
+		return
+	}
+
+	report, err := h.service.GetCostReport(filter)
+	if err != nil {
+		logrus.Errorf("Error building cost report: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to build cost report"))
+		return
+	}
+
+	if format == FormatJSON {
+		c.JSON(http.StatusOK, gin.H{"data": report})
+		return
+	}
+
+	c.Header("Content-Type", "text/csv")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="costs-%s-to-%s.csv"`, report.From, report.To))
+	c.Status(http.StatusOK)
+	if err := WriteCostReportCSV(c.Writer, report); err != nil {
+		logrus.Errorf("Error writing cost report: %v", err)
+	}
+}
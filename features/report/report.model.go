@@ -0,0 +1,64 @@
+package report
+
+import (
+	"time"
+)
+
+const (
+	FormatJSON = "json"
+	FormatCSV  = "csv"
+
+	GroupByCampaign = "campaign"
+	GroupByStock    = "stock"
+
+	// maxReportDays bounds a report to roughly a financial year.
+	maxReportDays = 366
+)
+
+// CostReportFilter selects the ledger lines a cost report covers. From and To
+// are inclusive calendar dates; the last 30 days when omitted.
+type CostReportFilter struct {
+	From     *time.Time `form:"from" time_format:"2006-01-02"`
+	To       *time.Time `form:"to" time_format:"2006-01-02"`
+	Campaign string     `form:"campaign"`
+	Stock    string     `form:"stock"`
+	GroupBy  string     `form:"group_by"`
+}
+
+// CostTotals is the company's spend booked in ledger_entries. StockCost is
+// the INR paid for rewarded units and Refunds the value returned by reward
+// adjustments. Fees holds every fee account, such as BROKERAGE_FEE, STT_FEE
+// and GST_FEE.
+type CostTotals struct {
+	RewardCount  int                `json:"reward_count"`
+	StockCost    float64            `json:"stock_cost"`
+	Refunds      float64            `json:"refunds"`
+	NetStockCost float64            `json:"net_stock_cost"`
+	Fees         map[string]float64 `json:"fees"`
+	TotalFees    float64            `json:"total_fees"`
+	TotalCost    float64            `json:"total_cost"`
+}
+
+type CostDay struct {
+	Date  string `json:"date"`
+	Group string `json:"group,omitempty"`
+	CostTotals
+}
+
+type CostGroup struct {
+	Group string `json:"group"`
+	CostTotals
+}
+
+type CostReport struct {
+	From        string      `json:"from"`
+	To          string      `json:"to"`
+	Campaign    string      `json:"campaign,omitempty"`
+	StockSymbol string      `json:"stock_symbol,omitempty"`
+	GroupBy     string      `json:"group_by,omitempty"`
+	FeeAccounts []string    `json:"fee_accounts"`
+	Totals      CostTotals  `json:"totals"`
+	Days        []CostDay   `json:"days"`
+	Groups      []CostGroup `json:"groups,omitempty"`
+	GeneratedAt time.Time   `json:"generated_at"`
+}
@@ -0,0 +1,12 @@
+package report
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(router *gin.RouterGroup, handler *ReportHandler) {
+	reports := router.Group("/reports")
+	{
+		reports.GET("/costs", handler.GetCostReport)
+	}
+}
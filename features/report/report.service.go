@@ -0,0 +1,261 @@
+package report
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+
+	"stocky-backend/domain"
+	"stocky-backend/features/stock"
+
+	"github.com/sirupsen/logrus"
+)
+
+// standardFeeAccounts always appear in a report, even at zero, so finance
+// sees the same columns every time.
+var standardFeeAccounts = []string{"BROKERAGE_FEE", "STT_FEE", "GST_FEE"}
+
+type ReportService struct {
+	db *sql.DB
+}
+
+func NewReportService(db *sql.DB) *ReportService {
+	return &ReportService{db: db}
+}
+
+// GetCostReport aggregates the reward ledger lines booked between the
+// filter's dates: INR paid for units (CREDIT INR_CASH), value returned by
+// adjustments (DEBIT INR_CASH) and every fee account. Lines are dated when
+// they were booked, so a reward approved later counts on its approval day.
+// Campaign and stock come from the reward the line belongs to.
+func (s *ReportService) GetCostReport(filter CostReportFilter) (*CostReport, error) {
+	from, to, err := reportRange(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	groupExpr := `''`
+	switch filter.GroupBy {
+	case "":
+	case GroupByCampaign:
+		groupExpr = `COALESCE(re.campaign, '')`
+	case GroupByStock:
+		groupExpr = `s.symbol`
+	default:
+		return nil, domain.Validation(domain.CodeInvalidReportRange, "unsupported group_by %q: use campaign or stock", filter.GroupBy)
+	}
+
+	report := &CostReport{
+		From:        from.Format("2006-01-02"),
+		To:          to.Format("2006-01-02"),
+		Campaign:    filter.Campaign,
+		GroupBy:     filter.GroupBy,
+		Days:        []CostDay{},
+		GeneratedAt: time.Now(),
+	}
+
+	var stockID int
+	if filter.Stock != "" {
+		ref, err := stock.Resolve(s.db, filter.Stock)
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFound(domain.CodeStockNotFound, "stock '%s' not found", filter.Stock)
+		}
+		if err != nil {
+			logrus.Errorf("Failed to resolve stock for cost report: %v", err)
+			return nil, err
+		}
+		stockID = ref.ID
+		report.StockSymbol = ref.Symbol
+	}
+
+	rows, err := s.db.Query(`
+		SELECT DATE(le.created_at), `+groupExpr+`, le.entry_type, le.account_type, COUNT(*), SUM(le.amount)
+		FROM ledger_entries le
+		JOIN reward_events re ON re.id = le.reward_event_id
+		JOIN stocks s ON s.id = re.stock_id
+		WHERE le.amount IS NOT NULL
+		AND le.created_at >= $1 AND le.created_at < $2
+		AND ($3 = '' OR re.campaign = $3)
+		AND ($4 = 0 OR re.stock_id = $4)
+		GROUP BY 1, 2, 3, 4
+		ORDER BY 1, 2
+	`, from, to.AddDate(0, 0, 1), filter.Campaign, stockID)
+	if err != nil {
+		logrus.Errorf("Failed to query cost report: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	type dayKey struct {
+		date  string
+		group string
+	}
+	days := make(map[dayKey]*CostTotals)
+	groups := make(map[string]*CostTotals)
+	var keys []dayKey
+	feeAccounts := make(map[string]bool)
+
+	for rows.Next() {
+		var date time.Time
+		var group, entryType, accountType string
+		var count int
+		var amount float64
+		if err := rows.Scan(&date, &group, &entryType, &accountType, &count, &amount); err != nil {
+			logrus.Errorf("Failed to scan cost report row: %v", err)
+			return nil, err
+		}
+
+		key := dayKey{date: date.Format("2006-01-02"), group: group}
+		if days[key] == nil {
+			days[key] = newTotals()
+			keys = append(keys, key)
+		}
+		if groups[group] == nil {
+			groups[group] = newTotals()
+		}
+
+		for _, totals := range []*CostTotals{days[key], groups[group]} {
+			if addLine(totals, entryType, accountType, count, amount) {
+				feeAccounts[accountType] = true
+			}
+		}
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	report.FeeAccounts = append([]string{}, standardFeeAccounts...)
+	var extra []string
+	for account := range feeAccounts {
+		if !isStandardFeeAccount(account) {
+			extra = append(extra, account)
+		}
+	}
+	sort.Strings(extra)
+	report.FeeAccounts = append(report.FeeAccounts, extra...)
+
+	// Without grouping every day of the range gets a row, so gaps in the
+	// breakdown mean no spend rather than missing data.
+	if filter.GroupBy == "" {
+		keys = keys[:0]
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			key := dayKey{date: day.Format("2006-01-02")}
+			if days[key] == nil {
+				days[key] = newTotals()
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	report.Totals = *newTotals()
+	for _, key := range keys {
+		totals := days[key]
+		finishTotals(totals, report.FeeAccounts)
+		report.Days = append(report.Days, CostDay{Date: key.date, Group: key.group, CostTotals: *totals})
+		mergeTotals(&report.Totals, totals)
+	}
+	finishTotals(&report.Totals, report.FeeAccounts)
+
+	if filter.GroupBy != "" {
+		names := make([]string, 0, len(groups))
+		for name := range groups {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		report.Groups = []CostGroup{}
+		for _, name := range names {
+			finishTotals(groups[name], report.FeeAccounts)
+			report.Groups = append(report.Groups, CostGroup{Group: name, CostTotals: *groups[name]})
+		}
+	}
+
+	logrus.Infof("Cost report %s to %s: %d rewards, total cost %.4f", report.From, report.To,
+		report.Totals.RewardCount, report.Totals.TotalCost)
+	return report, nil
+}
+
+// reportRange defaults to the 30 days ending today and rejects inverted or
+// overlong ranges.
+func reportRange(filter CostReportFilter) (time.Time, time.Time, error) {
+	now := time.Now()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if filter.To != nil {
+		to = truncateDate(*filter.To)
+	}
+	from := to.AddDate(0, 0, -29)
+	if filter.From != nil {
+		from = truncateDate(*filter.From)
+	}
+
+	if from.After(to) {
+		return from, to, domain.Validation(domain.CodeInvalidReportRange, "from %s is after to %s",
+			from.Format("2006-01-02"), to.Format("2006-01-02"))
+	}
+	if days := int(to.Sub(from).Hours()/24) + 1; days > maxReportDays {
+		return from, to, domain.Validation(domain.CodeInvalidReportRange, "report covers %d days; at most %d are allowed", days, maxReportDays)
+	}
+	return from, to, nil
+}
+
+func truncateDate(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func newTotals() *CostTotals {
+	return &CostTotals{Fees: make(map[string]float64)}
+}
+
+// addLine books one aggregated ledger line and reports whether it was a fee.
+func addLine(totals *CostTotals, entryType, accountType string, count int, amount float64) bool {
+	switch {
+	case accountType == "INR_CASH" && entryType == "CREDIT":
+		totals.StockCost += amount
+		totals.RewardCount += count
+	case accountType == "INR_CASH" && entryType == "DEBIT":
+		totals.Refunds += amount
+	case entryType == "CREDIT":
+		totals.Fees[accountType] += amount
+		return true
+	}
+	return false
+}
+
+func mergeTotals(into, from *CostTotals) {
+	into.RewardCount += from.RewardCount
+	into.StockCost += from.StockCost
+	into.Refunds += from.Refunds
+	for account, amount := range from.Fees {
+		into.Fees[account] += amount
+	}
+}
+
+// finishTotals fills every fee account, rounds to ledger precision and
+// derives the net and total figures.
+func finishTotals(totals *CostTotals, feeAccounts []string) {
+	totals.StockCost = roundAmount(totals.StockCost)
+	totals.Refunds = roundAmount(totals.Refunds)
+	totals.NetStockCost = roundAmount(totals.StockCost - totals.Refunds)
+
+	totals.TotalFees = 0
+	for _, account := range feeAccounts {
+		totals.Fees[account] = roundAmount(totals.Fees[account])
+		totals.TotalFees += totals.Fees[account]
+	}
+	totals.TotalFees = roundAmount(totals.TotalFees)
+	totals.TotalCost = roundAmount(totals.NetStockCost + totals.TotalFees)
+}
+
+func isStandardFeeAccount(account string) bool {
+	for _, standard := range standardFeeAccounts {
+		if account == standard {
+			return true
+		}
+	}
+	return false
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*10000) / 10000
+}
@@ -0,0 +1,98 @@
+package reward
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+)
+
+const defaultDuplicatePolicyCampaign = "DEFAULT"
+
+// getDuplicatePolicy returns the policy for the campaign, falling back to the
+// DEFAULT policy. A nil policy means duplicate detection is not configured.
+func getDuplicatePolicy(tx *sql.Tx, campaign string) (*DuplicateRewardPolicy, error) {
+	var policy DuplicateRewardPolicy
+	var matchFields string
+	err := tx.QueryRow(`
+		SELECT id, campaign, window_seconds, match_fields, quantity_tolerance, is_enabled
+		FROM duplicate_reward_policies
+		WHERE campaign = $1 OR campaign = $2
+		ORDER BY (campaign = $2)
+		LIMIT 1
+	`, campaign, defaultDuplicatePolicyCampaign).Scan(
+		&policy.ID, &policy.Campaign, &policy.WindowSeconds, &matchFields,
+		&policy.QuantityTolerance, &policy.IsEnabled,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		logrus.Errorf("Failed to load duplicate reward policy: %v", err)
+		return nil, err
+	}
+
+	for _, field := range strings.Split(matchFields, ",") {
+		if field = strings.TrimSpace(field); field != "" {
+			policy.MatchFields = append(policy.MatchFields, field)
+		}
+	}
+
+	return &policy, nil
+}
+
+// findDuplicateReward returns the ID of the most recent reward matching the
+// request under the policy, or 0 when there is none.
+func findDuplicateReward(tx *sql.Tx, policy *DuplicateRewardPolicy, req CreateRewardRequest, stockID int) (int, error) {
+	if policy == nil || !policy.IsEnabled || policy.WindowSeconds == 0 {
+		return 0, nil
+	}
+
+	conditions := []string{
+		"event_type = 'STOCK_REWARD'",
+		"status <> 'REJECTED'",
+		"created_at > NOW() - make_interval(secs => $1)",
+	}
+	args := []interface{}{policy.WindowSeconds}
+
+	for _, field := range policy.MatchFields {
+		switch field {
+		case "user_id":
+			args = append(args, req.UserID)
+			conditions = append(conditions, fmt.Sprintf("user_id = $%d", len(args)))
+		case "stock_id":
+			args = append(args, stockID)
+			conditions = append(conditions, fmt.Sprintf("stock_id = $%d", len(args)))
+		case "quantity":
+			args = append(args, req.Quantity, policy.QuantityTolerance)
+			conditions = append(conditions, fmt.Sprintf("ABS(quantity - $%d) <= $%d * $%d", len(args)-1, len(args), len(args)-1))
+		case "campaign":
+			args = append(args, req.Campaign)
+			conditions = append(conditions, fmt.Sprintf("COALESCE(campaign, '') = $%d", len(args)))
+		case "description":
+			// Compare without the idempotency key CreateReward appends.
+			args = append(args, req.Description, idempotencySuffixPattern)
+			conditions = append(conditions, fmt.Sprintf("REGEXP_REPLACE(COALESCE(description, ''), $%d, '') = $%d", len(args), len(args)-1))
+		default:
+			return 0, fmt.Errorf("invalid duplicate reward policy %q: unknown match field %q", policy.Campaign, field)
+		}
+	}
+
+	var rewardID int
+	err := tx.QueryRow(`
+		SELECT id FROM reward_events
+		WHERE `+strings.Join(conditions, " AND ")+`
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, args...).Scan(&rewardID)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		logrus.Errorf("Failed to check for duplicate reward: %v", err)
+		return 0, err
+	}
+
+	return rewardID, nil
+}
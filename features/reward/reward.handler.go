@@ -28,7 +28,22 @@ func (h *RewardHandler) CreateReward(c *gin.Context) {
 	reward, err := h.service.CreateReward(req)
 	if err != nil {
 		logrus.Errorf("Error creating reward: %v", err)
-		c.Error(middleware.InternalServerError("Failed to create reward", err.Error()))
+		c.Error(middleware.WrapServiceError(err, "Failed to create reward"))
+		return
+	}
+
+	if reward.Status == RewardStatusPendingApproval {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Reward exceeds the approval threshold and is pending approval",
+			"data":    reward,
+		})
+		return
+	}
+	if reward.Status == RewardStatusPendingKYC {
+		c.JSON(http.StatusAccepted, gin.H{
+			"message": "Reward is parked until the user's KYC is verified",
+			"data":    reward,
+		})
 		return
 	}
 
@@ -52,7 +67,7 @@ func (h *RewardHandler) GetAllRewards(c *gin.Context) {
 	rewards, err := h.service.GetAllRewards(page, pageSize)
 	if err != nil {
 		logrus.Errorf("Error getting rewards: %v", err)
-		c.Error(middleware.InternalServerError("Failed to retrieve rewards", err.Error()))
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve rewards"))
 		return
 	}
 
@@ -79,7 +94,7 @@ func (h *RewardHandler) GetRewardsByUserID(c *gin.Context) {
 	rewards, err := h.service.GetRewardsByUserID(userID, page, pageSize)
 	if err != nil {
 		logrus.Errorf("Error getting user rewards: %v", err)
-		c.Error(middleware.InternalServerError("Failed to retrieve user rewards", err.Error()))
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve user rewards"))
 		return
 	}
 
@@ -96,7 +111,7 @@ func (h *RewardHandler) AdjustReward(c *gin.Context) {
 	adjustment, err := h.service.AdjustReward(req)
 	if err != nil {
 		logrus.Errorf("Error adjusting reward: %v", err)
-		c.Error(middleware.InternalServerError("Failed to adjust reward", err.Error()))
+		c.Error(middleware.WrapServiceError(err, "Failed to adjust reward"))
 		return
 	}
 
@@ -105,3 +120,75 @@ func (h *RewardHandler) AdjustReward(c *gin.Context) {
 		"data":    adjustment,
 	})
 }
+
+func (h *RewardHandler) ApproveReward(c *gin.Context) {
+	rewardID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid reward ID", err.Error()))
+		return
+	}
+
+	var req ApproveRewardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	reward, err := h.service.ApproveReward(rewardID, req)
+	if err != nil {
+		logrus.Errorf("Error approving reward: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to approve reward"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reward approved and posted successfully",
+		"data":    reward,
+	})
+}
+
+func (h *RewardHandler) RejectReward(c *gin.Context) {
+	rewardID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid reward ID", err.Error()))
+		return
+	}
+
+	var req RejectRewardRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	reward, err := h.service.RejectReward(rewardID, req)
+	if err != nil {
+		logrus.Errorf("Error rejecting reward: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to reject reward"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Reward rejected",
+		"data":    reward,
+	})
+}
+
+func (h *RewardHandler) ReleasePendingKYCRewards(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+		return
+	}
+
+	released, err := h.service.ReleasePendingKYCRewards(userID)
+	if err != nil {
+		logrus.Errorf("Error releasing rewards pending KYC: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to release rewards pending KYC"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Rewards pending KYC released",
+		"data":    ReleaseKYCRewardsResponse{UserID: userID, ReleasedRewards: released},
+	})
+}
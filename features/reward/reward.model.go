@@ -4,18 +4,38 @@ import (
 	"time"
 )
 
+const (
+	RewardStatusCompleted       = "COMPLETED"
+	RewardStatusPendingApproval = "PENDING_APPROVAL"
+	RewardStatusRejected        = "REJECTED"
+	RewardStatusPendingKYC      = "PENDING_KYC"
+)
+
+// Event types of reward_events. Rewards take the column default; refunds
+// are recorded as adjustments against them.
+const (
+	EventTypeStockReward = "STOCK_REWARD"
+	EventTypeAdjustment  = "ADJUSTMENT"
+)
+
 type RewardEvent struct {
-	ID          int       `json:"id"`
-	UserID      int       `json:"user_id"`
-	StockID     int       `json:"stock_id"`
-	Quantity    float64   `json:"quantity"`
-	StockPrice  float64   `json:"stock_price"`
-	TotalValue  float64   `json:"total_value"`
-	EventType   string    `json:"event_type"`
-	Status      string    `json:"status"`
-	Description string    `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID              int        `json:"id"`
+	UserID          int        `json:"user_id"`
+	StockID         int        `json:"stock_id"`
+	Quantity        float64    `json:"quantity"`
+	StockPrice      float64    `json:"stock_price"`
+	TotalValue      float64    `json:"total_value"`
+	EventType       string     `json:"event_type"`
+	Status          string     `json:"status"`
+	Description     string     `json:"description"`
+	Campaign        string     `json:"campaign,omitempty"`
+	RequestedBy     string     `json:"requested_by,omitempty"`
+	ReviewedBy      string     `json:"reviewed_by,omitempty"`
+	ReviewedAt      *time.Time `json:"reviewed_at,omitempty"`
+	RejectionReason string     `json:"rejection_reason,omitempty"`
+	FeeVersionID    *int       `json:"fee_version_id,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
 type CreateRewardRequest struct {
@@ -24,6 +44,18 @@ type CreateRewardRequest struct {
 	Quantity       float64 `json:"quantity" binding:"required,gt=0"`
 	Description    string  `json:"description"`
 	IdempotencyKey string  `json:"idempotency_key"`
+	RequestedBy    string  `json:"requested_by"`
+	Campaign       string  `json:"campaign"`
+	Force          bool    `json:"force"`
+}
+
+type ApproveRewardRequest struct {
+	ApprovedBy string `json:"approved_by" binding:"required"`
+}
+
+type RejectRewardRequest struct {
+	RejectedBy string `json:"rejected_by" binding:"required"`
+	Reason     string `json:"reason" binding:"required"`
 }
 
 type AdjustRewardRequest struct {
@@ -50,14 +82,18 @@ type RewardEventWithDetails struct {
 	CreatedAt   time.Time `json:"created_at"`
 }
 
-type FeeConfiguration struct {
-	ID          int       `json:"id"`
-	FeeType     string    `json:"fee_type"`
-	Percentage  float64   `json:"percentage"`
-	Description string    `json:"description"`
-	IsActive    bool      `json:"is_active"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+type DuplicateRewardPolicy struct {
+	ID                int      `json:"id"`
+	Campaign          string   `json:"campaign"`
+	WindowSeconds     int      `json:"window_seconds"`
+	MatchFields       []string `json:"match_fields"`
+	QuantityTolerance float64  `json:"quantity_tolerance"`
+	IsEnabled         bool     `json:"is_enabled"`
+}
+
+type ReleaseKYCRewardsResponse struct {
+	UserID          int `json:"user_id"`
+	ReleasedRewards int `json:"released_rewards"`
 }
 
 type PaginatedRewardsResponse struct {
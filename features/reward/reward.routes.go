@@ -10,7 +10,10 @@ func RegisterRoutes(router *gin.RouterGroup, handler *RewardHandler) {
 	{
 		rewards.POST("", handler.CreateReward)
 		rewards.POST("/adjust", handler.AdjustReward)
+		rewards.POST("/:id/approve", handler.ApproveReward)
+		rewards.POST("/:id/reject", handler.RejectReward)
 		rewards.GET("", handler.GetAllRewards)
 		rewards.GET("/user/:userId", handler.GetRewardsByUserID)
+		rewards.POST("/user/:userId/release-kyc", handler.ReleasePendingKYCRewards)
 	}
 }
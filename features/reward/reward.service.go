@@ -2,17 +2,46 @@ package reward
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 
+	"stocky-backend/config"
+	"stocky-backend/domain"
+	"stocky-backend/features/fee"
+	"stocky-backend/features/stock"
+	"stocky-backend/features/tax_lot"
+
 	"github.com/sirupsen/logrus"
 )
 
+const rewardEventColumns = `id, user_id, stock_id, quantity, stock_price, total_value, event_type, status, description,
+	COALESCE(campaign, ''), COALESCE(requested_by, ''), COALESCE(reviewed_by, ''), reviewed_at, COALESCE(rejection_reason, ''),
+	fee_version_id, created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
 type RewardService struct {
-	db *sql.DB
+	db     *sql.DB
+	config *config.RewardConfig
+}
+
+func NewRewardService(db *sql.DB, cfg *config.RewardConfig) *RewardService {
+	return &RewardService{db: db, config: cfg}
+}
+
+func scanRewardEvent(row rowScanner, event *RewardEvent) error {
+	return row.Scan(
+		&event.ID, &event.UserID, &event.StockID, &event.Quantity,
+		&event.StockPrice, &event.TotalValue, &event.EventType,
+		&event.Status, &event.Description, &event.Campaign, &event.RequestedBy, &event.ReviewedBy,
+		&event.ReviewedAt, &event.RejectionReason, &event.FeeVersionID, &event.CreatedAt, &event.UpdatedAt,
+	)
 }
 
-func NewRewardService(db *sql.DB) *RewardService {
-	return &RewardService{db: db}
+func (s *RewardService) requiresApproval(totalValue float64) bool {
+	return s.config.ApprovalThreshold > 0 && totalValue > s.config.ApprovalThreshold
 }
 
 func (s *RewardService) CreateReward(req CreateRewardRequest) (*RewardEvent, error) {
@@ -23,59 +52,67 @@ func (s *RewardService) CreateReward(req CreateRewardRequest) (*RewardEvent, err
 	}
 	defer tx.Rollback()
 
-	var stockID int
-	var stockPrice float64
-	var stockName string
-	err = tx.QueryRow(`SELECT id, current_price, name FROM stocks WHERE symbol = $1 AND is_active = true`, 
-		req.StockSymbol).Scan(&stockID, &stockPrice, &stockName)
+	// Clients may send a symbol retired by a symbol change, or an ISIN;
+	// both resolve to the stock's current row.
+	ref, err := stock.Resolve(tx, req.StockSymbol)
 	if err != nil {
-		logrus.Errorf("Failed to get stock details: %v", err)
-		var isDelisted bool
-		err2 := tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM stocks WHERE symbol = $1 AND is_active = false)`, 
-			req.StockSymbol).Scan(&isDelisted)
-		if err2 == nil && isDelisted {
-			return nil, fmt.Errorf("stock '%s' is delisted and cannot receive new rewards", req.StockSymbol)
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFound(domain.CodeStockNotFound, "stock '%s' not found", req.StockSymbol)
 		}
-		return nil, fmt.Errorf("stock not found or inactive")
+		logrus.Errorf("Failed to get stock details: %v", err)
+		return nil, err
+	}
+	if !ref.IsActive {
+		return nil, domain.BusinessRule(domain.CodeStockDelisted, "stock '%s' is delisted and cannot receive new rewards", req.StockSymbol)
 	}
+	if ref.Symbol != req.StockSymbol {
+		logrus.Infof("Resolved stock '%s' to current symbol %s", req.StockSymbol, ref.Symbol)
+		req.StockSymbol = ref.Symbol
+	}
+	stockID, stockPrice := ref.ID, ref.CurrentPrice
 
-	var pendingAction string
-	err = tx.QueryRow(`
-		SELECT action_type FROM corporate_actions 
-		WHERE stock_id = $1 AND status = 'PENDING' 
-		AND effective_date <= CURRENT_DATE
-		LIMIT 1
-	`, stockID).Scan(&pendingAction)
-	if err == nil {
-		return nil, fmt.Errorf("stock '%s' has a pending %s corporate action. Please process it before issuing new rewards", req.StockSymbol, pendingAction)
-	} else if err != sql.ErrNoRows {
-		logrus.Errorf("Failed to check corporate actions: %v", err)
+	if err = checkPendingCorporateAction(tx, stockID, req.StockSymbol); err != nil {
 		return nil, err
 	}
 
-	var userExists bool
-	err = tx.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND is_active = true)`, 
-		req.UserID).Scan(&userExists)
-	if err != nil || !userExists {
-		return nil, fmt.Errorf("user not found or inactive")
+	if err = checkUserActive(tx, req.UserID); err != nil {
+		return nil, err
 	}
 
-	var duplicateExists bool
-	err = tx.QueryRow(`
-		SELECT EXISTS(
-			SELECT 1 FROM reward_events 
-			WHERE user_id = $1 
-			AND stock_id = $2 
-			AND quantity = $3 
-			AND created_at > NOW() - INTERVAL '5 minutes'
-		)
-	`, req.UserID, stockID, req.Quantity).Scan(&duplicateExists)
-	if err != nil {
-		logrus.Errorf("Failed to check for duplicate reward: %v", err)
-		return nil, err
+	parkForKYC := false
+	if s.config.KYCPolicy != config.KYCPolicyNone {
+		verified, err := isKYCVerified(tx, req.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if !verified && s.config.KYCPolicy == config.KYCPolicyRequire {
+			return nil, domain.BusinessRule(domain.CodeKYCNotVerified,
+				"user %d has not completed KYC verification and cannot receive rewards", req.UserID)
+		}
+		parkForKYC = !verified
 	}
-	if duplicateExists {
-		return nil, fmt.Errorf("duplicate reward detected: similar reward was created within the last 5 minutes")
+
+	if req.Force {
+		if req.RequestedBy == "" {
+			return nil, domain.Validation(domain.CodeRequestedByRequired, "requested_by is required when forcing a reward past duplicate detection")
+		}
+		logrus.Warnf("Duplicate reward detection bypassed by %s for user %d, stock %s",
+			req.RequestedBy, req.UserID, req.StockSymbol)
+	} else {
+		policy, err := getDuplicatePolicy(tx, req.Campaign)
+		if err != nil {
+			return nil, err
+		}
+
+		conflictingID, err := findDuplicateReward(tx, policy, req, stockID)
+		if err != nil {
+			return nil, err
+		}
+		if conflictingID != 0 {
+			return nil, domain.Conflict(domain.CodeDuplicateReward,
+				"duplicate reward detected: reward #%d matches this request within the last %d seconds",
+				conflictingID, policy.WindowSeconds).WithMeta("conflicting_reward_id", conflictingID)
+		}
 	}
 
 	if req.IdempotencyKey != "" {
@@ -87,7 +124,8 @@ func (s *RewardService) CreateReward(req CreateRewardRequest) (*RewardEvent, err
 			LIMIT 1
 		`, "%idempotency:"+req.IdempotencyKey+"%").Scan(&existingRewardID)
 		if err == nil {
-			return nil, fmt.Errorf("duplicate request: idempotency key already used")
+			return nil, domain.Conflict(domain.CodeIdempotencyKeyUsed, "duplicate request: idempotency key already used").
+				WithMeta("conflicting_reward_id", existingRewardID)
 		} else if err != sql.ErrNoRows {
 			logrus.Errorf("Failed to check idempotency key: %v", err)
 			return nil, err
@@ -96,78 +134,114 @@ func (s *RewardService) CreateReward(req CreateRewardRequest) (*RewardEvent, err
 
 	totalValue := req.Quantity * stockPrice
 
-	fees, err := s.getFeeConfigurations(tx)
-	if err != nil {
-		return nil, err
+	status := RewardStatusCompleted
+	if s.requiresApproval(totalValue) {
+		if req.RequestedBy == "" {
+			return nil, domain.Validation(domain.CodeRequestedByRequired, "requested_by is required for rewards above the approval threshold of %.2f", s.config.ApprovalThreshold)
+		}
+		status = RewardStatusPendingApproval
+	}
+	if parkForKYC {
+		status = RewardStatusPendingKYC
 	}
-
-	brokerageFee := totalValue * fees["BROKERAGE"]
-	sttFee := totalValue * fees["STT"]
-	gstFee := brokerageFee * fees["GST"]
 
 	description := req.Description
 	if req.IdempotencyKey != "" {
-		description = fmt.Sprintf("%s [idempotency:%s]", req.Description, req.IdempotencyKey)
+		description = req.Description + idempotencySuffix(req.IdempotencyKey)
+	}
+
+	// Fees follow the version in effect when the reward is created, even if
+	// it is only posted later on approval or KYC release.
+	feeVersionID, err := fee.CurrentVersionID(tx)
+	if err != nil {
+		return nil, err
 	}
 
 	var rewardEvent RewardEvent
-	err = tx.QueryRow(`
-		INSERT INTO reward_events (user_id, stock_id, quantity, stock_price, total_value, description)
-		VALUES ($1, $2, $3, $4, $5, $6)
-		RETURNING id, user_id, stock_id, quantity, stock_price, total_value, event_type, status, description, created_at, updated_at
-	`, req.UserID, stockID, req.Quantity, stockPrice, totalValue, description).Scan(
-		&rewardEvent.ID, &rewardEvent.UserID, &rewardEvent.StockID, &rewardEvent.Quantity,
-		&rewardEvent.StockPrice, &rewardEvent.TotalValue, &rewardEvent.EventType,
-		&rewardEvent.Status, &rewardEvent.Description, &rewardEvent.CreatedAt, &rewardEvent.UpdatedAt,
-	)
+	err = scanRewardEvent(tx.QueryRow(`
+		INSERT INTO reward_events (user_id, stock_id, quantity, stock_price, total_value, status, description, requested_by, campaign, fee_version_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), NULLIF($9, ''), $10)
+		RETURNING `+rewardEventColumns,
+		req.UserID, stockID, req.Quantity, stockPrice, totalValue, status, description, req.RequestedBy, req.Campaign, feeVersionID), &rewardEvent)
 	if err != nil {
 		logrus.Errorf("Failed to create reward event: %v", err)
 		return nil, err
 	}
 
-	_, err = tx.Exec(`
-		INSERT INTO ledger_entries (reward_event_id, user_id, entry_type, account_type, stock_id, quantity, description)
-		VALUES ($1, $2, 'DEBIT', 'STOCK_UNITS', $3, $4, 'Stock reward credited')
-	`, rewardEvent.ID, req.UserID, stockID, req.Quantity)
-	if err != nil {
-		logrus.Errorf("Failed to create stock ledger entry: %v", err)
+	if status == RewardStatusCompleted {
+		if err = s.postReward(tx, &rewardEvent); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.Errorf("Failed to commit transaction: %v", err)
 		return nil, err
 	}
 
-	_, err = tx.Exec(`
-		INSERT INTO ledger_entries (reward_event_id, user_id, entry_type, account_type, amount, description)
-		VALUES ($1, $2, 'CREDIT', 'INR_CASH', $3, 'Cash outflow for stock purchase')
-	`, rewardEvent.ID, req.UserID, totalValue)
+	if status == RewardStatusPendingApproval {
+		logrus.Infof("Reward %d queued for approval: User %d, %.6f units of stock %d (value %.2f)",
+			rewardEvent.ID, req.UserID, req.Quantity, stockID, totalValue)
+		return &rewardEvent, nil
+	}
+	if status == RewardStatusPendingKYC {
+		logrus.Infof("Reward %d parked until KYC verification: User %d, %.6f units of stock %d",
+			rewardEvent.ID, req.UserID, req.Quantity, stockID)
+		return &rewardEvent, nil
+	}
+
+	logrus.Infof("Reward created successfully: User %d received %.6f units of stock %d", 
+		req.UserID, req.Quantity, stockID)
+	return &rewardEvent, nil
+}
+
+// postReward books the ledger entries and holdings update for a reward that
+// has cleared all checks. It runs inside the caller's transaction.
+func (s *RewardService) postReward(tx *sql.Tx, rewardEvent *RewardEvent) error {
+	if rewardEvent.FeeVersionID == nil {
+		return fmt.Errorf("reward %d has no fee version", rewardEvent.ID)
+	}
+
+	var exchange string
+	err := tx.QueryRow(`SELECT exchange FROM stocks WHERE id = $1`, rewardEvent.StockID).Scan(&exchange)
 	if err != nil {
-		logrus.Errorf("Failed to create cash ledger entry: %v", err)
-		return nil, err
+		logrus.Errorf("Failed to fetch stock exchange: %v", err)
+		return err
 	}
 
-	_, err = tx.Exec(`
-		INSERT INTO ledger_entries (reward_event_id, user_id, entry_type, account_type, amount, description)
-		VALUES ($1, $2, 'CREDIT', 'BROKERAGE_FEE', $3, 'Brokerage fee')
-	`, rewardEvent.ID, req.UserID, brokerageFee)
+	totalValue := rewardEvent.TotalValue
+	charges, err := fee.Compute(tx, *rewardEvent.FeeVersionID, exchange, totalValue)
 	if err != nil {
-		logrus.Errorf("Failed to create brokerage fee ledger entry: %v", err)
-		return nil, err
+		return err
 	}
 
 	_, err = tx.Exec(`
-		INSERT INTO ledger_entries (reward_event_id, user_id, entry_type, account_type, amount, description)
-		VALUES ($1, $2, 'CREDIT', 'STT_FEE', $3, 'Securities Transaction Tax')
-	`, rewardEvent.ID, req.UserID, sttFee)
+		INSERT INTO ledger_entries (reward_event_id, user_id, entry_type, account_type, stock_id, quantity, description)
+		VALUES ($1, $2, 'DEBIT', 'STOCK_UNITS', $3, $4, 'Stock reward credited')
+	`, rewardEvent.ID, rewardEvent.UserID, rewardEvent.StockID, rewardEvent.Quantity)
 	if err != nil {
-		logrus.Errorf("Failed to create STT fee ledger entry: %v", err)
-		return nil, err
+		logrus.Errorf("Failed to create stock ledger entry: %v", err)
+		return err
 	}
 
 	_, err = tx.Exec(`
 		INSERT INTO ledger_entries (reward_event_id, user_id, entry_type, account_type, amount, description)
-		VALUES ($1, $2, 'CREDIT', 'GST_FEE', $3, 'GST on brokerage')
-	`, rewardEvent.ID, req.UserID, gstFee)
+		VALUES ($1, $2, 'CREDIT', 'INR_CASH', $3, 'Cash outflow for stock purchase')
+	`, rewardEvent.ID, rewardEvent.UserID, totalValue)
 	if err != nil {
-		logrus.Errorf("Failed to create GST fee ledger entry: %v", err)
-		return nil, err
+		logrus.Errorf("Failed to create cash ledger entry: %v", err)
+		return err
+	}
+
+	for _, charge := range charges {
+		_, err = tx.Exec(`
+			INSERT INTO ledger_entries (reward_event_id, user_id, entry_type, account_type, amount, description)
+			VALUES ($1, $2, 'CREDIT', $3, $4, $5)
+		`, rewardEvent.ID, rewardEvent.UserID, charge.AccountType, charge.Amount, charge.Name)
+		if err != nil {
+			logrus.Errorf("Failed to create %s fee ledger entry: %v", charge.FeeType, err)
+			return err
+		}
 	}
 
 	_, err = tx.Exec(`
@@ -180,20 +254,20 @@ func (s *RewardService) CreateReward(req CreateRewardRequest) (*RewardEvent, err
 							(EXCLUDED.total_quantity * EXCLUDED.average_price)) / 
 							(user_stock_holdings.total_quantity + EXCLUDED.total_quantity),
 			updated_at = CURRENT_TIMESTAMP
-	`, req.UserID, stockID, req.Quantity, stockPrice)
+	`, rewardEvent.UserID, rewardEvent.StockID, rewardEvent.Quantity, rewardEvent.StockPrice)
 	if err != nil {
 		logrus.Errorf("Failed to update user stock holdings: %v", err)
-		return nil, err
+		return err
 	}
 
-	if err = tx.Commit(); err != nil {
-		logrus.Errorf("Failed to commit transaction: %v", err)
-		return nil, err
-	}
-
-	logrus.Infof("Reward created successfully: User %d received %.6f units of stock %d", 
-		req.UserID, req.Quantity, stockID)
-	return &rewardEvent, nil
+	return tax_lot.Acquire(tx, tax_lot.Acquisition{
+		UserID:        rewardEvent.UserID,
+		StockID:       rewardEvent.StockID,
+		Source:        tax_lot.SourceReward,
+		RewardEventID: rewardEvent.ID,
+		Quantity:      rewardEvent.Quantity,
+		Cost:          totalValue,
+	})
 }
 
 func (s *RewardService) GetAllRewards(page, pageSize int) (*PaginatedRewardsResponse, error) {
@@ -321,59 +395,64 @@ func (s *RewardService) AdjustReward(req AdjustRewardRequest) (*RewardEvent, err
 
 	var originalReward RewardEvent
 	err = tx.QueryRow(`
-		SELECT id, user_id, stock_id, quantity, stock_price, total_value, event_type, status
+		SELECT id, user_id, stock_id, quantity, stock_price, total_value, event_type, status, COALESCE(campaign, '')
 		FROM reward_events
 		WHERE id = $1
 	`, req.RewardEventID).Scan(
 		&originalReward.ID, &originalReward.UserID, &originalReward.StockID,
 		&originalReward.Quantity, &originalReward.StockPrice, &originalReward.TotalValue,
-		&originalReward.EventType, &originalReward.Status,
+		&originalReward.EventType, &originalReward.Status, &originalReward.Campaign,
 	)
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("reward event not found")
+		return nil, domain.NotFound(domain.CodeRewardNotFound, "reward event not found")
 	}
 	if err != nil {
 		logrus.Errorf("Failed to fetch reward event: %v", err)
 		return nil, err
 	}
 
-	if originalReward.EventType == "ADJUSTMENT" {
-		return nil, fmt.Errorf("cannot adjust an adjustment entry")
+	if originalReward.EventType == EventTypeAdjustment {
+		return nil, domain.BusinessRule(domain.CodeRewardNotAdjustable, "cannot adjust an adjustment entry")
+	}
+
+	if originalReward.Status != RewardStatusCompleted {
+		return nil, domain.BusinessRule(domain.CodeRewardNotAdjustable, "cannot adjust a reward with status %s", originalReward.Status)
 	}
 
 	if req.AdjustmentType == "REFUND" && req.Quantity != originalReward.Quantity {
-		return nil, fmt.Errorf("full refund must match original quantity: %.6f", originalReward.Quantity)
+		return nil, domain.Validation(domain.CodeInvalidAdjustment, "full refund must match original quantity: %.6f", originalReward.Quantity)
 	}
 	if req.AdjustmentType == "PARTIAL_REFUND" && req.Quantity >= originalReward.Quantity {
-		return nil, fmt.Errorf("partial refund quantity must be less than original: %.6f", originalReward.Quantity)
+		return nil, domain.Validation(domain.CodeInvalidAdjustment, "partial refund quantity must be less than original: %.6f", originalReward.Quantity)
 	}
 
 	var currentHoldings float64
 	err = tx.QueryRow(`
 		SELECT total_quantity FROM user_stock_holdings
 		WHERE user_id = $1 AND stock_id = $2
+		FOR UPDATE
 	`, originalReward.UserID, originalReward.StockID).Scan(&currentHoldings)
 	if err != nil {
-		return nil, fmt.Errorf("user stock holdings not found")
+		return nil, domain.NotFound(domain.CodeHoldingsNotFound, "user stock holdings not found")
+	}
+
+	if err = checkNoCorporateActionProcessing(tx, originalReward.StockID); err != nil {
+		return nil, err
 	}
 	if currentHoldings < req.Quantity {
-		return nil, fmt.Errorf("insufficient holdings: user has %.6f, adjustment requires %.6f", currentHoldings, req.Quantity)
+		return nil, domain.BusinessRule(domain.CodeInsufficientHoldings, "insufficient holdings: user has %.6f, adjustment requires %.6f", currentHoldings, req.Quantity)
 	}
 
 	adjustmentValue := req.Quantity * originalReward.StockPrice
 	description := fmt.Sprintf("%s for reward #%d: %s", req.AdjustmentType, req.RewardEventID, req.Reason)
 
 	var adjustmentEvent RewardEvent
-	err = tx.QueryRow(`
-		INSERT INTO reward_events (user_id, stock_id, quantity, stock_price, total_value, event_type, description)
-		VALUES ($1, $2, $3, $4, $5, 'ADJUSTMENT', $6)
-		RETURNING id, user_id, stock_id, quantity, stock_price, total_value, event_type, status, description, created_at, updated_at
-	`, originalReward.UserID, originalReward.StockID, req.Quantity, originalReward.StockPrice, adjustmentValue, description).Scan(
-		&adjustmentEvent.ID, &adjustmentEvent.UserID, &adjustmentEvent.StockID,
-		&adjustmentEvent.Quantity, &adjustmentEvent.StockPrice, &adjustmentEvent.TotalValue,
-		&adjustmentEvent.EventType, &adjustmentEvent.Status, &adjustmentEvent.Description,
-		&adjustmentEvent.CreatedAt, &adjustmentEvent.UpdatedAt,
-	)
+	err = scanRewardEvent(tx.QueryRow(`
+		INSERT INTO reward_events (user_id, stock_id, quantity, stock_price, total_value, event_type, description, campaign)
+		VALUES ($1, $2, $3, $4, $5, 'ADJUSTMENT', $6, NULLIF($7, ''))
+		RETURNING `+rewardEventColumns,
+		originalReward.UserID, originalReward.StockID, req.Quantity, originalReward.StockPrice, adjustmentValue, description,
+		originalReward.Campaign), &adjustmentEvent)
 	if err != nil {
 		logrus.Errorf("Failed to create adjustment event: %v", err)
 		return nil, err
@@ -408,6 +487,20 @@ func (s *RewardService) AdjustReward(req AdjustRewardRequest) (*RewardEvent, err
 		return nil, err
 	}
 
+	// The refunded units come out of the oldest lots, which need not be the
+	// refunded reward's own.
+	err = tax_lot.Dispose(tx, tax_lot.Disposal{
+		UserID:        originalReward.UserID,
+		StockID:       originalReward.StockID,
+		Type:          tax_lot.DisposalRefund,
+		RewardEventID: adjustmentEvent.ID,
+		Quantity:      req.Quantity,
+		Proceeds:      adjustmentValue,
+	})
+	if err != nil {
+		return nil, err
+	}
+
 	if err = tx.Commit(); err != nil {
 		logrus.Errorf("Failed to commit adjustment transaction: %v", err)
 		return nil, err
@@ -418,23 +511,367 @@ func (s *RewardService) AdjustReward(req AdjustRewardRequest) (*RewardEvent, err
 	return &adjustmentEvent, nil
 }
 
-func (s *RewardService) getFeeConfigurations(tx *sql.Tx) (map[string]float64, error) {
-	rows, err := tx.Query(`SELECT fee_type, percentage FROM fee_configurations WHERE is_active = true`)
+func (s *RewardService) ApproveReward(rewardID int, req ApproveRewardRequest) (*RewardEvent, error) {
+	tx, err := s.db.Begin()
 	if err != nil {
-		logrus.Errorf("Failed to query fee configurations: %v", err)
+		logrus.Errorf("Failed to begin transaction: %v", err)
 		return nil, err
 	}
-	defer rows.Close()
+	defer tx.Rollback()
+
+	rewardEvent, err := getPendingApprovalReward(tx, rewardID, req.ApprovedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	var stockSymbol string
+	var stockActive bool
+	err = tx.QueryRow(`SELECT symbol, is_active FROM stocks WHERE id = $1`, rewardEvent.StockID).Scan(&stockSymbol, &stockActive)
+	if err != nil {
+		logrus.Errorf("Failed to get stock details: %v", err)
+		return nil, err
+	}
+	if !stockActive {
+		return nil, domain.BusinessRule(domain.CodeStockDelisted, "stock '%s' is delisted and cannot receive new rewards", stockSymbol)
+	}
+
+	if err = checkPendingCorporateAction(tx, rewardEvent.StockID, stockSymbol); err != nil {
+		return nil, err
+	}
+
+	actionID, actionType, err := findUnitChangeSince(tx, rewardEvent)
+	if err != nil {
+		return nil, err
+	}
+	if actionID != 0 {
+		return nil, domain.BusinessRule(domain.CodeRewardUnitsChanged,
+			"stock '%s' had a %s corporate action (#%d) after reward %d was requested; its quantity and price are in the old units, reject it and issue a new reward",
+			stockSymbol, actionType, actionID, rewardID).WithMeta("corporate_action_id", actionID)
+	}
 
-	fees := make(map[string]float64)
+	if err = checkUserActive(tx, rewardEvent.UserID); err != nil {
+		return nil, err
+	}
+
+	if err = s.postReward(tx, rewardEvent); err != nil {
+		return nil, err
+	}
+
+	err = scanRewardEvent(tx.QueryRow(`
+		UPDATE reward_events
+		SET status = $1, reviewed_by = $2, reviewed_at = NOW(), updated_at = NOW()
+		WHERE id = $3
+		RETURNING `+rewardEventColumns,
+		RewardStatusCompleted, req.ApprovedBy, rewardID), rewardEvent)
+	if err != nil {
+		logrus.Errorf("Failed to update reward status: %v", err)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.Errorf("Failed to commit approval transaction: %v", err)
+		return nil, err
+	}
+
+	logrus.Infof("Reward %d approved by %s: User %d received %.6f units of stock %d",
+		rewardID, req.ApprovedBy, rewardEvent.UserID, rewardEvent.Quantity, rewardEvent.StockID)
+	return rewardEvent, nil
+}
+
+func (s *RewardService) RejectReward(rewardID int, req RejectRewardRequest) (*RewardEvent, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		logrus.Errorf("Failed to begin transaction: %v", err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rewardEvent, err := getPendingApprovalReward(tx, rewardID, req.RejectedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	err = scanRewardEvent(tx.QueryRow(`
+		UPDATE reward_events
+		SET status = $1, reviewed_by = $2, reviewed_at = NOW(), rejection_reason = $3, updated_at = NOW()
+		WHERE id = $4
+		RETURNING `+rewardEventColumns,
+		RewardStatusRejected, req.RejectedBy, req.Reason, rewardID), rewardEvent)
+	if err != nil {
+		logrus.Errorf("Failed to update reward status: %v", err)
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.Errorf("Failed to commit rejection transaction: %v", err)
+		return nil, err
+	}
+
+	logrus.Infof("Reward %d rejected by %s: %s", rewardID, req.RejectedBy, req.Reason)
+	return rewardEvent, nil
+}
+
+// ReleasePendingKYCRewards moves the user's PENDING_KYC rewards forward once
+// KYC is verified: rewards above the approval threshold go to
+// PENDING_APPROVAL, the rest are posted. Rewards whose stock was delisted or
+// had its units changed by a corporate action in the meantime are rejected;
+// rewards blocked by a due corporate action stay parked for a later release.
+// It returns the number of rewards released.
+func (s *RewardService) ReleasePendingKYCRewards(userID int) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		logrus.Errorf("Failed to begin transaction: %v", err)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if err = checkUserActive(tx, userID); err != nil {
+		return 0, err
+	}
+	verified, err := isKYCVerified(tx, userID)
+	if err != nil {
+		return 0, err
+	}
+	if !verified {
+		return 0, domain.BusinessRule(domain.CodeKYCNotVerified,
+			"user %d has not completed KYC verification", userID)
+	}
+
+	rows, err := tx.Query(`
+		SELECT `+rewardEventColumns+`
+		FROM reward_events
+		WHERE user_id = $1 AND status = $2
+		ORDER BY created_at
+		FOR UPDATE
+	`, userID, RewardStatusPendingKYC)
+	if err != nil {
+		logrus.Errorf("Failed to query rewards pending KYC: %v", err)
+		return 0, err
+	}
+
+	var parked []RewardEvent
 	for rows.Next() {
-		var feeType string
-		var percentage float64
-		if err := rows.Scan(&feeType, &percentage); err != nil {
-			return nil, err
+		var rewardEvent RewardEvent
+		if err := scanRewardEvent(rows, &rewardEvent); err != nil {
+			rows.Close()
+			logrus.Errorf("Failed to scan reward pending KYC: %v", err)
+			return 0, err
 		}
-		fees[feeType] = percentage
+		parked = append(parked, rewardEvent)
 	}
+	rows.Close()
+
+	released := 0
+	for i := range parked {
+		rewardEvent := &parked[i]
+
+		var stockSymbol string
+		var stockActive bool
+		err = tx.QueryRow(`SELECT symbol, is_active FROM stocks WHERE id = $1`, rewardEvent.StockID).Scan(&stockSymbol, &stockActive)
+		if err != nil {
+			logrus.Errorf("Failed to get stock details: %v", err)
+			return 0, err
+		}
+		if !stockActive {
+			if err = rejectParkedReward(tx, rewardEvent.ID, fmt.Sprintf("stock '%s' was delisted while awaiting KYC", stockSymbol)); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		actionID, actionType, err := findUnitChangeSince(tx, rewardEvent)
+		if err != nil {
+			return 0, err
+		}
+		if actionID != 0 {
+			reason := fmt.Sprintf("stock '%s' had a %s corporate action (#%d) while awaiting KYC", stockSymbol, actionType, actionID)
+			if err = rejectParkedReward(tx, rewardEvent.ID, reason); err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		err = checkPendingCorporateAction(tx, rewardEvent.StockID, stockSymbol)
+		if errors.Is(err, domain.ErrBusinessRule) {
+			logrus.Warnf("Reward %d stays parked: %v", rewardEvent.ID, err)
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		status := RewardStatusCompleted
+		if s.requiresApproval(rewardEvent.TotalValue) {
+			status = RewardStatusPendingApproval
+		} else if err = s.postReward(tx, rewardEvent); err != nil {
+			return 0, err
+		}
 
-	return fees, nil
+		_, err = tx.Exec(`UPDATE reward_events SET status = $1, updated_at = NOW() WHERE id = $2`, status, rewardEvent.ID)
+		if err != nil {
+			logrus.Errorf("Failed to update reward status: %v", err)
+			return 0, err
+		}
+		released++
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.Errorf("Failed to commit KYC release transaction: %v", err)
+		return 0, err
+	}
+
+	logrus.Infof("Released %d of %d rewards parked for KYC for user %d", released, len(parked), userID)
+	return released, nil
+}
+
+func rejectParkedReward(tx *sql.Tx, rewardID int, reason string) error {
+	_, err := tx.Exec(`
+		UPDATE reward_events
+		SET status = $1, rejection_reason = $2, reviewed_at = NOW(), updated_at = NOW()
+		WHERE id = $3
+	`, RewardStatusRejected, reason, rewardID)
+	if err != nil {
+		logrus.Errorf("Failed to reject reward %d: %v", rewardID, err)
+	}
+	return err
+}
+
+// findUnitChangeSince returns the corporate action that changed the units of
+// the reward's stock after the reward was requested, or 0 when there is none.
+// A parked reward carries the quantity and price quoted at request time;
+// after a split, bonus or reverse split, posting it would credit units that
+// no longer exist at a price that no longer applies.
+func findUnitChangeSince(tx *sql.Tx, rewardEvent *RewardEvent) (int, string, error) {
+	var actionID int
+	var actionType string
+	err := tx.QueryRow(`
+		SELECT ca.id, ca.action_type FROM corporate_actions ca
+		JOIN reward_events re ON re.id = $2
+		WHERE ca.stock_id = $1 AND ca.status = 'COMPLETED'
+		AND ca.action_type IN ('STOCK_SPLIT', 'BONUS', 'REVERSE_SPLIT')
+		AND ca.processed_at > re.created_at
+		ORDER BY ca.processed_at
+		LIMIT 1
+	`, rewardEvent.StockID, rewardEvent.ID).Scan(&actionID, &actionType)
+	if err == sql.ErrNoRows {
+		return 0, "", nil
+	}
+	if err != nil {
+		logrus.Errorf("Failed to check corporate actions since reward %d: %v", rewardEvent.ID, err)
+		return 0, "", err
+	}
+	return actionID, actionType, nil
+}
+
+// getPendingApprovalReward locks a reward awaiting approval and enforces that
+// the reviewer is not the operator who requested it.
+func getPendingApprovalReward(tx *sql.Tx, rewardID int, reviewer string) (*RewardEvent, error) {
+	var rewardEvent RewardEvent
+	err := scanRewardEvent(tx.QueryRow(`
+		SELECT `+rewardEventColumns+`
+		FROM reward_events
+		WHERE id = $1
+		FOR UPDATE
+	`, rewardID), &rewardEvent)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeRewardNotFound, "reward event not found")
+	}
+	if err != nil {
+		logrus.Errorf("Failed to fetch reward event: %v", err)
+		return nil, err
+	}
+
+	if rewardEvent.Status != RewardStatusPendingApproval {
+		return nil, domain.Conflict(domain.CodeRewardNotPendingReview, "reward is not pending approval (status: %s)", rewardEvent.Status)
+	}
+	if rewardEvent.RequestedBy == reviewer {
+		return nil, domain.BusinessRule(domain.CodeSameOperatorReview, "reward must be reviewed by a different operator than the one who requested it")
+	}
+
+	return &rewardEvent, nil
+}
+
+func checkPendingCorporateAction(tx *sql.Tx, stockID int, stockSymbol string) error {
+	var pendingAction string
+	err := tx.QueryRow(`
+		SELECT action_type FROM corporate_actions 
+		WHERE stock_id = $1
+		AND (status = 'PROCESSING'
+		     OR (status IN ('PENDING', 'APPROVED') AND (effective_date <= CURRENT_DATE OR record_date < CURRENT_DATE)))
+		AND action_type NOT IN ('DIVIDEND', 'SYMBOL_CHANGE')
+		LIMIT 1
+	`, stockID).Scan(&pendingAction)
+	if err == nil {
+		return domain.BusinessRule(domain.CodePendingCorporateAction, "stock '%s' has a pending %s corporate action. Please process it before issuing new rewards", stockSymbol, pendingAction)
+	} else if err != sql.ErrNoRows {
+		logrus.Errorf("Failed to check corporate actions: %v", err)
+		return err
+	}
+	return nil
+}
+
+// checkNoCorporateActionProcessing refuses while a corporate action on the
+// stock is part-way through its chunks. The chunks write holdings and lots
+// from the snapshot taken when processing started and would undo a change
+// made in between. Callers lock the holding first: starting an action locks
+// the same rows to take its snapshot, so either the snapshot sees the change
+// or the change sees the action.
+func checkNoCorporateActionProcessing(tx *sql.Tx, stockID int) error {
+	var actionID int
+	var actionType string
+	err := tx.QueryRow(`
+		SELECT id, action_type FROM corporate_actions
+		WHERE status = 'PROCESSING'
+		AND (stock_id = $1 OR merger_to_stock_id = $1 OR spinoff_stock_id = $1)
+		LIMIT 1
+	`, stockID).Scan(&actionID, &actionType)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		logrus.Errorf("Failed to check corporate actions in progress: %v", err)
+		return err
+	}
+	return domain.Conflict(domain.CodeCorporateActionInProgress,
+		"%s corporate action %d on this stock is still processing; retry once it completes", actionType, actionID).
+		WithMeta("corporate_action_id", actionID)
+}
+
+// idempotencySuffixPattern matches what idempotencySuffix appends to a
+// reward's description.
+const idempotencySuffixPattern = ` \[idempotency:.*\]$`
+
+// idempotencySuffix records the idempotency key of a request in the reward's
+// description, where the idempotency check looks for it.
+func idempotencySuffix(key string) string {
+	return fmt.Sprintf(" [idempotency:%s]", key)
+}
+
+func checkUserActive(tx *sql.Tx, userID int) error {
+	var isActive bool
+	err := tx.QueryRow(`SELECT is_active FROM users WHERE id = $1`, userID).Scan(&isActive)
+	if err == sql.ErrNoRows {
+		return domain.NotFound(domain.CodeUserNotFound, "user %d not found", userID)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to check user: %v", err)
+		return err
+	}
+	if !isActive {
+		return domain.BusinessRule(domain.CodeUserInactive, "user %d is inactive and cannot receive rewards", userID)
+	}
+	return nil
+}
+
+func isKYCVerified(tx *sql.Tx, userID int) (bool, error) {
+	var kycStatus string
+	err := tx.QueryRow(`SELECT kyc_status FROM users WHERE id = $1`, userID).Scan(&kycStatus)
+	if err == sql.ErrNoRows {
+		return false, domain.NotFound(domain.CodeUserNotFound, "user %d not found", userID)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to check KYC status: %v", err)
+		return false, err
+	}
+	return kycStatus == "KYC_VERIFIED", nil
 }
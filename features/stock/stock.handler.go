@@ -0,0 +1,58 @@
+package stock
+
+import (
+	"net/http"
+	"strconv"
+
+	"stocky-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type StockHandler struct {
+	service *StockService
+}
+
+func NewStockHandler(service *StockService) *StockHandler {
+	return &StockHandler{service: service}
+}
+
+func (h *StockHandler) GetAllStocks(c *gin.Context) {
+	stocks, err := h.service.GetAllStocks()
+	if err != nil {
+		logrus.Errorf("Error getting stocks: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve stocks"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": stocks})
+}
+
+func (h *StockHandler) GetStockByID(c *gin.Context) {
+	stockID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid stock ID", err.Error()))
+		return
+	}
+
+	stock, err := h.service.GetStockByID(stockID)
+	if err != nil {
+		logrus.Errorf("Error getting stock: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve stock"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": stock})
+}
+
+func (h *StockHandler) GetStockBySymbol(c *gin.Context) {
+	stock, err := h.service.GetStockBySymbol(c.Param("symbol"))
+	if err != nil {
+		logrus.Errorf("Error getting stock: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve stock"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": stock})
+}
@@ -0,0 +1,26 @@
+package stock
+
+import (
+	"time"
+)
+
+type Stock struct {
+	ID              int            `json:"id"`
+	Symbol          string         `json:"symbol"`
+	ISIN            string         `json:"isin,omitempty"`
+	Name            string         `json:"name"`
+	Exchange        string         `json:"exchange"`
+	CurrentPrice    float64        `json:"current_price"`
+	IsActive        bool           `json:"is_active"`
+	PreviousSymbols []SymbolChange `json:"previous_symbols,omitempty"`
+	CreatedAt       time.Time      `json:"created_at"`
+	UpdatedAt       time.Time      `json:"updated_at"`
+}
+
+// SymbolChange is a completed SYMBOL_CHANGE corporate action on the stock.
+type SymbolChange struct {
+	CorporateActionID int    `json:"corporate_action_id"`
+	OldSymbol         string `json:"old_symbol"`
+	NewSymbol         string `json:"new_symbol"`
+	EffectiveDate     string `json:"effective_date"`
+}
@@ -0,0 +1,46 @@
+package stock
+
+import (
+	"database/sql"
+)
+
+// Ref is the current identity of a stock looked up by a symbol a client may
+// have cached before a symbol change.
+type Ref struct {
+	ID           int
+	Symbol       string
+	ISIN         string
+	Name         string
+	CurrentPrice float64
+	IsActive     bool
+}
+
+type queryer interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// Resolve finds the stock a client means by symbol: a current symbol wins,
+// then an ISIN, then a symbol the stock carried before a completed
+// SYMBOL_CHANGE (the most recently retired one, if a symbol was reused).
+// It returns sql.ErrNoRows when nothing matches.
+func Resolve(q queryer, symbol string) (*Ref, error) {
+	var ref Ref
+	err := q.QueryRow(`
+		SELECT s.id, s.symbol, COALESCE(s.isin, ''), s.name, s.current_price, COALESCE(s.is_active, true)
+		FROM (
+			SELECT id AS stock_id, 0 AS rank, NULL::date AS retired_on FROM stocks WHERE symbol = $1
+			UNION ALL
+			SELECT id, 1, NULL FROM stocks WHERE isin = $1
+			UNION ALL
+			SELECT stock_id, 2, effective_date FROM corporate_actions
+			WHERE action_type = 'SYMBOL_CHANGE' AND status = 'COMPLETED' AND old_symbol = $1
+		) m
+		JOIN stocks s ON s.id = m.stock_id
+		ORDER BY m.rank, m.retired_on DESC NULLS LAST
+		LIMIT 1
+	`, symbol).Scan(&ref.ID, &ref.Symbol, &ref.ISIN, &ref.Name, &ref.CurrentPrice, &ref.IsActive)
+	if err != nil {
+		return nil, err
+	}
+	return &ref, nil
+}
@@ -0,0 +1,14 @@
+package stock
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(router *gin.RouterGroup, handler *StockHandler) {
+	stocks := router.Group("/stocks")
+	{
+		stocks.GET("", handler.GetAllStocks)
+		stocks.GET("/symbol/:symbol", handler.GetStockBySymbol)
+		stocks.GET("/:id", handler.GetStockByID)
+	}
+}
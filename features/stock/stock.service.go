@@ -0,0 +1,103 @@
+package stock
+
+import (
+	"database/sql"
+
+	"stocky-backend/domain"
+
+	"github.com/sirupsen/logrus"
+)
+
+const stockColumns = `id, symbol, COALESCE(isin, ''), name, exchange, current_price, COALESCE(is_active, true), created_at, updated_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+type StockService struct {
+	db *sql.DB
+}
+
+func NewStockService(db *sql.DB) *StockService {
+	return &StockService{db: db}
+}
+
+func scanStock(row rowScanner, stock *Stock) error {
+	return row.Scan(&stock.ID, &stock.Symbol, &stock.ISIN, &stock.Name, &stock.Exchange,
+		&stock.CurrentPrice, &stock.IsActive, &stock.CreatedAt, &stock.UpdatedAt)
+}
+
+func (s *StockService) GetAllStocks() ([]Stock, error) {
+	rows, err := s.db.Query(`SELECT ` + stockColumns + ` FROM stocks ORDER BY symbol`)
+	if err != nil {
+		logrus.Errorf("Failed to query stocks: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	stocks := []Stock{}
+	for rows.Next() {
+		var stock Stock
+		if err := scanStock(rows, &stock); err != nil {
+			logrus.Errorf("Failed to scan stock: %v", err)
+			return nil, err
+		}
+		stocks = append(stocks, stock)
+	}
+	return stocks, rows.Err()
+}
+
+func (s *StockService) GetStockByID(id int) (*Stock, error) {
+	var stock Stock
+	err := scanStock(s.db.QueryRow(`SELECT `+stockColumns+` FROM stocks WHERE id = $1`, id), &stock)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeStockNotFound, "stock %d not found", id)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to query stock: %v", err)
+		return nil, err
+	}
+
+	if stock.PreviousSymbols, err = s.getSymbolChanges(stock.ID); err != nil {
+		return nil, err
+	}
+	return &stock, nil
+}
+
+// GetStockBySymbol accepts a current symbol, an ISIN or a retired symbol
+// and returns the stock as it is now.
+func (s *StockService) GetStockBySymbol(symbol string) (*Stock, error) {
+	ref, err := Resolve(s.db, symbol)
+	if err == sql.ErrNoRows {
+		return nil, domain.NotFound(domain.CodeStockNotFound, "stock '%s' not found", symbol)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to resolve stock %s: %v", symbol, err)
+		return nil, err
+	}
+	return s.GetStockByID(ref.ID)
+}
+
+func (s *StockService) getSymbolChanges(stockID int) ([]SymbolChange, error) {
+	rows, err := s.db.Query(`
+		SELECT id, old_symbol, new_symbol, TO_CHAR(effective_date, 'YYYY-MM-DD')
+		FROM corporate_actions
+		WHERE stock_id = $1 AND action_type = 'SYMBOL_CHANGE' AND status = 'COMPLETED'
+		ORDER BY effective_date DESC, id DESC
+	`, stockID)
+	if err != nil {
+		logrus.Errorf("Failed to query symbol changes: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []SymbolChange
+	for rows.Next() {
+		var change SymbolChange
+		if err := rows.Scan(&change.CorporateActionID, &change.OldSymbol, &change.NewSymbol, &change.EffectiveDate); err != nil {
+			return nil, err
+		}
+		changes = append(changes, change)
+	}
+	return changes, rows.Err()
+}
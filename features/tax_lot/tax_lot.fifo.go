@@ -0,0 +1,348 @@
+package tax_lot
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// Acquisition is a lot to open. A zero AcquiredAt means now.
+type Acquisition struct {
+	UserID            int
+	StockID           int
+	Source            string
+	RewardEventID     int
+	CorporateActionID int
+	ParentLotID       int
+	AcquiredAt        time.Time
+	Quantity          float64
+	Cost              float64
+}
+
+// Acquire opens a lot. It runs inside the caller's transaction, next to the
+// holdings update for the same units.
+func Acquire(tx *sql.Tx, lot Acquisition) error {
+	_, err := tx.Exec(`
+		INSERT INTO tax_lots (user_id, stock_id, source, reward_event_id, corporate_action_id, parent_lot_id, acquired_at,
+		                      quantity, cost, remaining_quantity, remaining_cost)
+		VALUES ($1, $2, $3, $4, $5, $6, COALESCE($7::timestamp, LOCALTIMESTAMP), $8, $9, $8, $9)
+	`, lot.UserID, lot.StockID, lot.Source, nullInt(lot.RewardEventID), nullInt(lot.CorporateActionID), nullInt(lot.ParentLotID),
+		nullTime(lot.AcquiredAt), roundQuantity(lot.Quantity), roundAmount(lot.Cost))
+	if err != nil {
+		logrus.Errorf("Failed to open tax lot for user %d: %v", lot.UserID, err)
+	}
+	return err
+}
+
+// Disposal is a number of units given up for Proceeds in total. A zero
+// DisposedAt means now.
+type Disposal struct {
+	UserID            int
+	StockID           int
+	Type              string
+	RewardEventID     int
+	CorporateActionID int
+	DisposedAt        time.Time
+	Quantity          float64
+	Proceeds          float64
+}
+
+// Dispose consumes units from the user's oldest open lots of the stock,
+// first in first out, and records one disposal per lot touched with its
+// share of the cost and the proceeds. Units held more than twelve months
+// are long term, as for listed equity.
+func Dispose(tx *sql.Tx, disposal Disposal) error {
+	quantity := roundQuantity(disposal.Quantity)
+	if quantity <= 0 {
+		return nil
+	}
+
+	pieces, err := takeOldest(tx, disposal.UserID, disposal.StockID, quantity)
+	if err != nil {
+		return err
+	}
+
+	proceeds := splitProRata(disposal.Proceeds, quantity, pieces, roundAmount)
+	for i, piece := range pieces {
+		if err = reduceLot(tx, piece.lot.id, piece.quantity, piece.cost); err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO tax_lot_disposals (tax_lot_id, user_id, stock_id, disposal_type, reward_event_id, corporate_action_id,
+			                               acquired_at, disposed_at, quantity, cost, proceeds, term)
+			SELECT l.id, l.user_id, l.stock_id, $2::varchar, $3::integer, $4::integer, l.acquired_at, d.disposed_at,
+			       $5::numeric, $6::numeric, $7::numeric,
+			       CASE WHEN d.disposed_at::date > (l.acquired_at + INTERVAL '12 months')::date THEN $8::varchar ELSE $9::varchar END
+			FROM tax_lots l, (SELECT COALESCE($10::timestamp, LOCALTIMESTAMP) AS disposed_at) d
+			WHERE l.id = $1
+		`, piece.lot.id, disposal.Type, nullInt(disposal.RewardEventID), nullInt(disposal.CorporateActionID),
+			piece.quantity, piece.cost, proceeds[i], TermLong, TermShort, nullTime(disposal.DisposedAt))
+		if err != nil {
+			logrus.Errorf("Failed to record disposal of tax lot %d: %v", piece.lot.id, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// Conversion carries a corporate action's unit change over to the lots.
+//
+//	ConvertReplace  the oldest Quantity units become Entitled units of
+//	                ToStockID, keeping their acquisition dates and cost
+//	                (split, reverse split, merger)
+//	ConvertDemerge  the units stay; CostShare of their cost moves to Entitled
+//	                units of ToStockID with the same acquisition dates
+//	                (spin-off)
+//	ConvertBonus    Entitled - Quantity new units at nil cost, acquired on At
+//
+// Settled is what the fractional policy left of Entitled. A fraction paid
+// out as CashInLieu is disposed of on At; any other difference is rounding
+// and only changes the quantity of the last lot opened.
+type Conversion struct {
+	Kind              string
+	UserID            int
+	CorporateActionID int
+	Source            string
+	FromStockID       int
+	ToStockID         int
+	Quantity          float64
+	Entitled          float64
+	Settled           float64
+	CashInLieu        float64
+	CostShare         float64
+	At                time.Time
+}
+
+// Convert applies a conversion inside the corporate action's transaction.
+// Converted lots are not edited in place: the converted part is taken out of
+// them and successor lots are opened with parent_lot_id pointing back.
+func Convert(tx *sql.Tx, conversion Conversion) error {
+	quantity := roundQuantity(conversion.Quantity)
+	entitled := roundQuantity(conversion.Entitled)
+	if quantity <= 0 || entitled <= 0 {
+		return nil
+	}
+
+	var opened []Acquisition
+	switch conversion.Kind {
+	case ConvertBonus:
+		opened = append(opened, Acquisition{
+			AcquiredAt: conversion.At,
+			Quantity:   roundQuantity(entitled - quantity),
+		})
+
+	case ConvertReplace, ConvertDemerge:
+		pieces, err := takeOldest(tx, conversion.UserID, conversion.FromStockID, quantity)
+		if err != nil {
+			return err
+		}
+
+		units := splitProRata(entitled, quantity, pieces, roundQuantity)
+		for i, piece := range pieces {
+			cost := piece.cost
+			if conversion.Kind == ConvertDemerge {
+				cost = roundAmount(piece.cost * conversion.CostShare)
+				err = reduceLot(tx, piece.lot.id, 0, cost)
+			} else {
+				err = reduceLot(tx, piece.lot.id, piece.quantity, piece.cost)
+			}
+			if err != nil {
+				return err
+			}
+
+			opened = append(opened, Acquisition{
+				ParentLotID: piece.lot.id,
+				AcquiredAt:  piece.lot.acquiredAt,
+				Quantity:    units[i],
+				Cost:        cost,
+			})
+		}
+
+	default:
+		return fmt.Errorf("unknown tax lot conversion: %s", conversion.Kind)
+	}
+
+	fraction := roundQuantity(entitled - conversion.Settled)
+	if conversion.CashInLieu <= 0 && fraction != 0 {
+		fraction = trimNewest(opened, fraction)
+	}
+
+	for _, lot := range opened {
+		if lot.Quantity <= 0 {
+			continue
+		}
+		lot.UserID = conversion.UserID
+		lot.StockID = conversion.ToStockID
+		lot.Source = conversion.Source
+		lot.CorporateActionID = conversion.CorporateActionID
+		if err := Acquire(tx, lot); err != nil {
+			return err
+		}
+	}
+
+	if conversion.CashInLieu > 0 && fraction > 0 {
+		return Dispose(tx, Disposal{
+			UserID:            conversion.UserID,
+			StockID:           conversion.ToStockID,
+			Type:              DisposalCashInLieu,
+			CorporateActionID: conversion.CorporateActionID,
+			DisposedAt:        conversion.At,
+			Quantity:          fraction,
+			Proceeds:          conversion.CashInLieu,
+		})
+	}
+	return nil
+}
+
+type openLot struct {
+	id                int
+	acquiredAt        time.Time
+	remainingQuantity float64
+	remainingCost     float64
+}
+
+// lotPiece is the part of a lot a disposal or conversion takes.
+type lotPiece struct {
+	lot      openLot
+	quantity float64
+	cost     float64
+}
+
+// takeOldest locks the user's open lots of the stock and picks quantity
+// units from them, oldest first, with their share of the cost. The lots are
+// not changed. Lots that cannot cover quantity mean they are out of step
+// with the holding, which is an error.
+func takeOldest(tx *sql.Tx, userID, stockID int, quantity float64) ([]lotPiece, error) {
+	rows, err := tx.Query(`
+		SELECT id, acquired_at, remaining_quantity, remaining_cost
+		FROM tax_lots
+		WHERE user_id = $1 AND stock_id = $2 AND remaining_quantity > 0
+		ORDER BY acquired_at, id
+		FOR UPDATE
+	`, userID, stockID)
+	if err != nil {
+		logrus.Errorf("Failed to query tax lots for user %d: %v", userID, err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var lots []openLot
+	for rows.Next() {
+		var lot openLot
+		if err := rows.Scan(&lot.id, &lot.acquiredAt, &lot.remainingQuantity, &lot.remainingCost); err != nil {
+			return nil, err
+		}
+		lots = append(lots, lot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	pieces, left := pickOldest(lots, quantity)
+	if left > 0 {
+		return nil, fmt.Errorf("tax lots of user %d in stock %d are %.6f units short of %.6f", userID, stockID, left, quantity)
+	}
+	return pieces, nil
+}
+
+// pickOldest takes quantity units from lots, which are in FIFO order, with
+// their share of each lot's cost. It returns the units lots could not cover.
+func pickOldest(lots []openLot, quantity float64) ([]lotPiece, float64) {
+	var pieces []lotPiece
+	left := roundQuantity(quantity)
+	for _, lot := range lots {
+		if left <= 0 {
+			break
+		}
+
+		piece := lotPiece{lot: lot, quantity: lot.remainingQuantity, cost: lot.remainingCost}
+		if left < lot.remainingQuantity {
+			piece.quantity = left
+			piece.cost = roundAmount(lot.remainingCost * left / lot.remainingQuantity)
+		}
+		left = roundQuantity(left - piece.quantity)
+		pieces = append(pieces, piece)
+	}
+	return pieces, left
+}
+
+// splitProRata shares total out over pieces in proportion to their part of
+// quantity, rounding each share. The last piece takes what is left, so the
+// shares always add up to the rounded total.
+func splitProRata(total, quantity float64, pieces []lotPiece, round func(float64) float64) []float64 {
+	shares := make([]float64, len(pieces))
+	left := round(total)
+	for i, piece := range pieces {
+		share := left
+		if i < len(pieces)-1 {
+			share = round(total * piece.quantity / quantity)
+		}
+		left = round(left - share)
+		shares[i] = share
+	}
+	return shares
+}
+
+// trimNewest takes fraction units off the newest of opened lots (or adds
+// them when fraction is negative), never leaving a lot below zero. It returns
+// what could not be taken.
+func trimNewest(opened []Acquisition, fraction float64) float64 {
+	for i := len(opened) - 1; i >= 0 && fraction != 0; i-- {
+		adjusted := math.Max(0, roundQuantity(opened[i].Quantity-fraction))
+		fraction = roundQuantity(fraction - (opened[i].Quantity - adjusted))
+		opened[i].Quantity = adjusted
+	}
+	return fraction
+}
+
+// longTermFrom is the first day a disposal of units acquired at acquiredAt
+// is long term: the day after the first anniversary, which for 29 February
+// is 28 February, as the term check in Dispose counts twelve months.
+func longTermFrom(acquiredAt time.Time) time.Time {
+	year, month, day := acquiredAt.Date()
+	anniversary := time.Date(year+1, month, day, 0, 0, 0, 0, acquiredAt.Location())
+	if anniversary.Day() != day {
+		anniversary = anniversary.AddDate(0, 0, -anniversary.Day())
+	}
+	return anniversary.AddDate(0, 0, 1)
+}
+
+func reduceLot(tx *sql.Tx, lotID int, quantity, cost float64) error {
+	_, err := tx.Exec(`
+		UPDATE tax_lots
+		SET remaining_quantity = remaining_quantity - $2,
+		    remaining_cost = GREATEST(remaining_cost - $3, 0),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+	`, lotID, quantity, cost)
+	if err != nil {
+		logrus.Errorf("Failed to reduce tax lot %d: %v", lotID, err)
+	}
+	return err
+}
+
+func nullInt(i int) *int {
+	if i == 0 {
+		return nil
+	}
+	return &i
+}
+
+func nullTime(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+func roundQuantity(quantity float64) float64 {
+	return math.Round(quantity*1e6) / 1e6
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*10000) / 10000
+}
@@ -0,0 +1,213 @@
+package tax_lot
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func day(year int, month time.Month, d int) time.Time {
+	return time.Date(year, month, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPickOldest(t *testing.T) {
+	lots := []openLot{
+		{id: 1, acquiredAt: day(2024, time.January, 10), remainingQuantity: 10, remainingCost: 1000},
+		{id: 2, acquiredAt: day(2024, time.June, 5), remainingQuantity: 3, remainingCost: 450},
+		{id: 3, acquiredAt: day(2025, time.February, 1), remainingQuantity: 0.5, remainingCost: 100},
+	}
+
+	type piece struct {
+		lotID    int
+		quantity float64
+		cost     float64
+	}
+	tests := []struct {
+		name     string
+		quantity float64
+		want     []piece
+		wantLeft float64
+	}{
+		{
+			name:     "part of the oldest lot",
+			quantity: 4,
+			want:     []piece{{lotID: 1, quantity: 4, cost: 400}},
+		},
+		{
+			name:     "whole oldest lot",
+			quantity: 10,
+			want:     []piece{{lotID: 1, quantity: 10, cost: 1000}},
+		},
+		{
+			name:     "across lots, oldest first",
+			quantity: 12,
+			want:     []piece{{lotID: 1, quantity: 10, cost: 1000}, {lotID: 2, quantity: 2, cost: 300}},
+		},
+		{
+			name:     "every lot",
+			quantity: 13.5,
+			want:     []piece{{lotID: 1, quantity: 10, cost: 1000}, {lotID: 2, quantity: 3, cost: 450}, {lotID: 3, quantity: 0.5, cost: 100}},
+		},
+		{
+			name:     "cost share is rounded",
+			quantity: 1.0 / 3,
+			want:     []piece{{lotID: 1, quantity: 0.333333, cost: 33.3333}},
+		},
+		{
+			name:     "lots short",
+			quantity: 15,
+			want:     []piece{{lotID: 1, quantity: 10, cost: 1000}, {lotID: 2, quantity: 3, cost: 450}, {lotID: 3, quantity: 0.5, cost: 100}},
+			wantLeft: 1.5,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pieces, left := pickOldest(lots, tt.quantity)
+
+			got := make([]piece, 0, len(pieces))
+			for _, p := range pieces {
+				got = append(got, piece{lotID: p.lot.id, quantity: p.quantity, cost: p.cost})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("pickOldest(%v) = %+v, want %+v", tt.quantity, got, tt.want)
+			}
+			if left != tt.wantLeft {
+				t.Errorf("pickOldest(%v) left = %v, want %v", tt.quantity, left, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestSplitProRata(t *testing.T) {
+	pieces := func(quantities ...float64) []lotPiece {
+		var p []lotPiece
+		for _, q := range quantities {
+			p = append(p, lotPiece{quantity: q})
+		}
+		return p
+	}
+
+	tests := []struct {
+		name     string
+		total    float64
+		quantity float64
+		pieces   []lotPiece
+		round    func(float64) float64
+		want     []float64
+	}{
+		{
+			name:     "single piece takes everything",
+			total:    1234.56789,
+			quantity: 5,
+			pieces:   pieces(5),
+			round:    roundAmount,
+			want:     []float64{1234.5679},
+		},
+		{
+			name:     "proceeds by quantity",
+			total:    1500,
+			quantity: 15,
+			pieces:   pieces(10, 5),
+			round:    roundAmount,
+			want:     []float64{1000, 500},
+		},
+		{
+			name:     "rounding remainder goes to the last piece",
+			total:    100,
+			quantity: 3,
+			pieces:   pieces(1, 1, 1),
+			round:    roundAmount,
+			want:     []float64{33.3333, 33.3333, 33.3334},
+		},
+		{
+			name:     "entitled units in quantity precision",
+			total:    10,
+			quantity: 3,
+			pieces:   pieces(1, 2),
+			round:    roundQuantity,
+			want:     []float64{3.333333, 6.666667},
+		},
+		{
+			name:     "zero proceeds",
+			total:    0,
+			quantity: 4,
+			pieces:   pieces(3, 1),
+			round:    roundAmount,
+			want:     []float64{0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := splitProRata(tt.total, tt.quantity, tt.pieces, tt.round)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitProRata(%v) = %v, want %v", tt.total, got, tt.want)
+			}
+
+			var sum float64
+			for _, share := range got {
+				sum = tt.round(sum + share)
+			}
+			if sum != tt.round(tt.total) {
+				t.Errorf("splitProRata(%v) shares add up to %v", tt.total, sum)
+			}
+		})
+	}
+}
+
+func TestTrimNewest(t *testing.T) {
+	tests := []struct {
+		name       string
+		quantities []float64
+		fraction   float64
+		want       []float64
+		wantLeft   float64
+	}{
+		{name: "off the newest lot", quantities: []float64{5, 2.5}, fraction: 0.5, want: []float64{5, 2}},
+		{name: "across lots", quantities: []float64{5, 0.3}, fraction: 0.5, want: []float64{4.8, 0}},
+		{name: "added to the newest lot", quantities: []float64{5, 2}, fraction: -0.000001, want: []float64{5, 2.000001}},
+		{name: "more than the lots hold", quantities: []float64{0.2, 0.3}, fraction: 1, want: []float64{0, 0}, wantLeft: 0.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opened := make([]Acquisition, len(tt.quantities))
+			for i, q := range tt.quantities {
+				opened[i].Quantity = q
+			}
+
+			left := trimNewest(opened, tt.fraction)
+
+			got := make([]float64, len(opened))
+			for i, lot := range opened {
+				got[i] = lot.Quantity
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("trimNewest(%v, %v) = %v, want %v", tt.quantities, tt.fraction, got, tt.want)
+			}
+			if left != tt.wantLeft {
+				t.Errorf("trimNewest(%v, %v) left = %v, want %v", tt.quantities, tt.fraction, left, tt.wantLeft)
+			}
+		})
+	}
+}
+
+func TestLongTermFrom(t *testing.T) {
+	tests := []struct {
+		acquired time.Time
+		want     time.Time
+	}{
+		{acquired: day(2024, time.January, 15), want: day(2025, time.January, 16)},
+		{acquired: time.Date(2024, time.March, 31, 18, 30, 0, 0, time.UTC), want: day(2025, time.April, 1)},
+		{acquired: day(2024, time.December, 31), want: day(2026, time.January, 1)},
+		{acquired: day(2024, time.February, 29), want: day(2025, time.March, 1)},
+		{acquired: day(2023, time.February, 28), want: day(2024, time.February, 29)},
+	}
+
+	for _, tt := range tests {
+		if got := longTermFrom(tt.acquired); !got.Equal(tt.want) {
+			t.Errorf("longTermFrom(%s) = %s, want %s", tt.acquired.Format("2006-01-02"), got.Format("2006-01-02"), tt.want.Format("2006-01-02"))
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package tax_lot
+
+import (
+	"net/http"
+	"strconv"
+
+	"stocky-backend/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+type TaxLotHandler struct {
+	service *TaxLotService
+}
+
+func NewTaxLotHandler(service *TaxLotService) *TaxLotHandler {
+	return &TaxLotHandler{service: service}
+}
+
+func (h *TaxLotHandler) GetLots(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+		return
+	}
+
+	var filter LotFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.Error(middleware.BadRequestError("Invalid query parameters", err.Error()))
+		return
+	}
+
+	lots, err := h.service.GetLots(userID, filter)
+	if err != nil {
+		logrus.Errorf("Error getting tax lots: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve tax lots"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": lots})
+}
+
+func (h *TaxLotHandler) GetCapitalGains(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+		return
+	}
+
+	var filter CapitalGainsFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.Error(middleware.BadRequestError("Invalid query parameters", err.Error()))
+		return
+	}
+
+	report, err := h.service.GetCapitalGains(userID, filter)
+	if err != nil {
+		logrus.Errorf("Error building capital gains report: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to build capital gains report"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": report})
+}
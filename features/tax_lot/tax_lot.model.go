@@ -0,0 +1,99 @@
+package tax_lot
+
+import (
+	"time"
+)
+
+// Lot sources besides the corporate action types (STOCK_SPLIT, BONUS,
+// MERGER, ...), which are recorded as the source of the lots they open.
+const (
+	SourceReward  = "REWARD"
+	SourceOpening = "OPENING"
+)
+
+const (
+	DisposalRefund     = "REFUND"
+	DisposalDelisting  = "DELISTING"
+	DisposalCashInLieu = "CASH_IN_LIEU"
+)
+
+const (
+	TermShort = "SHORT_TERM"
+	TermLong  = "LONG_TERM"
+)
+
+// Conversion kinds, see Convert.
+const (
+	ConvertReplace = "REPLACE"
+	ConvertDemerge = "DEMERGE"
+	ConvertBonus   = "BONUS"
+)
+
+type Lot struct {
+	ID                int       `json:"id"`
+	UserID            int       `json:"user_id"`
+	StockID           int       `json:"stock_id"`
+	StockSymbol       string    `json:"stock_symbol"`
+	Source            string    `json:"source"`
+	RewardEventID     *int      `json:"reward_event_id,omitempty"`
+	CorporateActionID *int      `json:"corporate_action_id,omitempty"`
+	ParentLotID       *int      `json:"parent_lot_id,omitempty"`
+	AcquiredAt        time.Time `json:"acquired_at"`
+	Quantity          float64   `json:"quantity"`
+	Cost              float64   `json:"cost"`
+	RemainingQuantity float64   `json:"remaining_quantity"`
+	RemainingCost     float64   `json:"remaining_cost"`
+	// LongTermFrom is the first day a disposal of the lot counts as long
+	// term.
+	LongTermFrom string `json:"long_term_from"`
+}
+
+type LotFilter struct {
+	Stock         string `form:"stock"`
+	IncludeClosed bool   `form:"include_closed"`
+}
+
+// RealisedGain is one recorded disposal: the part of one lot given up by a
+// refund, delisting or cash in lieu payment.
+type RealisedGain struct {
+	ID                int       `json:"id"`
+	TaxLotID          int       `json:"tax_lot_id"`
+	StockSymbol       string    `json:"stock_symbol"`
+	DisposalType      string    `json:"disposal_type"`
+	RewardEventID     *int      `json:"reward_event_id,omitempty"`
+	CorporateActionID *int      `json:"corporate_action_id,omitempty"`
+	AcquiredAt        time.Time `json:"acquired_at"`
+	DisposedAt        time.Time `json:"disposed_at"`
+	Quantity          float64   `json:"quantity"`
+	Cost              float64   `json:"cost"`
+	Proceeds          float64   `json:"proceeds"`
+	Gain              float64   `json:"gain"`
+	Term              string    `json:"term"`
+}
+
+type GainTotals struct {
+	Quantity float64 `json:"quantity"`
+	Proceeds float64 `json:"proceeds"`
+	Cost     float64 `json:"cost"`
+	Gain     float64 `json:"gain"`
+}
+
+type FinancialYearGains struct {
+	FinancialYear string         `json:"financial_year"`
+	ShortTerm     GainTotals     `json:"short_term"`
+	LongTerm      GainTotals     `json:"long_term"`
+	NetGain       float64        `json:"net_gain"`
+	Disposals     []RealisedGain `json:"disposals"`
+}
+
+// CapitalGainsFilter narrows the report to one financial year, written as
+// 2025-26.
+type CapitalGainsFilter struct {
+	FinancialYear string `form:"financial_year"`
+}
+
+type CapitalGainsReport struct {
+	UserID      int                  `json:"user_id"`
+	Years       []FinancialYearGains `json:"years"`
+	GeneratedAt time.Time            `json:"generated_at"`
+}
@@ -0,0 +1,13 @@
+package tax_lot
+
+import (
+	"github.com/gin-gonic/gin"
+)
+
+func RegisterRoutes(router *gin.RouterGroup, handler *TaxLotHandler) {
+	users := router.Group("/users/:id")
+	{
+		users.GET("/tax-lots", handler.GetLots)
+		users.GET("/capital-gains", handler.GetCapitalGains)
+	}
+}
@@ -0,0 +1,212 @@
+package tax_lot
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"stocky-backend/domain"
+	"stocky-backend/features/stock"
+
+	"github.com/sirupsen/logrus"
+)
+
+var financialYearPattern = regexp.MustCompile(`^(\d{4})-(\d{2})$`)
+
+type TaxLotService struct {
+	db *sql.DB
+}
+
+func NewTaxLotService(db *sql.DB) *TaxLotService {
+	return &TaxLotService{db: db}
+}
+
+// GetLots lists a user's lots in the order disposals consume them. Closed
+// lots (fully disposed of or converted by a corporate action) are left out
+// unless asked for.
+func (s *TaxLotService) GetLots(userID int, filter LotFilter) ([]Lot, error) {
+	if err := checkUserExists(s.db, userID); err != nil {
+		return nil, err
+	}
+
+	var stockID int
+	if filter.Stock != "" {
+		ref, err := stock.Resolve(s.db, filter.Stock)
+		if err == sql.ErrNoRows {
+			return nil, domain.NotFound(domain.CodeStockNotFound, "stock '%s' not found", filter.Stock)
+		}
+		if err != nil {
+			logrus.Errorf("Failed to resolve stock for tax lots: %v", err)
+			return nil, err
+		}
+		stockID = ref.ID
+	}
+
+	rows, err := s.db.Query(`
+		SELECT l.id, l.user_id, l.stock_id, s.symbol, l.source, l.reward_event_id, l.corporate_action_id, l.parent_lot_id,
+		       l.acquired_at, l.quantity, l.cost, l.remaining_quantity, l.remaining_cost
+		FROM tax_lots l
+		JOIN stocks s ON s.id = l.stock_id
+		WHERE l.user_id = $1
+		AND ($2 = 0 OR l.stock_id = $2)
+		AND ($3 OR l.remaining_quantity > 0)
+		ORDER BY s.symbol, l.acquired_at, l.id
+	`, userID, stockID, filter.IncludeClosed)
+	if err != nil {
+		logrus.Errorf("Failed to query tax lots: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	lots := []Lot{}
+	for rows.Next() {
+		var lot Lot
+		var rewardEventID, corporateActionID, parentLotID sql.NullInt64
+		err := rows.Scan(&lot.ID, &lot.UserID, &lot.StockID, &lot.StockSymbol, &lot.Source, &rewardEventID, &corporateActionID, &parentLotID,
+			&lot.AcquiredAt, &lot.Quantity, &lot.Cost, &lot.RemainingQuantity, &lot.RemainingCost)
+		if err != nil {
+			logrus.Errorf("Failed to scan tax lot: %v", err)
+			return nil, err
+		}
+		lot.RewardEventID = intPtr(rewardEventID)
+		lot.CorporateActionID = intPtr(corporateActionID)
+		lot.ParentLotID = intPtr(parentLotID)
+		lot.LongTermFrom = longTermFrom(lot.AcquiredAt).Format("2006-01-02")
+		lots = append(lots, lot)
+	}
+	return lots, rows.Err()
+}
+
+// GetCapitalGains reports a user's realised gains per financial year (April
+// to March) of disposal, split into short and long term. Disposals undone by
+// a corporate action reversal are left out.
+func (s *TaxLotService) GetCapitalGains(userID int, filter CapitalGainsFilter) (*CapitalGainsReport, error) {
+	if err := checkUserExists(s.db, userID); err != nil {
+		return nil, err
+	}
+
+	var from, to time.Time
+	if filter.FinancialYear != "" {
+		var err error
+		if from, err = parseFinancialYear(filter.FinancialYear); err != nil {
+			return nil, err
+		}
+		to = from.AddDate(1, 0, 0)
+	}
+
+	rows, err := s.db.Query(`
+		SELECT d.id, d.tax_lot_id, s.symbol, d.disposal_type, d.reward_event_id, d.corporate_action_id,
+		       d.acquired_at, d.disposed_at, d.quantity, d.cost, d.proceeds, d.term
+		FROM tax_lot_disposals d
+		JOIN stocks s ON s.id = d.stock_id
+		WHERE d.user_id = $1 AND d.reversed_at IS NULL
+		AND ($2::date IS NULL OR (d.disposed_at >= $2::date AND d.disposed_at < $3::date))
+		ORDER BY d.disposed_at, d.id
+	`, userID, nullDate(from), nullDate(to))
+	if err != nil {
+		logrus.Errorf("Failed to query tax lot disposals: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	report := &CapitalGainsReport{UserID: userID, Years: []FinancialYearGains{}, GeneratedAt: time.Now()}
+	years := make(map[string]*FinancialYearGains)
+	for rows.Next() {
+		var disposal RealisedGain
+		var rewardEventID, corporateActionID sql.NullInt64
+		err := rows.Scan(&disposal.ID, &disposal.TaxLotID, &disposal.StockSymbol, &disposal.DisposalType, &rewardEventID, &corporateActionID,
+			&disposal.AcquiredAt, &disposal.DisposedAt, &disposal.Quantity, &disposal.Cost, &disposal.Proceeds, &disposal.Term)
+		if err != nil {
+			logrus.Errorf("Failed to scan tax lot disposal: %v", err)
+			return nil, err
+		}
+		disposal.RewardEventID = intPtr(rewardEventID)
+		disposal.CorporateActionID = intPtr(corporateActionID)
+		disposal.Gain = roundAmount(disposal.Proceeds - disposal.Cost)
+
+		label := financialYearLabel(disposal.DisposedAt)
+		year := years[label]
+		if year == nil {
+			year = &FinancialYearGains{FinancialYear: label, Disposals: []RealisedGain{}}
+			years[label] = year
+		}
+
+		totals := &year.ShortTerm
+		if disposal.Term == TermLong {
+			totals = &year.LongTerm
+		}
+		totals.Quantity = roundQuantity(totals.Quantity + disposal.Quantity)
+		totals.Proceeds = roundAmount(totals.Proceeds + disposal.Proceeds)
+		totals.Cost = roundAmount(totals.Cost + disposal.Cost)
+		totals.Gain = roundAmount(totals.Gain + disposal.Gain)
+		year.NetGain = roundAmount(year.NetGain + disposal.Gain)
+		year.Disposals = append(year.Disposals, disposal)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, year := range years {
+		report.Years = append(report.Years, *year)
+	}
+	sort.Slice(report.Years, func(i, j int) bool {
+		return report.Years[i].FinancialYear < report.Years[j].FinancialYear
+	})
+	return report, nil
+}
+
+func checkUserExists(q *sql.DB, userID int) error {
+	var exists bool
+	err := q.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`, userID).Scan(&exists)
+	if err != nil {
+		logrus.Errorf("Failed to check user: %v", err)
+		return err
+	}
+	if !exists {
+		return domain.NotFound(domain.CodeUserNotFound, "user not found")
+	}
+	return nil
+}
+
+// parseFinancialYear reads a financial year written as 2025-26 and returns
+// its first day.
+func parseFinancialYear(label string) (time.Time, error) {
+	match := financialYearPattern.FindStringSubmatch(label)
+	if match == nil {
+		return time.Time{}, domain.Validation(domain.CodeInvalidReportRange, "financial_year %q must look like 2025-26", label)
+	}
+	year, _ := strconv.Atoi(match[1])
+	next, _ := strconv.Atoi(match[2])
+	if (year+1)%100 != next {
+		return time.Time{}, domain.Validation(domain.CodeInvalidReportRange, "financial_year %q must cover two consecutive years", label)
+	}
+	return time.Date(year, time.April, 1, 0, 0, 0, 0, time.UTC), nil
+}
+
+// financialYearLabel names the Indian financial year t falls in, as 2025-26.
+func financialYearLabel(t time.Time) string {
+	year := t.Year()
+	if t.Month() < time.April {
+		year--
+	}
+	return fmt.Sprintf("%d-%02d", year, (year+1)%100)
+}
+
+func nullDate(t time.Time) *string {
+	if t.IsZero() {
+		return nil
+	}
+	date := t.Format("2006-01-02")
+	return &date
+}
+
+func intPtr(value sql.NullInt64) *int {
+	if !value.Valid {
+		return nil
+	}
+	i := int(value.Int64)
+	return &i
+}
@@ -0,0 +1,68 @@
+package tax_lot
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"stocky-backend/domain"
+)
+
+func TestFinancialYearLabel(t *testing.T) {
+	tests := []struct {
+		at   time.Time
+		want string
+	}{
+		{at: time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC), want: "2025-26"},
+		{at: time.Date(2026, time.March, 31, 23, 59, 59, 0, time.UTC), want: "2025-26"},
+		{at: time.Date(2026, time.January, 15, 12, 0, 0, 0, time.UTC), want: "2025-26"},
+		{at: time.Date(2025, time.December, 31, 0, 0, 0, 0, time.UTC), want: "2025-26"},
+		{at: time.Date(2025, time.March, 31, 0, 0, 0, 0, time.UTC), want: "2024-25"},
+		{at: time.Date(1999, time.June, 1, 0, 0, 0, 0, time.UTC), want: "1999-00"},
+		{at: time.Date(2009, time.May, 1, 0, 0, 0, 0, time.UTC), want: "2009-10"},
+	}
+
+	for _, tt := range tests {
+		if got := financialYearLabel(tt.at); got != tt.want {
+			t.Errorf("financialYearLabel(%s) = %s, want %s", tt.at.Format(time.RFC3339), got, tt.want)
+		}
+	}
+}
+
+func TestParseFinancialYear(t *testing.T) {
+	tests := []struct {
+		label   string
+		want    time.Time
+		wantErr bool
+	}{
+		{label: "2025-26", want: time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{label: "1999-00", want: time.Date(1999, time.April, 1, 0, 0, 0, 0, time.UTC)},
+		{label: "2025-27", wantErr: true},
+		{label: "2025-25", wantErr: true},
+		{label: "2025-2026", wantErr: true},
+		{label: "2025", wantErr: true},
+		{label: "FY2025-26", wantErr: true},
+		{label: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			got, err := parseFinancialYear(tt.label)
+			if tt.wantErr {
+				if !errors.Is(err, domain.ErrValidation) {
+					t.Fatalf("parseFinancialYear(%q) error = %v, want a validation error", tt.label, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseFinancialYear(%q) error = %v", tt.label, err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("parseFinancialYear(%q) = %s, want %s", tt.label, got, tt.want)
+			}
+			if label := financialYearLabel(got); label != tt.label {
+				t.Errorf("financialYearLabel(parseFinancialYear(%q)) = %s", tt.label, label)
+			}
+		})
+	}
+}
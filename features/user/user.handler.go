@@ -29,10 +29,16 @@ func (h *UserHandler) GetAllUsers(c *gin.Context) {
 		pageSize = 10
 	}
 
-	users, err := h.service.GetAllUsers(page, pageSize)
+	var filter UserFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.Error(middleware.BadRequestError("Invalid query parameters", err.Error()))
+		return
+	}
+
+	users, err := h.service.GetAllUsers(filter, page, pageSize)
 	if err != nil {
 		logrus.Errorf("Error getting users: %v", err)
-		c.Error(middleware.InternalServerError("Failed to retrieve users", err.Error()))
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve users"))
 		return
 	}
 
@@ -49,17 +55,111 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 	user, err := h.service.GetUserByID(userID)
 	if err != nil {
 		logrus.Errorf("Error getting user: %v", err)
-		c.Error(middleware.NotFoundError("User not found", err.Error()))
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve user"))
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"data": user})
 }
 
+func (h *UserHandler) CreateUser(c *gin.Context) {
+	var req CreateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	user, err := h.service.CreateUser(req)
+	if err != nil {
+		logrus.Errorf("Error creating user: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to create user"))
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "User created successfully",
+		"data":    user,
+	})
+}
+
+func (h *UserHandler) UpdateUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+		return
+	}
+
+	var req UpdateUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+		return
+	}
+
+	user, err := h.service.UpdateUser(userID, req)
+	if err != nil {
+		logrus.Errorf("Error updating user: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to update user"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User updated successfully",
+		"data":    user,
+	})
+}
+
+func (h *UserHandler) DeactivateUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+		return
+	}
+
+	var req DeactivateUserRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.Error(middleware.BadRequestError("Invalid request body", err.Error()))
+			return
+		}
+	}
+
+	user, err := h.service.DeactivateUser(userID, req)
+	if err != nil {
+		logrus.Errorf("Error deactivating user: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to deactivate user"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User deactivated successfully",
+		"data":    user,
+	})
+}
+
+func (h *UserHandler) ReactivateUser(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+		return
+	}
+
+	user, err := h.service.ReactivateUser(userID)
+	if err != nil {
+		logrus.Errorf("Error reactivating user: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to reactivate user"))
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "User reactivated successfully",
+		"data":    user,
+	})
+}
+
 func (h *UserHandler) GetTodayStockRewards(c *gin.Context) {
 	userID, err := strconv.Atoi(c.Param("userId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
 		return
 	}
 
@@ -76,7 +176,7 @@ func (h *UserHandler) GetTodayStockRewards(c *gin.Context) {
 	rewards, err := h.service.GetTodayStockRewards(userID, page, pageSize)
 	if err != nil {
 		logrus.Errorf("Error getting today's stock rewards: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve today's stock rewards"})
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve today's stock rewards"))
 		return
 	}
 
@@ -86,7 +186,7 @@ func (h *UserHandler) GetTodayStockRewards(c *gin.Context) {
 func (h *UserHandler) GetHistoricalINRValues(c *gin.Context) {
 	userID, err := strconv.Atoi(c.Param("userId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
 		return
 	}
 
@@ -103,7 +203,7 @@ func (h *UserHandler) GetHistoricalINRValues(c *gin.Context) {
 	historicalValues, err := h.service.GetHistoricalINRValues(userID, page, pageSize)
 	if err != nil {
 		logrus.Errorf("Error getting historical INR values: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve historical INR values"})
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve historical INR values"))
 		return
 	}
 
@@ -113,14 +213,14 @@ func (h *UserHandler) GetHistoricalINRValues(c *gin.Context) {
 func (h *UserHandler) GetUserStats(c *gin.Context) {
 	userID, err := strconv.Atoi(c.Param("userId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
 		return
 	}
 
 	stats, err := h.service.GetUserStats(userID)
 	if err != nil {
 		logrus.Errorf("Error getting user stats: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user stats"})
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve user stats"))
 		return
 	}
 
@@ -130,7 +230,7 @@ func (h *UserHandler) GetUserStats(c *gin.Context) {
 func (h *UserHandler) GetUserPortfolio(c *gin.Context) {
 	userID, err := strconv.Atoi(c.Param("userId"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
 		return
 	}
 
@@ -147,9 +247,36 @@ func (h *UserHandler) GetUserPortfolio(c *gin.Context) {
 	portfolio, err := h.service.GetUserPortfolio(userID, page, pageSize)
 	if err != nil {
 		logrus.Errorf("Error getting user portfolio: %v", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user portfolio"})
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve user portfolio"))
 		return
 	}
 
 	c.JSON(http.StatusOK, portfolio)
 }
+
+func (h *UserHandler) GetPortfolioHistory(c *gin.Context) {
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		c.Error(middleware.BadRequestError("Invalid user ID", err.Error()))
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	history, err := h.service.GetPortfolioHistory(userID, page, pageSize)
+	if err != nil {
+		logrus.Errorf("Error getting portfolio history: %v", err)
+		c.Error(middleware.WrapServiceError(err, "Failed to retrieve portfolio history"))
+		return
+	}
+
+	c.JSON(http.StatusOK, history)
+}
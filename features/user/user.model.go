@@ -5,13 +5,17 @@ import (
 )
 
 type User struct {
-	ID        int       `json:"id"`
-	Email     string    `json:"email"`
-	Name      string    `json:"name"`
-	Phone     string    `json:"phone"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID                 int        `json:"id"`
+	Email              string     `json:"email"`
+	Name               string     `json:"name"`
+	Phone              string     `json:"phone"`
+	IsActive           bool       `json:"is_active"`
+	KYCStatus          string     `json:"kyc_status"`
+	DeactivatedAt      *time.Time `json:"deactivated_at,omitempty"`
+	DeactivationReason string     `json:"deactivation_reason,omitempty"`
+	ErasedAt           *time.Time `json:"erased_at,omitempty"`
+	CreatedAt          time.Time  `json:"created_at"`
+	UpdatedAt          time.Time  `json:"updated_at"`
 }
 
 type CreateUserRequest struct {
@@ -25,6 +29,22 @@ type UpdateUserRequest struct {
 	Phone string `json:"phone"`
 }
 
+// UserFilter narrows GET /api/users. Dates are inclusive calendar days.
+type UserFilter struct {
+	Query        string     `form:"q"`
+	IsActive     *bool      `form:"is_active"`
+	CreatedFrom  *time.Time `form:"created_from" time_format:"2006-01-02"`
+	CreatedTo    *time.Time `form:"created_to" time_format:"2006-01-02"`
+	KYCStatus    string     `form:"kyc_status"`
+	HoldsSymbol  string     `form:"holds_symbol"`
+	RewardedFrom *time.Time `form:"rewarded_from" time_format:"2006-01-02"`
+	RewardedTo   *time.Time `form:"rewarded_to" time_format:"2006-01-02"`
+}
+
+type DeactivateUserRequest struct {
+	Reason string `json:"reason"`
+}
+
 type TodayStockReward struct {
 	ID          int       `json:"id"`
 	StockSymbol string    `json:"stock_symbol"`
@@ -71,6 +91,7 @@ type UserStats struct {
 
 type PortfolioHolding struct {
 	StockSymbol    string  `json:"stock_symbol"`
+	ISIN           string  `json:"isin,omitempty"`
 	StockName      string  `json:"stock_name"`
 	TotalQuantity  float64 `json:"total_quantity"`
 	AveragePrice   float64 `json:"average_price"`
@@ -96,3 +117,24 @@ type PaginatedPortfolioResponse struct {
 	TotalPages       int                `json:"total_pages"`
 	TotalPortfolioValue float64         `json:"total_portfolio_value"`
 }
+
+type PortfolioHistoryEvent struct {
+	EventAt        time.Time `json:"event_at"`
+	EventType      string    `json:"event_type"`
+	Source         string    `json:"source"`
+	ReferenceID    int       `json:"reference_id"`
+	StockSymbol    string    `json:"stock_symbol"`
+	QuantityChange float64   `json:"quantity_change"`
+	Price          float64   `json:"price"`
+	CashAmount     float64   `json:"cash_amount"`
+	RealizedPnL    float64   `json:"realized_pnl"`
+	Description    string    `json:"description"`
+}
+
+type PaginatedPortfolioHistoryResponse struct {
+	Data       []PortfolioHistoryEvent `json:"data"`
+	Page       int                     `json:"page"`
+	PageSize   int                     `json:"page_size"`
+	TotalCount int                     `json:"total_count"`
+	TotalPages int                     `json:"total_pages"`
+}
@@ -8,11 +8,16 @@ func RegisterRoutes(router *gin.RouterGroup, handler *UserHandler) {
 	users := router.Group("/users")
 	{
 		users.GET("", handler.GetAllUsers)
+		users.POST("", handler.CreateUser)
 		users.GET("/:id", handler.GetUserByID)
+		users.PUT("/:id", handler.UpdateUser)
+		users.POST("/:id/deactivate", handler.DeactivateUser)
+		users.POST("/:id/reactivate", handler.ReactivateUser)
 	}
 
 	router.GET("/today-stocks/:userId", handler.GetTodayStockRewards)
 	router.GET("/historical-inr/:userId", handler.GetHistoricalINRValues)
 	router.GET("/stats/:userId", handler.GetUserStats)
 	router.GET("/portfolio/:userId", handler.GetUserPortfolio)
+	router.GET("/portfolio/:userId/history", handler.GetPortfolioHistory)
 }
@@ -3,10 +3,28 @@ package user
 import (
 	"database/sql"
 	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
 
+	"stocky-backend/domain"
+	"stocky-backend/features/stock"
+
+	"github.com/lib/pq"
 	"github.com/sirupsen/logrus"
 )
 
+const userColumns = `id, email, name, COALESCE(phone, ''), is_active, kyc_status, deactivated_at,
+	COALESCE(deactivation_reason, ''), erased_at, created_at, updated_at`
+
+// phonePattern accepts an optional country code followed by 6-14 digits,
+// e.g. "+91-9876543210" or "9876543210".
+var phonePattern = regexp.MustCompile(`^(\+[0-9]{1,3}[- ]?)?[0-9]{6,14}$`)
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
 type UserService struct {
 	db *sql.DB
 }
@@ -15,21 +33,230 @@ func NewUserService(db *sql.DB) *UserService {
 	return &UserService{db: db}
 }
 
-func (s *UserService) GetAllUsers(page, pageSize int) (*PaginatedUsersResponse, error) {
+func scanUser(row rowScanner, user *User) error {
+	return row.Scan(
+		&user.ID, &user.Email, &user.Name, &user.Phone, &user.IsActive, &user.KYCStatus,
+		&user.DeactivatedAt, &user.DeactivationReason, &user.ErasedAt, &user.CreatedAt, &user.UpdatedAt,
+	)
+}
+
+func validatePhone(phone string) error {
+	if !phonePattern.MatchString(phone) {
+		return domain.Validation(domain.CodeInvalidPhone,
+			"invalid phone number %q: expected an optional country code followed by 6-14 digits", phone)
+	}
+	return nil
+}
+
+func (s *UserService) CreateUser(req CreateUserRequest) (*User, error) {
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	phone := strings.TrimSpace(req.Phone)
+	if err := validatePhone(phone); err != nil {
+		return nil, err
+	}
+
+	var user User
+	err := scanUser(s.db.QueryRow(`
+		INSERT INTO users (email, name, phone, is_active)
+		VALUES ($1, $2, $3, true)
+		RETURNING `+userColumns,
+		email, strings.TrimSpace(req.Name), phone), &user)
+	if isUniqueViolation(err) {
+		return nil, domain.Conflict(domain.CodeEmailAlreadyExists, "a user with email %s already exists", email)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to create user: %v", err)
+		return nil, err
+	}
+
+	logrus.Infof("User created: %d (%s)", user.ID, user.Email)
+	return &user, nil
+}
+
+func (s *UserService) UpdateUser(id int, req UpdateUserRequest) (*User, error) {
+	name := strings.TrimSpace(req.Name)
+	phone := strings.TrimSpace(req.Phone)
+	if name == "" && phone == "" {
+		return nil, domain.Validation(domain.CodeInvalidUserUpdate, "at least one of name or phone must be provided")
+	}
+	if phone != "" {
+		if err := validatePhone(phone); err != nil {
+			return nil, err
+		}
+	}
+
+	var user User
+	err := scanUser(s.db.QueryRow(`
+		UPDATE users
+		SET name = COALESCE(NULLIF($1, ''), name),
+		    phone = COALESCE(NULLIF($2, ''), phone),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $3 AND erased_at IS NULL
+		RETURNING `+userColumns,
+		name, phone, id), &user)
+	if err == sql.ErrNoRows {
+		if _, lookupErr := s.GetUserByID(id); lookupErr != nil {
+			return nil, lookupErr
+		}
+		return nil, domain.BusinessRule(domain.CodeUserErased, "user %d has been erased and cannot be updated", id)
+	}
+	if err != nil {
+		logrus.Errorf("Failed to update user: %v", err)
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// DeactivateUser blocks the user from receiving new rewards. Rewards, ledger
+// entries and holdings are left untouched.
+func (s *UserService) DeactivateUser(id int, req DeactivateUserRequest) (*User, error) {
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, domain.Conflict(domain.CodeUserInactive, "user %d is already deactivated", id)
+	}
+
+	err = scanUser(s.db.QueryRow(`
+		UPDATE users
+		SET is_active = false,
+		    deactivated_at = CURRENT_TIMESTAMP,
+		    deactivation_reason = NULLIF($1, ''),
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $2
+		RETURNING `+userColumns,
+		strings.TrimSpace(req.Reason), id), user)
+	if err != nil {
+		logrus.Errorf("Failed to deactivate user: %v", err)
+		return nil, err
+	}
+
+	logrus.Infof("User %d deactivated", id)
+	return user, nil
+}
+
+func (s *UserService) ReactivateUser(id int) (*User, error) {
+	user, err := s.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+	if user.ErasedAt != nil {
+		return nil, domain.BusinessRule(domain.CodeUserErased, "user %d has been erased and cannot be reactivated", id)
+	}
+	if user.IsActive {
+		return nil, domain.Conflict(domain.CodeUserAlreadyActive, "user %d is already active", id)
+	}
+
+	err = scanUser(s.db.QueryRow(`
+		UPDATE users
+		SET is_active = true,
+		    deactivated_at = NULL,
+		    deactivation_reason = NULL,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING `+userColumns,
+		id), user)
+	if err != nil {
+		logrus.Errorf("Failed to reactivate user: %v", err)
+		return nil, err
+	}
+
+	logrus.Infof("User %d reactivated", id)
+	return user, nil
+}
+
+// buildUserFilter returns a WHERE clause over the users table aliased as u,
+// and its positional arguments.
+func buildUserFilter(filter UserFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	next := func(value interface{}) string {
+		args = append(args, value)
+		return "$" + strconv.Itoa(len(args))
+	}
+
+	if q := strings.TrimSpace(filter.Query); q != "" {
+		prefix := next(escapeLike(strings.ToLower(q)) + "%")
+		conditions = append(conditions, fmt.Sprintf(
+			"(LOWER(u.email) LIKE %[1]s OR LOWER(u.name) LIKE %[1]s OR u.phone LIKE %[1]s)", prefix))
+	}
+	if filter.IsActive != nil {
+		conditions = append(conditions, "u.is_active = "+next(*filter.IsActive))
+	}
+	if filter.KYCStatus != "" {
+		conditions = append(conditions, "u.kyc_status = "+next(filter.KYCStatus))
+	}
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, "u.created_at >= "+next(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, "u.created_at < "+next(filter.CreatedTo.AddDate(0, 0, 1)))
+	}
+	if filter.HoldsSymbol != "" {
+		conditions = append(conditions, `EXISTS (
+			SELECT 1 FROM user_stock_holdings ush
+			JOIN stocks s ON ush.stock_id = s.id
+			WHERE ush.user_id = u.id AND ush.total_quantity > 0 AND s.symbol = `+next(filter.HoldsSymbol)+`)`)
+	}
+	if filter.RewardedFrom != nil || filter.RewardedTo != nil {
+		rewardConditions := []string{"re.user_id = u.id", "re.status = 'COMPLETED'", "re.event_type = 'STOCK_REWARD'"}
+		if filter.RewardedFrom != nil {
+			rewardConditions = append(rewardConditions, "re.created_at >= "+next(*filter.RewardedFrom))
+		}
+		if filter.RewardedTo != nil {
+			rewardConditions = append(rewardConditions, "re.created_at < "+next(filter.RewardedTo.AddDate(0, 0, 1)))
+		}
+		conditions = append(conditions, "EXISTS (SELECT 1 FROM reward_events re WHERE "+
+			strings.Join(rewardConditions, " AND ")+")")
+	}
+
+	if len(conditions) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conditions, " AND "), args
+}
+
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
+
+func isUniqueViolation(err error) bool {
+	pqErr, ok := err.(*pq.Error)
+	return ok && pqErr.Code == "23505"
+}
+
+func (s *UserService) GetAllUsers(filter UserFilter, page, pageSize int) (*PaginatedUsersResponse, error) {
+	// holds_symbol may be a retired symbol or an ISIN.
+	if filter.HoldsSymbol != "" {
+		ref, err := stock.Resolve(s.db, filter.HoldsSymbol)
+		if err == nil {
+			filter.HoldsSymbol = ref.Symbol
+		} else if err != sql.ErrNoRows {
+			logrus.Errorf("Failed to resolve stock %s: %v", filter.HoldsSymbol, err)
+			return nil, err
+		}
+	}
+
+	where, args := buildUserFilter(filter)
+
 	var totalCount int
-	err := s.db.QueryRow(`SELECT COUNT(*) FROM users`).Scan(&totalCount)
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM users u`+where, args...).Scan(&totalCount)
 	if err != nil {
 		logrus.Errorf("Failed to count users: %v", err)
 		return nil, err
 	}
 
 	offset := (page - 1) * pageSize
+	args = append(args, pageSize, offset)
 
-	query := `SELECT id, email, name, phone, is_active, created_at, updated_at 
-			  FROM users ORDER BY created_at DESC
-			  LIMIT $1 OFFSET $2`
+	query := `SELECT ` + userColumns + `
+			  FROM users u` + where + `
+			  ORDER BY created_at DESC
+			  LIMIT $` + strconv.Itoa(len(args)-1) + ` OFFSET $` + strconv.Itoa(len(args))
 
-	rows, err := s.db.Query(query, pageSize, offset)
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		logrus.Errorf("Failed to query users: %v", err)
 		return nil, err
@@ -39,8 +266,7 @@ func (s *UserService) GetAllUsers(page, pageSize int) (*PaginatedUsersResponse,
 	var users []User
 	for rows.Next() {
 		var user User
-		err := rows.Scan(&user.ID, &user.Email, &user.Name, &user.Phone, 
-						 &user.IsActive, &user.CreatedAt, &user.UpdatedAt)
+		err := scanUser(rows, &user)
 		if err != nil {
 			logrus.Errorf("Failed to scan user: %v", err)
 			return nil, err
@@ -60,17 +286,14 @@ func (s *UserService) GetAllUsers(page, pageSize int) (*PaginatedUsersResponse,
 }
 
 func (s *UserService) GetUserByID(id int) (*User, error) {
-	query := `SELECT id, email, name, phone, is_active, created_at, updated_at 
+	query := `SELECT ` + userColumns + `
 			  FROM users WHERE id = $1`
 
 	var user User
-	err := s.db.QueryRow(query, id).Scan(
-		&user.ID, &user.Email, &user.Name, &user.Phone,
-		&user.IsActive, &user.CreatedAt, &user.UpdatedAt,
-	)
+	err := scanUser(s.db.QueryRow(query, id), &user)
 
 	if err == sql.ErrNoRows {
-		return nil, fmt.Errorf("user not found")
+		return nil, domain.NotFound(domain.CodeUserNotFound, "user %d not found", id)
 	}
 	if err != nil {
 		logrus.Errorf("Failed to query user: %v", err)
@@ -85,7 +308,7 @@ func (s *UserService) GetTodayStockRewards(userID, page, pageSize int) (*Paginat
 	err := s.db.QueryRow(`
 		SELECT COUNT(*) 
 		FROM reward_events 
-		WHERE user_id = $1 AND DATE(created_at) = CURRENT_DATE
+		WHERE user_id = $1 AND status = 'COMPLETED' AND DATE(created_at) = CURRENT_DATE
 	`, userID).Scan(&totalCount)
 	if err != nil {
 		logrus.Errorf("Failed to count today's stock rewards: %v", err)
@@ -107,6 +330,7 @@ func (s *UserService) GetTodayStockRewards(userID, page, pageSize int) (*Paginat
 		FROM reward_events re
 		JOIN stocks s ON re.stock_id = s.id
 		WHERE re.user_id = $1 
+		AND re.status = 'COMPLETED'
 		AND DATE(re.created_at) = CURRENT_DATE
 		ORDER BY re.created_at DESC
 		LIMIT $2 OFFSET $3
@@ -155,7 +379,7 @@ func (s *UserService) GetHistoricalINRValues(userID, page, pageSize int) (*Pagin
 	err := s.db.QueryRow(`
 		SELECT COUNT(DISTINCT DATE(created_at))
 		FROM reward_events
-		WHERE user_id = $1 AND DATE(created_at) < CURRENT_DATE
+		WHERE user_id = $1 AND status = 'COMPLETED' AND DATE(created_at) < CURRENT_DATE
 	`, userID).Scan(&totalCount)
 	if err != nil {
 		logrus.Errorf("Failed to count historical dates: %v", err)
@@ -171,6 +395,7 @@ func (s *UserService) GetHistoricalINRValues(userID, page, pageSize int) (*Pagin
 			COUNT(*) as reward_count
 		FROM reward_events re
 		WHERE re.user_id = $1 
+		AND re.status = 'COMPLETED'
 		AND DATE(re.created_at) < CURRENT_DATE
 		GROUP BY DATE(re.created_at)
 		ORDER BY reward_date DESC
@@ -219,6 +444,7 @@ func (s *UserService) GetUserStats(userID int) (*UserStats, error) {
 		FROM reward_events re
 		JOIN stocks s ON re.stock_id = s.id
 		WHERE re.user_id = $1 
+		AND re.status = 'COMPLETED'
 		AND DATE(re.created_at) = CURRENT_DATE
 		GROUP BY s.symbol, s.name
 		ORDER BY s.symbol
@@ -297,6 +523,7 @@ func (s *UserService) GetUserPortfolio(userID, page, pageSize int) (*PaginatedPo
 	query := `
 		SELECT 
 			s.symbol,
+			COALESCE(s.isin, ''),
 			s.name,
 			ush.total_quantity,
 			ush.average_price,
@@ -323,6 +550,7 @@ func (s *UserService) GetUserPortfolio(userID, page, pageSize int) (*PaginatedPo
 		var holding PortfolioHolding
 		err := rows.Scan(
 			&holding.StockSymbol,
+			&holding.ISIN,
 			&holding.StockName,
 			&holding.TotalQuantity,
 			&holding.AveragePrice,
@@ -349,3 +577,135 @@ func (s *UserService) GetUserPortfolio(userID, page, pageSize int) (*PaginatedPo
 		TotalPortfolioValue: totalPortfolioValue,
 	}, nil
 }
+
+// portfolioHistoryQuery lists every event that changed a user's units or
+// paid them cash: rewards and adjustments, unit-changing corporate actions
+// (mergers produce one row for the stock given up and one for the stock
+// received; spin-offs one row for the parent's re-apportioned cost and one
+// for the new stock), delistings with their settlement or write-off, and dividends.
+// A reversed corporate action keeps its original rows and adds mirrored
+// *_REVERSAL rows at the time it was reversed.
+const portfolioHistoryQuery = `
+	SELECT re.created_at AS event_at, re.event_type AS event_type, 'REWARD' AS source, re.id AS reference_id,
+	       s.symbol AS stock_symbol,
+	       CASE WHEN re.event_type = 'ADJUSTMENT' THEN -re.quantity ELSE re.quantity END AS quantity_change,
+	       re.stock_price AS price,
+	       CASE WHEN re.event_type = 'ADJUSTMENT' THEN re.total_value ELSE 0 END AS cash_amount,
+	       0 AS realized_pnl,
+	       COALESCE(re.description, '') AS description
+	FROM reward_events re
+	JOIN stocks s ON re.stock_id = s.id
+	WHERE re.user_id = $1 AND re.status = 'COMPLETED'
+	UNION ALL
+	SELECT e.created_at, ca.action_type, 'CORPORATE_ACTION', ca.id, s.symbol,
+	       CASE WHEN ca.action_type = 'SPINOFF' THEN 0
+	            WHEN e.to_stock_id IS NOT NULL AND e.to_stock_id <> e.stock_id THEN -e.quantity_before
+	            ELSE e.quantity_after - e.quantity_before END,
+	       CASE WHEN ca.action_type = 'DELISTING' THEN COALESCE(ca.exit_price, 0)
+	            WHEN ca.action_type = 'SPINOFF' THEN e.source_average_price_after
+	            ELSE e.average_price_after END,
+	       e.cash_in_lieu_amount + e.settlement_amount,
+	       e.realized_pnl,
+	       COALESCE(ca.description, '')
+	FROM corporate_action_entitlements e
+	JOIN corporate_actions ca ON e.corporate_action_id = ca.id
+	JOIN stocks s ON e.stock_id = s.id
+	WHERE e.user_id = $1
+	UNION ALL
+	SELECT e.created_at, ca.action_type, 'CORPORATE_ACTION', ca.id, ts.symbol,
+	       e.quantity_after, e.average_price_after, 0, 0, COALESCE(ca.description, '')
+	FROM corporate_action_entitlements e
+	JOIN corporate_actions ca ON e.corporate_action_id = ca.id
+	JOIN stocks ts ON e.to_stock_id = ts.id
+	WHERE e.user_id = $1 AND e.to_stock_id <> e.stock_id AND e.quantity_after > 0
+	UNION ALL
+	SELECT de.paid_at, ca.action_type, 'CORPORATE_ACTION', ca.id, s.symbol,
+	       0, de.dividend_per_share, de.net_amount, 0, COALESCE(ca.description, '')
+	FROM dividend_entitlements de
+	JOIN corporate_actions ca ON de.corporate_action_id = ca.id
+	JOIN stocks s ON de.stock_id = s.id
+	WHERE de.user_id = $1
+	UNION ALL
+	SELECT ca.reversed_at, ca.action_type || '_REVERSAL', 'CORPORATE_ACTION', ca.id, s.symbol,
+	       CASE WHEN ca.action_type = 'SPINOFF' THEN 0
+	            WHEN e.to_stock_id IS NOT NULL AND e.to_stock_id <> e.stock_id THEN e.quantity_before
+	            ELSE e.quantity_before - e.quantity_after END,
+	       e.average_price_before,
+	       -(e.cash_in_lieu_amount + e.settlement_amount),
+	       -e.realized_pnl,
+	       COALESCE(ca.reversal_reason, '')
+	FROM corporate_action_entitlements e
+	JOIN corporate_actions ca ON e.corporate_action_id = ca.id
+	JOIN stocks s ON e.stock_id = s.id
+	WHERE e.user_id = $1 AND ca.status = 'REVERSED'
+	UNION ALL
+	SELECT ca.reversed_at, ca.action_type || '_REVERSAL', 'CORPORATE_ACTION', ca.id, ts.symbol,
+	       -e.quantity_after, e.average_price_after, 0, 0, COALESCE(ca.reversal_reason, '')
+	FROM corporate_action_entitlements e
+	JOIN corporate_actions ca ON e.corporate_action_id = ca.id
+	JOIN stocks ts ON e.to_stock_id = ts.id
+	WHERE e.user_id = $1 AND ca.status = 'REVERSED' AND e.to_stock_id <> e.stock_id AND e.quantity_after > 0
+	UNION ALL
+	SELECT ca.reversed_at, ca.action_type || '_REVERSAL', 'CORPORATE_ACTION', ca.id, s.symbol,
+	       0, de.dividend_per_share, -de.net_amount, 0, COALESCE(ca.reversal_reason, '')
+	FROM dividend_entitlements de
+	JOIN corporate_actions ca ON de.corporate_action_id = ca.id
+	JOIN stocks s ON de.stock_id = s.id
+	WHERE de.user_id = $1 AND ca.status = 'REVERSED'
+`
+
+func (s *UserService) GetPortfolioHistory(userID, page, pageSize int) (*PaginatedPortfolioHistoryResponse, error) {
+	var totalCount int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM (`+portfolioHistoryQuery+`) h`, userID).Scan(&totalCount)
+	if err != nil {
+		logrus.Errorf("Failed to count portfolio history: %v", err)
+		return nil, err
+	}
+
+	offset := (page - 1) * pageSize
+
+	rows, err := s.db.Query(`
+		SELECT event_at, event_type, source, reference_id, stock_symbol, quantity_change,
+		       price, cash_amount, realized_pnl, description
+		FROM (`+portfolioHistoryQuery+`) h
+		ORDER BY event_at DESC, reference_id DESC
+		LIMIT $2 OFFSET $3
+	`, userID, pageSize, offset)
+	if err != nil {
+		logrus.Errorf("Failed to query portfolio history: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []PortfolioHistoryEvent{}
+	for rows.Next() {
+		var event PortfolioHistoryEvent
+		err := rows.Scan(
+			&event.EventAt,
+			&event.EventType,
+			&event.Source,
+			&event.ReferenceID,
+			&event.StockSymbol,
+			&event.QuantityChange,
+			&event.Price,
+			&event.CashAmount,
+			&event.RealizedPnL,
+			&event.Description,
+		)
+		if err != nil {
+			logrus.Errorf("Failed to scan portfolio history event: %v", err)
+			return nil, err
+		}
+		history = append(history, event)
+	}
+
+	totalPages := (totalCount + pageSize - 1) / pageSize
+
+	return &PaginatedPortfolioHistoryResponse{
+		Data:       history,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: totalCount,
+		TotalPages: totalPages,
+	}, nil
+}
@@ -1,13 +1,21 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"stocky-backend/config"
 	"stocky-backend/features/corporate_action"
+	"stocky-backend/features/fee"
+	"stocky-backend/features/invoice"
+	"stocky-backend/features/kyc"
+	"stocky-backend/features/privacy"
+	"stocky-backend/features/report"
 	"stocky-backend/features/reward"
+	"stocky-backend/features/stock"
+	"stocky-backend/features/tax_lot"
 	"stocky-backend/features/user"
 	"stocky-backend/middleware"
 
@@ -49,19 +57,53 @@ func main() {
 		})
 	})
 
+	schedulerCtx, stopScheduler := context.WithCancel(context.Background())
+	defer stopScheduler()
+
 	api := router.Group("/api")
 	{
-		rewardService := reward.NewRewardService(db)
+		rewardService := reward.NewRewardService(db, config.LoadRewardConfig())
 		rewardHandler := reward.NewRewardHandler(rewardService)
 		reward.RegisterRoutes(api, rewardHandler)
 
-		corporateActionService := corporate_action.NewCorporateActionService(db)
-		corporateActionHandler := corporate_action.NewCorporateActionHandler(corporateActionService)
+		corporateActionConfig := config.LoadCorporateActionConfig()
+		corporateActionService := corporate_action.NewCorporateActionService(db, corporateActionConfig)
+		corporateActionScheduler := corporate_action.NewScheduler(db, corporateActionService, corporateActionConfig)
+		corporateActionScheduler.Start(schedulerCtx)
+		corporateActionHandler := corporate_action.NewCorporateActionHandler(corporateActionService, corporateActionScheduler)
 		corporate_action.RegisterRoutes(api, corporateActionHandler)
 
 		userService := user.NewUserService(db)
 		userHandler := user.NewUserHandler(userService)
 		user.RegisterRoutes(api, userHandler)
+
+		kycService := kyc.NewKYCService(db, rewardService)
+		kycHandler := kyc.NewKYCHandler(kycService)
+		kyc.RegisterRoutes(api, kycHandler)
+
+		privacyService := privacy.NewPrivacyService(db)
+		privacyHandler := privacy.NewPrivacyHandler(privacyService)
+		privacy.RegisterRoutes(api, privacyHandler)
+
+		stockService := stock.NewStockService(db)
+		stockHandler := stock.NewStockHandler(stockService)
+		stock.RegisterRoutes(api, stockHandler)
+
+		feeService := fee.NewFeeService(db)
+		feeHandler := fee.NewFeeHandler(feeService)
+		fee.RegisterRoutes(api, feeHandler)
+
+		reportService := report.NewReportService(db)
+		reportHandler := report.NewReportHandler(reportService)
+		report.RegisterRoutes(api, reportHandler)
+
+		invoiceService := invoice.NewInvoiceService(db, config.LoadInvoiceConfig())
+		invoiceHandler := invoice.NewInvoiceHandler(invoiceService)
+		invoice.RegisterRoutes(api, invoiceHandler)
+
+		taxLotService := tax_lot.NewTaxLotService(db)
+		taxLotHandler := tax_lot.NewTaxLotHandler(taxLotService)
+		tax_lot.RegisterRoutes(api, taxLotHandler)
 	}
 
 	port := os.Getenv("PORT")
@@ -81,4 +123,5 @@ func main() {
 	<-quit
 
 	logrus.Info("Shutting down server...")
+	stopScheduler()
 }
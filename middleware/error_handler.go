@@ -1,35 +1,78 @@
 package middleware
 
 import (
+	"errors"
 	"net/http"
 
+	"stocky-backend/domain"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
 
 type AppError struct {
-	Code    int    `json:"code"`
-	Message string `json:"message"`
-	Detail  string `json:"detail,omitempty"`
+	Code      int                    `json:"code"`
+	ErrorCode string                 `json:"error_code"`
+	Message   string                 `json:"message"`
+	Detail    string                 `json:"detail,omitempty"`
+	Meta      map[string]interface{} `json:"meta,omitempty"`
 }
 
 func (e *AppError) Error() string {
 	return e.Message
 }
 
+func (e *AppError) WithMeta(key string, value interface{}) *AppError {
+	if e.Meta == nil {
+		e.Meta = make(map[string]interface{})
+	}
+	e.Meta[key] = value
+	return e
+}
+
 func NewAppError(code int, message string, detail string) *AppError {
 	return &AppError{
-		Code:    code,
-		Message: message,
-		Detail:  detail,
+		Code:      code,
+		ErrorCode: defaultErrorCodes[code],
+		Message:   message,
+		Detail:    detail,
 	}
 }
 
+var defaultErrorCodes = map[int]string{
+	http.StatusBadRequest:          "BAD_REQUEST",
+	http.StatusUnauthorized:        "UNAUTHORIZED",
+	http.StatusForbidden:           "FORBIDDEN",
+	http.StatusNotFound:            "NOT_FOUND",
+	http.StatusConflict:            "CONFLICT",
+	http.StatusUnprocessableEntity: "UNPROCESSABLE_ENTITY",
+	http.StatusInternalServerError: "INTERNAL_ERROR",
+}
+
+var domainErrorStatuses = map[domain.ErrorKind]int{
+	domain.KindNotFound:     http.StatusNotFound,
+	domain.KindConflict:     http.StatusConflict,
+	domain.KindValidation:   http.StatusBadRequest,
+	domain.KindBusinessRule: http.StatusUnprocessableEntity,
+}
+
 type ErrorResponse struct {
-	Success bool   `json:"success"`
-	Error   string `json:"error"`
-	Detail  string `json:"detail,omitempty"`
-	Code    int    `json:"code"`
+	Success   bool                   `json:"success"`
+	Error     string                 `json:"error"`
+	ErrorCode string                 `json:"error_code"`
+	Detail    string                 `json:"detail,omitempty"`
+	Code      int                    `json:"code"`
+	Meta      map[string]interface{} `json:"meta,omitempty"`
+}
+
+// WrapServiceError lets domain errors through untouched so GlobalErrorHandler
+// maps them to their own status, and turns anything else into a 500.
+func WrapServiceError(err error, message string) error {
+	var domainErr *domain.Error
+	if errors.As(err, &domainErr) {
+		return err
+	}
+	return InternalServerError(message, err.Error())
 }
 
 func GlobalErrorHandler() gin.HandlerFunc {
@@ -45,21 +88,41 @@ func GlobalErrorHandler() gin.HandlerFunc {
 				"error":  err.Error(),
 			}).Error("Request error")
 
-			if appErr, ok := err.Err.(*AppError); ok {
+			var domainErr *domain.Error
+			if errors.As(err.Err, &domainErr) {
+				status, ok := domainErrorStatuses[domainErr.Kind]
+				if !ok {
+					status = http.StatusInternalServerError
+				}
+				c.JSON(status, ErrorResponse{
+					Success:   false,
+					Error:     domainErr.Message,
+					ErrorCode: domainErr.Code,
+					Code:      status,
+					Meta:      domainErr.Meta,
+				})
+				return
+			}
+
+			var appErr *AppError
+			if errors.As(err.Err, &appErr) {
 				c.JSON(appErr.Code, ErrorResponse{
-					Success: false,
-					Error:   appErr.Message,
-					Detail:  appErr.Detail,
-					Code:    appErr.Code,
+					Success:   false,
+					Error:     appErr.Message,
+					ErrorCode: appErr.ErrorCode,
+					Detail:    appErr.Detail,
+					Code:      appErr.Code,
+					Meta:      appErr.Meta,
 				})
 				return
 			}
 
 			c.JSON(http.StatusInternalServerError, ErrorResponse{
-				Success: false,
-				Error:   "Internal server error",
-				Detail:  err.Error(),
-				Code:    http.StatusInternalServerError,
+				Success:   false,
+				Error:     "Internal server error",
+				ErrorCode: defaultErrorCodes[http.StatusInternalServerError],
+				Detail:    err.Error(),
+				Code:      http.StatusInternalServerError,
 			})
 		}
 	}
@@ -76,10 +139,11 @@ func RecoveryHandler() gin.HandlerFunc {
 				}).Error("Panic recovered")
 
 				c.JSON(http.StatusInternalServerError, ErrorResponse{
-					Success: false,
-					Error:   "Internal server error",
-					Detail:  "An unexpected error occurred",
-					Code:    http.StatusInternalServerError,
+					Success:   false,
+					Error:     "Internal server error",
+					ErrorCode: defaultErrorCodes[http.StatusInternalServerError],
+					Detail:    "An unexpected error occurred",
+					Code:      http.StatusInternalServerError,
 				})
 
 				c.Abort()
@@ -0,0 +1,4 @@
+ALTER TABLE reward_events ADD COLUMN IF NOT EXISTS requested_by VARCHAR(255);
+ALTER TABLE reward_events ADD COLUMN IF NOT EXISTS reviewed_by VARCHAR(255);
+ALTER TABLE reward_events ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP;
+ALTER TABLE reward_events ADD COLUMN IF NOT EXISTS rejection_reason TEXT;
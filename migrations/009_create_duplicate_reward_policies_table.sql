@@ -0,0 +1,19 @@
+ALTER TABLE reward_events ADD COLUMN IF NOT EXISTS campaign VARCHAR(100);
+
+CREATE INDEX IF NOT EXISTS idx_reward_events_campaign ON reward_events(campaign);
+
+CREATE TABLE IF NOT EXISTS duplicate_reward_policies (
+    id SERIAL PRIMARY KEY,
+    campaign VARCHAR(100) UNIQUE NOT NULL,
+    window_seconds INTEGER NOT NULL DEFAULT 300 CHECK (window_seconds >= 0),
+    match_fields TEXT NOT NULL DEFAULT 'user_id,stock_id,quantity',
+    quantity_tolerance NUMERIC(7, 6) NOT NULL DEFAULT 0 CHECK (quantity_tolerance >= 0),
+    is_enabled BOOLEAN DEFAULT true,
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO duplicate_reward_policies (campaign, window_seconds, match_fields, quantity_tolerance, description) VALUES
+('DEFAULT', 300, 'user_id,stock_id,quantity', 0, 'Reject identical rewards to the same user and stock within 5 minutes')
+ON CONFLICT (campaign) DO NOTHING;
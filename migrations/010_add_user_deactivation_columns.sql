@@ -0,0 +1,4 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivation_reason TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower ON users(LOWER(email));
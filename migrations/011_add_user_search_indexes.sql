@@ -0,0 +1,5 @@
+CREATE INDEX IF NOT EXISTS idx_users_email_prefix ON users(LOWER(email) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_name_prefix ON users(LOWER(name) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_phone_prefix ON users(phone text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at);
+CREATE INDEX IF NOT EXISTS idx_reward_events_user_id_created_at ON reward_events(user_id, created_at);
@@ -0,0 +1,26 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pan VARCHAR(10);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS kyc_status VARCHAR(20) NOT NULL DEFAULT 'NOT_STARTED'
+    CHECK (kyc_status IN ('NOT_STARTED', 'PENDING', 'KYC_VERIFIED', 'KYC_REJECTED'));
+ALTER TABLE users ADD COLUMN IF NOT EXISTS kyc_submitted_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS kyc_verified_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS kyc_reviewed_by VARCHAR(255);
+ALTER TABLE users ADD COLUMN IF NOT EXISTS kyc_rejection_reason TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_pan ON users(pan) WHERE pan IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_users_kyc_status ON users(kyc_status);
+
+CREATE TABLE IF NOT EXISTS demat_accounts (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    depository VARCHAR(10) NOT NULL CHECK (depository IN ('NSDL', 'CDSL')),
+    dp_id VARCHAR(8) NOT NULL,
+    client_id VARCHAR(16) NOT NULL,
+    is_primary BOOLEAN NOT NULL DEFAULT false,
+    status VARCHAR(20) NOT NULL DEFAULT 'ACTIVE' CHECK (status IN ('ACTIVE', 'CLOSED')),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(depository, dp_id, client_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_demat_accounts_user_id ON demat_accounts(user_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_demat_accounts_primary ON demat_accounts(user_id) WHERE is_primary AND status = 'ACTIVE';
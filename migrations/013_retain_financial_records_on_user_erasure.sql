@@ -0,0 +1,43 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erased_at TIMESTAMP;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS erasure_requested_by VARCHAR(255);
+
+-- Financial records must survive user erasure, so user and reward deletes are
+-- restricted instead of cascading.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'reward_events_user_id_fkey' AND confdeltype = 'c'
+    ) THEN
+        ALTER TABLE reward_events DROP CONSTRAINT reward_events_user_id_fkey;
+        ALTER TABLE reward_events ADD CONSTRAINT reward_events_user_id_fkey
+            FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'ledger_entries_user_id_fkey' AND confdeltype = 'c'
+    ) THEN
+        ALTER TABLE ledger_entries DROP CONSTRAINT ledger_entries_user_id_fkey;
+        ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_user_id_fkey
+            FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'ledger_entries_reward_event_id_fkey' AND confdeltype = 'c'
+    ) THEN
+        ALTER TABLE ledger_entries DROP CONSTRAINT ledger_entries_reward_event_id_fkey;
+        ALTER TABLE ledger_entries ADD CONSTRAINT ledger_entries_reward_event_id_fkey
+            FOREIGN KEY (reward_event_id) REFERENCES reward_events(id) ON DELETE RESTRICT;
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'user_stock_holdings_user_id_fkey' AND confdeltype = 'c'
+    ) THEN
+        ALTER TABLE user_stock_holdings DROP CONSTRAINT user_stock_holdings_user_id_fkey;
+        ALTER TABLE user_stock_holdings ADD CONSTRAINT user_stock_holdings_user_id_fkey
+            FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE RESTRICT;
+    END IF;
+END $$;
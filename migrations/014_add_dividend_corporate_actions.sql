@@ -0,0 +1,52 @@
+-- Ledger entries can now originate from a corporate action instead of a reward.
+ALTER TABLE ledger_entries ALTER COLUMN reward_event_id DROP NOT NULL;
+ALTER TABLE ledger_entries ADD COLUMN IF NOT EXISTS corporate_action_id INTEGER REFERENCES corporate_actions(id) ON DELETE RESTRICT;
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_corporate_action_id ON ledger_entries(corporate_action_id);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'check_ledger_entry_source'
+        AND conrelid = 'ledger_entries'::regclass
+    ) THEN
+        ALTER TABLE ledger_entries ADD CONSTRAINT check_ledger_entry_source
+            CHECK (reward_event_id IS NOT NULL OR corporate_action_id IS NOT NULL);
+    END IF;
+END $$;
+
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS dividend_per_share NUMERIC(18, 4);
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS record_date DATE;
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS payment_date DATE;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'corporate_actions_action_type_check'
+        AND pg_get_constraintdef(oid) LIKE '%DIVIDEND%'
+    ) THEN
+        ALTER TABLE corporate_actions DROP CONSTRAINT IF EXISTS corporate_actions_action_type_check;
+        ALTER TABLE corporate_actions ADD CONSTRAINT corporate_actions_action_type_check
+            CHECK (action_type IN ('STOCK_SPLIT', 'MERGER', 'DELISTING', 'DIVIDEND'));
+    END IF;
+END $$;
+
+CREATE TABLE IF NOT EXISTS dividend_entitlements (
+    id SERIAL PRIMARY KEY,
+    corporate_action_id INTEGER NOT NULL REFERENCES corporate_actions(id) ON DELETE RESTRICT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    stock_id INTEGER NOT NULL REFERENCES stocks(id) ON DELETE RESTRICT,
+    record_quantity NUMERIC(18, 6) NOT NULL,
+    dividend_per_share NUMERIC(18, 4) NOT NULL,
+    gross_amount NUMERIC(18, 4) NOT NULL,
+    tds_rate NUMERIC(5, 4) NOT NULL,
+    tds_amount NUMERIC(18, 4) NOT NULL,
+    net_amount NUMERIC(18, 4) NOT NULL,
+    paid_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(corporate_action_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_dividend_entitlements_user_id ON dividend_entitlements(user_id);
+CREATE INDEX IF NOT EXISTS idx_dividend_entitlements_stock_id ON dividend_entitlements(stock_id);
@@ -0,0 +1,37 @@
+-- Integer ratio pair for BONUS (numerator bonus shares for every denominator
+-- held) and REVERSE_SPLIT (numerator old shares consolidated into denominator
+-- new shares).
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS ratio_numerator INTEGER CHECK (ratio_numerator > 0);
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS ratio_denominator INTEGER CHECK (ratio_denominator > 0);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'corporate_actions_action_type_check'
+        AND pg_get_constraintdef(oid) LIKE '%REVERSE_SPLIT%'
+    ) THEN
+        ALTER TABLE corporate_actions DROP CONSTRAINT IF EXISTS corporate_actions_action_type_check;
+        ALTER TABLE corporate_actions ADD CONSTRAINT corporate_actions_action_type_check
+            CHECK (action_type IN ('STOCK_SPLIT', 'MERGER', 'DELISTING', 'DIVIDEND', 'BONUS', 'REVERSE_SPLIT'));
+    END IF;
+END $$;
+
+-- Per-user outcome of a unit-changing corporate action, including the
+-- fractional part of the entitlement.
+CREATE TABLE IF NOT EXISTS corporate_action_entitlements (
+    id SERIAL PRIMARY KEY,
+    corporate_action_id INTEGER NOT NULL REFERENCES corporate_actions(id) ON DELETE RESTRICT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    stock_id INTEGER NOT NULL REFERENCES stocks(id) ON DELETE RESTRICT,
+    quantity_before NUMERIC(18, 6) NOT NULL,
+    average_price_before NUMERIC(18, 4) NOT NULL,
+    entitled_quantity NUMERIC(18, 6) NOT NULL,
+    fractional_quantity NUMERIC(18, 6) NOT NULL DEFAULT 0,
+    quantity_after NUMERIC(18, 6) NOT NULL,
+    average_price_after NUMERIC(18, 4) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(corporate_action_id, user_id, stock_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_corporate_action_entitlements_user_id ON corporate_action_entitlements(user_id);
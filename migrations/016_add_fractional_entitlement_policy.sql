@@ -0,0 +1,22 @@
+-- How fractional entitlements are settled when a corporate action changes
+-- unit counts: KEEP the fraction, ROUND_DOWN_CASH and pay cash in lieu at
+-- cash_in_lieu_price, or ROUND_HALF_UP to the nearest whole share.
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS fractional_policy VARCHAR(20) NOT NULL DEFAULT 'KEEP';
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS cash_in_lieu_price NUMERIC(18, 4);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'check_corporate_action_fractional_policy'
+        AND conrelid = 'corporate_actions'::regclass
+    ) THEN
+        ALTER TABLE corporate_actions ADD CONSTRAINT check_corporate_action_fractional_policy
+            CHECK (fractional_policy IN ('KEEP', 'ROUND_DOWN_CASH', 'ROUND_HALF_UP'));
+    END IF;
+END $$;
+
+-- Mergers credit a different stock than the one given up.
+ALTER TABLE corporate_action_entitlements ADD COLUMN IF NOT EXISTS to_stock_id INTEGER REFERENCES stocks(id) ON DELETE RESTRICT;
+ALTER TABLE corporate_action_entitlements ADD COLUMN IF NOT EXISTS fractional_policy VARCHAR(20) NOT NULL DEFAULT 'KEEP';
+ALTER TABLE corporate_action_entitlements ADD COLUMN IF NOT EXISTS cash_in_lieu_amount NUMERIC(18, 4) NOT NULL DEFAULT 0;
@@ -0,0 +1,19 @@
+-- Delisting either buys users out at exit_price (CASH_SETTLEMENT) or writes
+-- their holding off as a recorded loss (WRITE_OFF).
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS delisting_mode VARCHAR(20);
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS exit_price NUMERIC(18, 4);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'check_corporate_action_delisting_mode'
+        AND conrelid = 'corporate_actions'::regclass
+    ) THEN
+        ALTER TABLE corporate_actions ADD CONSTRAINT check_corporate_action_delisting_mode
+            CHECK (delisting_mode IS NULL OR delisting_mode IN ('CASH_SETTLEMENT', 'WRITE_OFF'));
+    END IF;
+END $$;
+
+ALTER TABLE corporate_action_entitlements ADD COLUMN IF NOT EXISTS settlement_amount NUMERIC(18, 4) NOT NULL DEFAULT 0;
+ALTER TABLE corporate_action_entitlements ADD COLUMN IF NOT EXISTS realized_pnl NUMERIC(18, 4) NOT NULL DEFAULT 0;
@@ -0,0 +1,37 @@
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'corporate_actions_status_check'
+        AND pg_get_constraintdef(oid) LIKE '%REVERSED%'
+    ) THEN
+        ALTER TABLE corporate_actions DROP CONSTRAINT IF EXISTS corporate_actions_status_check;
+        ALTER TABLE corporate_actions ADD CONSTRAINT corporate_actions_status_check
+            CHECK (status IN ('PENDING', 'COMPLETED', 'REVERSED'));
+    END IF;
+END $$;
+
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS reversed_at TIMESTAMP;
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS reversed_by VARCHAR(255);
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS reversal_reason TEXT;
+
+-- State of every holding and stock an action touches, captured just before
+-- it is processed so that it can be reversed.
+CREATE TABLE IF NOT EXISTS corporate_action_holding_snapshots (
+    id SERIAL PRIMARY KEY,
+    corporate_action_id INTEGER NOT NULL REFERENCES corporate_actions(id) ON DELETE RESTRICT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    stock_id INTEGER NOT NULL REFERENCES stocks(id) ON DELETE RESTRICT,
+    total_quantity NUMERIC(18, 6) NOT NULL,
+    average_price NUMERIC(18, 4) NOT NULL,
+    UNIQUE(corporate_action_id, user_id, stock_id)
+);
+
+CREATE TABLE IF NOT EXISTS corporate_action_stock_snapshots (
+    id SERIAL PRIMARY KEY,
+    corporate_action_id INTEGER NOT NULL REFERENCES corporate_actions(id) ON DELETE RESTRICT,
+    stock_id INTEGER NOT NULL REFERENCES stocks(id) ON DELETE RESTRICT,
+    current_price NUMERIC(18, 4) NOT NULL,
+    is_active BOOLEAN NOT NULL,
+    UNIQUE(corporate_action_id, stock_id)
+);
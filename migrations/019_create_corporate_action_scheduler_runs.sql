@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS corporate_action_scheduler_runs (
+    id SERIAL PRIMARY KEY,
+    instance_id VARCHAR(255) NOT NULL,
+    trigger_type VARCHAR(20) NOT NULL DEFAULT 'SCHEDULED' CHECK (trigger_type IN ('SCHEDULED', 'MANUAL')),
+    status VARCHAR(20) NOT NULL DEFAULT 'RUNNING' CHECK (status IN ('RUNNING', 'SUCCEEDED', 'PARTIAL', 'FAILED')),
+    actions_due INTEGER NOT NULL DEFAULT 0,
+    actions_processed INTEGER NOT NULL DEFAULT 0,
+    actions_failed INTEGER NOT NULL DEFAULT 0,
+    actions_skipped INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_corporate_action_scheduler_runs_started_at ON corporate_action_scheduler_runs(started_at);
+
+-- Outcome of each due action within a run.
+CREATE TABLE IF NOT EXISTS corporate_action_scheduler_run_items (
+    id SERIAL PRIMARY KEY,
+    run_id INTEGER NOT NULL REFERENCES corporate_action_scheduler_runs(id) ON DELETE CASCADE,
+    corporate_action_id INTEGER NOT NULL REFERENCES corporate_actions(id) ON DELETE RESTRICT,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('PROCESSED', 'FAILED', 'SKIPPED')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_corporate_action_scheduler_run_items_run_id ON corporate_action_scheduler_run_items(run_id);
+CREATE INDEX IF NOT EXISTS idx_corporate_action_scheduler_run_items_action_id ON corporate_action_scheduler_run_items(corporate_action_id);
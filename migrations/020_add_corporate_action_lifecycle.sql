@@ -0,0 +1,35 @@
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'corporate_actions_status_check'
+        AND pg_get_constraintdef(oid) LIKE '%CANCELLED%'
+    ) THEN
+        ALTER TABLE corporate_actions DROP CONSTRAINT IF EXISTS corporate_actions_status_check;
+        ALTER TABLE corporate_actions ADD CONSTRAINT corporate_actions_status_check
+            CHECK (status IN ('PENDING', 'APPROVED', 'COMPLETED', 'CANCELLED', 'REVERSED'));
+    END IF;
+END $$;
+
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS created_by VARCHAR(255);
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS updated_by VARCHAR(255);
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS approved_by VARCHAR(255);
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS approved_at TIMESTAMP;
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS cancelled_by VARCHAR(255);
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS cancelled_at TIMESTAMP;
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS cancellation_reason TEXT;
+
+-- Every status change and every edit of a corporate action. Edits keep the
+-- changed fields as {"field": {"from": ..., "to": ...}}.
+CREATE TABLE IF NOT EXISTS corporate_action_status_history (
+    id SERIAL PRIMARY KEY,
+    corporate_action_id INTEGER NOT NULL REFERENCES corporate_actions(id) ON DELETE RESTRICT,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20) NOT NULL,
+    changed_by VARCHAR(255),
+    reason TEXT,
+    changes JSONB,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_corporate_action_status_history_action_id ON corporate_action_status_history(corporate_action_id);
@@ -0,0 +1,21 @@
+-- Every action now has a record date; it defaults to the effective date.
+UPDATE corporate_actions SET record_date = effective_date
+WHERE record_date IS NULL AND action_type <> 'DIVIDEND';
+
+-- Holdings as of the end of an action's record date, rebuilt from the
+-- STOCK_UNITS ledger when the action is processed. Processors work from
+-- these rows rather than from live holdings.
+CREATE TABLE IF NOT EXISTS corporate_action_record_holdings (
+    id SERIAL PRIMARY KEY,
+    corporate_action_id INTEGER NOT NULL REFERENCES corporate_actions(id) ON DELETE RESTRICT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    stock_id INTEGER NOT NULL REFERENCES stocks(id) ON DELETE RESTRICT,
+    record_date DATE NOT NULL,
+    record_quantity NUMERIC(18, 6) NOT NULL,
+    current_quantity NUMERIC(18, 6) NOT NULL,
+    average_price NUMERIC(18, 4) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(corporate_action_id, user_id, stock_id)
+);
+
+ALTER TABLE corporate_action_entitlements ADD COLUMN IF NOT EXISTS record_quantity NUMERIC(18, 6);
@@ -0,0 +1,29 @@
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'corporate_actions_action_type_check'
+        AND pg_get_constraintdef(oid) LIKE '%SPINOFF%'
+    ) THEN
+        ALTER TABLE corporate_actions DROP CONSTRAINT IF EXISTS corporate_actions_action_type_check;
+        ALTER TABLE corporate_actions ADD CONSTRAINT corporate_actions_action_type_check
+            CHECK (action_type IN ('STOCK_SPLIT', 'MERGER', 'DELISTING', 'DIVIDEND', 'BONUS', 'REVERSE_SPLIT', 'SPINOFF'));
+    END IF;
+END $$;
+
+-- A spin-off credits spinoff_stock_id units at ratio_numerator:ratio_denominator
+-- and moves cost_apportionment_pct percent of the parent's cost basis to them.
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS spinoff_stock_id INTEGER REFERENCES stocks(id) ON DELETE RESTRICT;
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS cost_apportionment_pct NUMERIC(7, 4);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'corporate_actions_cost_apportionment_pct_check') THEN
+        ALTER TABLE corporate_actions ADD CONSTRAINT corporate_actions_cost_apportionment_pct_check
+            CHECK (cost_apportionment_pct IS NULL OR (cost_apportionment_pct > 0 AND cost_apportionment_pct < 100));
+    END IF;
+END $$;
+
+-- The parent keeps its units in a spin-off; this is its average price after
+-- part of the cost moved to the new stock.
+ALTER TABLE corporate_action_entitlements ADD COLUMN IF NOT EXISTS source_average_price_after NUMERIC(18, 4);
@@ -0,0 +1,38 @@
+-- ISIN identifies a security across symbol changes; clients that cache it
+-- keep working when the exchange symbol is renamed.
+ALTER TABLE stocks ADD COLUMN IF NOT EXISTS isin VARCHAR(12);
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'stocks_isin_key') THEN
+        ALTER TABLE stocks ADD CONSTRAINT stocks_isin_key UNIQUE (isin);
+    END IF;
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'stocks_isin_check') THEN
+        ALTER TABLE stocks ADD CONSTRAINT stocks_isin_check
+            CHECK (isin IS NULL OR isin ~ '^[A-Z]{2}[A-Z0-9]{9}[0-9]$');
+    END IF;
+END $$;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'corporate_actions_action_type_check'
+        AND pg_get_constraintdef(oid) LIKE '%SYMBOL_CHANGE%'
+    ) THEN
+        ALTER TABLE corporate_actions DROP CONSTRAINT IF EXISTS corporate_actions_action_type_check;
+        ALTER TABLE corporate_actions ADD CONSTRAINT corporate_actions_action_type_check
+            CHECK (action_type IN ('STOCK_SPLIT', 'MERGER', 'DELISTING', 'DIVIDEND', 'BONUS', 'REVERSE_SPLIT', 'SPINOFF', 'SYMBOL_CHANGE'));
+    END IF;
+END $$;
+
+-- A symbol change renames the stock on its effective date. old_symbol is
+-- the symbol it replaced, filled in when the action is processed; completed
+-- symbol changes are how old symbols resolve to the current stock.
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS old_symbol VARCHAR(50);
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS new_symbol VARCHAR(50);
+
+CREATE INDEX IF NOT EXISTS idx_corporate_actions_old_symbol ON corporate_actions(old_symbol) WHERE old_symbol IS NOT NULL;
+
+-- Reversing a symbol change restores the symbol from the snapshot.
+ALTER TABLE corporate_action_stock_snapshots ADD COLUMN IF NOT EXISTS symbol VARCHAR(50);
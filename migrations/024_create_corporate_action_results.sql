@@ -0,0 +1,47 @@
+-- Outcome of processing an action, captured in the same transaction so the
+-- detail view does not depend on holdings that keep changing afterwards.
+-- units_before/units_after are totals over the affected users; for mergers
+-- and spin-offs units_after counts units of the target or new stock.
+CREATE TABLE IF NOT EXISTS corporate_action_results (
+    corporate_action_id INTEGER PRIMARY KEY REFERENCES corporate_actions(id) ON DELETE RESTRICT,
+    users_affected INTEGER NOT NULL DEFAULT 0,
+    units_before NUMERIC(18, 6) NOT NULL DEFAULT 0,
+    units_after NUMERIC(18, 6) NOT NULL DEFAULT 0,
+    fractional_units NUMERIC(18, 6) NOT NULL DEFAULT 0,
+    cash_amount NUMERIC(18, 4) NOT NULL DEFAULT 0,
+    tds_amount NUMERIC(18, 4) NOT NULL DEFAULT 0,
+    stock_price_before NUMERIC(18, 4),
+    stock_price_after NUMERIC(18, 4),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Backfill actions processed before results were captured, from the rows
+-- they left behind.
+INSERT INTO corporate_action_results (corporate_action_id, users_affected, units_before, units_after,
+                                      fractional_units, cash_amount, tds_amount, stock_price_before, created_at)
+SELECT ca.id,
+       COALESCE(e.users, d.users, 0),
+       COALESCE(e.units_before, d.units, 0),
+       COALESCE(e.units_after, d.units, 0),
+       COALESCE(e.fractional, 0),
+       COALESCE(e.cash, d.cash, 0),
+       COALESCE(d.tds, 0),
+       snap.current_price,
+       COALESCE(ca.processed_at, CURRENT_TIMESTAMP)
+FROM corporate_actions ca
+LEFT JOIN (
+    SELECT corporate_action_id, COUNT(DISTINCT user_id) AS users, SUM(quantity_before) AS units_before,
+           SUM(quantity_after) AS units_after, SUM(fractional_quantity) AS fractional,
+           SUM(cash_in_lieu_amount + settlement_amount) AS cash
+    FROM corporate_action_entitlements GROUP BY corporate_action_id
+) e ON e.corporate_action_id = ca.id
+LEFT JOIN (
+    SELECT corporate_action_id, COUNT(DISTINCT user_id) AS users, SUM(record_quantity) AS units,
+           SUM(net_amount) AS cash, SUM(tds_amount) AS tds
+    FROM dividend_entitlements GROUP BY corporate_action_id
+) d ON d.corporate_action_id = ca.id
+LEFT JOIN corporate_action_stock_snapshots snap ON snap.corporate_action_id = ca.id AND snap.stock_id = ca.stock_id
+WHERE ca.processed_at IS NOT NULL
+ON CONFLICT (corporate_action_id) DO NOTHING;
+
+CREATE INDEX IF NOT EXISTS idx_corporate_actions_action_type ON corporate_actions(action_type);
@@ -0,0 +1,33 @@
+-- Actions are processed in chunks of holders across several transactions;
+-- PROCESSING marks an action whose snapshot has been taken but whose
+-- switchover has not run yet.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'corporate_actions_status_check'
+        AND pg_get_constraintdef(oid) LIKE '%PROCESSING%'
+    ) THEN
+        ALTER TABLE corporate_actions DROP CONSTRAINT IF EXISTS corporate_actions_status_check;
+        ALTER TABLE corporate_actions ADD CONSTRAINT corporate_actions_status_check
+            CHECK (status IN ('PENDING', 'APPROVED', 'PROCESSING', 'COMPLETED', 'CANCELLED', 'REVERSED'));
+    END IF;
+END $$;
+
+-- Progress of a chunked run. last_user_id is the cursor into
+-- corporate_action_record_holdings: every position up to and including it
+-- has been applied, so an interrupted run resumes after it.
+CREATE TABLE IF NOT EXISTS corporate_action_processing_progress (
+    corporate_action_id INTEGER PRIMARY KEY REFERENCES corporate_actions(id) ON DELETE RESTRICT,
+    total_positions INTEGER NOT NULL DEFAULT 0,
+    processed_positions INTEGER NOT NULL DEFAULT 0,
+    chunks_processed INTEGER NOT NULL DEFAULT 0,
+    last_user_id INTEGER NOT NULL DEFAULT 0,
+    started_by VARCHAR(255),
+    last_error TEXT,
+    started_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_corporate_actions_processing ON corporate_actions(stock_id) WHERE status = 'PROCESSING';
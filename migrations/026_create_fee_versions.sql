@@ -0,0 +1,43 @@
+-- Effective-dated fee schedules. Each version is a complete set of rates and
+-- a reward keeps the version in effect when it was created, so scheduling a
+-- change never rewrites fees already charged. Versions are cancelled rather
+-- than deleted.
+CREATE TABLE IF NOT EXISTS fee_versions (
+    id SERIAL PRIMARY KEY,
+    effective_from TIMESTAMP NOT NULL,
+    description TEXT,
+    created_by VARCHAR(255),
+    cancelled_by VARCHAR(255),
+    cancelled_at TIMESTAMP,
+    cancellation_reason TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_fee_versions_effective_from ON fee_versions(effective_from) WHERE cancelled_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS fee_rates (
+    id SERIAL PRIMARY KEY,
+    fee_version_id INTEGER NOT NULL REFERENCES fee_versions(id) ON DELETE RESTRICT,
+    fee_type VARCHAR(50) NOT NULL,
+    percentage NUMERIC(9, 6) NOT NULL CHECK (percentage >= 0),
+    description TEXT,
+    UNIQUE (fee_version_id, fee_type)
+);
+
+-- The rates seeded by migration 006 become the first version, in effect
+-- from the start so every existing reward falls under it.
+INSERT INTO fee_versions (effective_from, description)
+SELECT '1970-01-01', 'Initial fee schedule'
+WHERE NOT EXISTS (SELECT 1 FROM fee_versions);
+
+INSERT INTO fee_rates (fee_version_id, fee_type, percentage, description)
+SELECT (SELECT MIN(id) FROM fee_versions), fee_type, percentage, description
+FROM fee_configurations
+WHERE is_active = true
+ON CONFLICT (fee_version_id, fee_type) DO NOTHING;
+
+ALTER TABLE reward_events ADD COLUMN IF NOT EXISTS fee_version_id INTEGER REFERENCES fee_versions(id);
+
+UPDATE reward_events SET fee_version_id = (SELECT MIN(id) FROM fee_versions) WHERE fee_version_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_reward_events_fee_version_id ON reward_events(fee_version_id);
@@ -0,0 +1,64 @@
+-- Fee rates become rules the fee engine evaluates. A rule charges
+-- base × percentage + flat_amount, clamped to [min_amount, max_amount].
+-- The base is the transaction value, or the sum of the fees listed in
+-- base_fee_types (GST on brokerage). A rule with exchanges only applies to
+-- stocks listed on one of them; NULL means every exchange.
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_name = 'fee_rates' AND column_name = 'base_fee_types'
+    ) THEN
+        ALTER TABLE fee_rates ADD COLUMN base_fee_types TEXT[];
+
+        -- GST was hard-coded on brokerage before the engine existed.
+        UPDATE fee_rates SET base_fee_types = ARRAY['BROKERAGE'] WHERE fee_type = 'GST';
+    END IF;
+END $$;
+
+-- Exchange and regulator charges are quoted to 7 decimal places.
+ALTER TABLE fee_rates ALTER COLUMN percentage TYPE NUMERIC(12, 9);
+
+ALTER TABLE fee_rates ADD COLUMN IF NOT EXISTS name VARCHAR(100);
+ALTER TABLE fee_rates ADD COLUMN IF NOT EXISTS account_type VARCHAR(50);
+ALTER TABLE fee_rates ADD COLUMN IF NOT EXISTS flat_amount NUMERIC(18, 4) NOT NULL DEFAULT 0 CHECK (flat_amount >= 0);
+ALTER TABLE fee_rates ADD COLUMN IF NOT EXISTS min_amount NUMERIC(18, 4) CHECK (min_amount >= 0);
+ALTER TABLE fee_rates ADD COLUMN IF NOT EXISTS max_amount NUMERIC(18, 4) CHECK (max_amount >= 0);
+ALTER TABLE fee_rates ADD COLUMN IF NOT EXISTS exchanges TEXT[];
+
+-- Keep the ledger lines the hard-coded fees used to book. Both columns stay
+-- nullable because migration 026 re-inserts its seed rows without them; the
+-- engine falls back to the fee type.
+UPDATE fee_rates SET name = 'Brokerage fee' WHERE fee_type = 'BROKERAGE' AND name IS NULL;
+UPDATE fee_rates SET name = 'Securities Transaction Tax' WHERE fee_type = 'STT' AND name IS NULL;
+UPDATE fee_rates SET name = 'GST on brokerage' WHERE fee_type = 'GST' AND name IS NULL;
+UPDATE fee_rates SET name = fee_type WHERE name IS NULL;
+UPDATE fee_rates SET account_type = fee_type || '_FEE' WHERE account_type IS NULL;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint
+        WHERE conname = 'check_fee_rates_cap_range'
+        AND conrelid = 'fee_rates'::regclass
+    ) THEN
+        ALTER TABLE fee_rates ADD CONSTRAINT check_fee_rates_cap_range
+            CHECK (min_amount IS NULL OR max_amount IS NULL OR min_amount <= max_amount);
+    END IF;
+END $$;
+
+-- Slabs by transaction value. When a rule has slabs, the slab containing the
+-- transaction value supplies the percentage and flat amount; a value outside
+-- every slab means the fee does not apply.
+CREATE TABLE IF NOT EXISTS fee_rate_slabs (
+    id SERIAL PRIMARY KEY,
+    fee_rate_id INTEGER NOT NULL REFERENCES fee_rates(id) ON DELETE CASCADE,
+    from_value NUMERIC(18, 4) NOT NULL CHECK (from_value >= 0),
+    to_value NUMERIC(18, 4),
+    percentage NUMERIC(12, 9) NOT NULL DEFAULT 0 CHECK (percentage >= 0),
+    flat_amount NUMERIC(18, 4) NOT NULL DEFAULT 0 CHECK (flat_amount >= 0),
+    CHECK (to_value IS NULL OR to_value > from_value),
+    UNIQUE (fee_rate_id, from_value)
+);
+
+CREATE INDEX IF NOT EXISTS idx_fee_rate_slabs_fee_rate_id ON fee_rate_slabs(fee_rate_id);
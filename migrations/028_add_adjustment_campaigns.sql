@@ -0,0 +1,12 @@
+-- Adjustments carry the campaign of the reward they refund so cost reports
+-- net refunds against the right campaign. Older adjustments only name the
+-- original reward in their description ("REFUND for reward #123: ...").
+UPDATE reward_events a
+SET campaign = o.campaign
+FROM reward_events o
+WHERE a.event_type = 'ADJUSTMENT'
+AND a.campaign IS NULL
+AND o.campaign IS NOT NULL
+AND o.id = substring(a.description FROM 'for reward #([0-9]+)')::int;
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_account_type_created_at ON ledger_entries(account_type, created_at);
@@ -0,0 +1,43 @@
+-- GST tax invoices for the fees charged on rewards. An invoice is rendered
+-- once, and its JSON and PDF are stored as generated so re-downloads are
+-- byte-for-byte unchanged even if user, fee or supplier details change later.
+-- Invoices are tax records: they are never deleted and survive user erasure.
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+    financial_year VARCHAR(7) PRIMARY KEY,
+    last_number INTEGER NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS invoices (
+    id SERIAL PRIMARY KEY,
+    invoice_number VARCHAR(50) UNIQUE NOT NULL,
+    financial_year VARCHAR(7) NOT NULL,
+    sequence_number INTEGER NOT NULL,
+    scope VARCHAR(20) NOT NULL CHECK (scope IN ('REWARD', 'DAILY')),
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    invoice_date DATE NOT NULL,
+    period_date DATE NOT NULL,
+    supplier_gstin VARCHAR(15) NOT NULL,
+    recipient_gstin VARCHAR(15),
+    place_of_supply VARCHAR(2) NOT NULL,
+    taxable_value NUMERIC(18, 4) NOT NULL,
+    cgst_amount NUMERIC(18, 4) NOT NULL DEFAULT 0,
+    sgst_amount NUMERIC(18, 4) NOT NULL DEFAULT 0,
+    igst_amount NUMERIC(18, 4) NOT NULL DEFAULT 0,
+    total_amount NUMERIC(18, 4) NOT NULL,
+    document TEXT NOT NULL,
+    pdf BYTEA NOT NULL,
+    generated_by VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (financial_year, sequence_number)
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoices_user_id ON invoices(user_id);
+CREATE INDEX IF NOT EXISTS idx_invoices_invoice_date ON invoices(invoice_date);
+
+-- A reward's fees appear on exactly one invoice.
+CREATE TABLE IF NOT EXISTS invoice_rewards (
+    invoice_id INTEGER NOT NULL REFERENCES invoices(id) ON DELETE RESTRICT,
+    reward_event_id INTEGER NOT NULL REFERENCES reward_events(id) ON DELETE RESTRICT,
+    PRIMARY KEY (invoice_id, reward_event_id),
+    UNIQUE (reward_event_id)
+);
@@ -0,0 +1,116 @@
+-- Tax lots: every acquisition of units is kept as its own lot with its
+-- acquisition date and cost, so units given up can be matched first in,
+-- first out and the gain split into short and long term. Holdings keep the
+-- weighted average price for portfolio valuation. Lots and disposals are tax
+-- records and survive user erasure.
+CREATE TABLE IF NOT EXISTS tax_lots (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    stock_id INTEGER NOT NULL REFERENCES stocks(id) ON DELETE RESTRICT,
+    source VARCHAR(20) NOT NULL,
+    reward_event_id INTEGER REFERENCES reward_events(id) ON DELETE RESTRICT,
+    corporate_action_id INTEGER REFERENCES corporate_actions(id) ON DELETE RESTRICT,
+    parent_lot_id INTEGER REFERENCES tax_lots(id) ON DELETE RESTRICT,
+    acquired_at TIMESTAMP NOT NULL,
+    quantity NUMERIC(18, 6) NOT NULL CHECK (quantity > 0),
+    cost NUMERIC(18, 4) NOT NULL CHECK (cost >= 0),
+    remaining_quantity NUMERIC(18, 6) NOT NULL CHECK (remaining_quantity >= 0),
+    remaining_cost NUMERIC(18, 4) NOT NULL CHECK (remaining_cost >= 0),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_lots_user_stock_acquired_at ON tax_lots(user_id, stock_id, acquired_at, id);
+CREATE INDEX IF NOT EXISTS idx_tax_lots_reward_event_id ON tax_lots(reward_event_id);
+CREATE INDEX IF NOT EXISTS idx_tax_lots_corporate_action_id ON tax_lots(corporate_action_id);
+
+-- One row per lot a disposal consumed. A reversed corporate action keeps its
+-- disposals with reversed_at set; they no longer count towards gains.
+CREATE TABLE IF NOT EXISTS tax_lot_disposals (
+    id SERIAL PRIMARY KEY,
+    tax_lot_id INTEGER NOT NULL REFERENCES tax_lots(id) ON DELETE RESTRICT,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE RESTRICT,
+    stock_id INTEGER NOT NULL REFERENCES stocks(id) ON DELETE RESTRICT,
+    disposal_type VARCHAR(20) NOT NULL CHECK (disposal_type IN ('REFUND', 'DELISTING', 'CASH_IN_LIEU')),
+    reward_event_id INTEGER REFERENCES reward_events(id) ON DELETE RESTRICT,
+    corporate_action_id INTEGER REFERENCES corporate_actions(id) ON DELETE RESTRICT,
+    acquired_at TIMESTAMP NOT NULL,
+    disposed_at TIMESTAMP NOT NULL,
+    quantity NUMERIC(18, 6) NOT NULL CHECK (quantity > 0),
+    cost NUMERIC(18, 4) NOT NULL,
+    proceeds NUMERIC(18, 4) NOT NULL,
+    term VARCHAR(10) NOT NULL CHECK (term IN ('SHORT_TERM', 'LONG_TERM')),
+    reversed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_tax_lot_disposals_user_disposed_at ON tax_lot_disposals(user_id, disposed_at);
+CREATE INDEX IF NOT EXISTS idx_tax_lot_disposals_tax_lot_id ON tax_lot_disposals(tax_lot_id);
+CREATE INDEX IF NOT EXISTS idx_tax_lot_disposals_corporate_action_id ON tax_lot_disposals(corporate_action_id);
+
+-- Lots an action can touch, captured with the holding snapshots so that a
+-- reversal puts them back too. Actions processed before lots were tracked
+-- have tax_lots_snapshotted = false and cannot restore them.
+CREATE TABLE IF NOT EXISTS corporate_action_lot_snapshots (
+    corporate_action_id INTEGER NOT NULL REFERENCES corporate_actions(id) ON DELETE RESTRICT,
+    tax_lot_id INTEGER NOT NULL REFERENCES tax_lots(id) ON DELETE RESTRICT,
+    remaining_quantity NUMERIC(18, 6) NOT NULL,
+    remaining_cost NUMERIC(18, 4) NOT NULL,
+    PRIMARY KEY (corporate_action_id, tax_lot_id)
+);
+
+ALTER TABLE corporate_actions ADD COLUMN IF NOT EXISTS tax_lots_snapshotted BOOLEAN NOT NULL DEFAULT false;
+
+-- Opening lots for holdings that predate lot tracking. Where a holding has
+-- only been moved by rewards and refunds, its lots are rebuilt from the
+-- rewards: refunds consumed the oldest units, so what is left is the newest
+-- rewards up to the held quantity.
+WITH untracked AS (
+    SELECT h.user_id, h.stock_id, h.total_quantity
+    FROM user_stock_holdings h
+    WHERE h.total_quantity > 0
+    AND NOT EXISTS (SELECT 1 FROM tax_lots l WHERE l.user_id = h.user_id AND l.stock_id = h.stock_id)
+    AND NOT EXISTS (
+        SELECT 1 FROM ledger_entries le
+        WHERE le.user_id = h.user_id AND le.stock_id = h.stock_id
+        AND le.account_type = 'STOCK_UNITS' AND le.corporate_action_id IS NOT NULL
+    )
+),
+rewards AS (
+    SELECT re.id, re.user_id, re.stock_id, re.quantity, re.stock_price, u.total_quantity,
+           COALESCE(le.created_at, re.created_at) AS acquired_at,
+           SUM(re.quantity) OVER (
+               PARTITION BY re.user_id, re.stock_id
+               ORDER BY COALESCE(le.created_at, re.created_at) DESC, re.id DESC
+           ) AS newer_quantity,
+           SUM(re.quantity) OVER (PARTITION BY re.user_id, re.stock_id) AS rewarded_quantity
+    FROM reward_events re
+    JOIN untracked u ON u.user_id = re.user_id AND u.stock_id = re.stock_id
+    LEFT JOIN ledger_entries le ON le.reward_event_id = re.id AND le.account_type = 'STOCK_UNITS'
+    WHERE re.event_type <> 'ADJUSTMENT' AND re.status = 'COMPLETED'
+),
+kept AS (
+    SELECT id, user_id, stock_id, quantity, stock_price, acquired_at,
+           LEAST(quantity, total_quantity - (newer_quantity - quantity)) AS remaining_quantity
+    FROM rewards
+    WHERE rewarded_quantity >= total_quantity
+)
+INSERT INTO tax_lots (user_id, stock_id, source, reward_event_id, acquired_at, quantity, cost, remaining_quantity, remaining_cost)
+SELECT user_id, stock_id, 'REWARD', id, acquired_at, quantity, ROUND(quantity * stock_price, 4),
+       remaining_quantity, ROUND(remaining_quantity * stock_price, 4)
+FROM kept
+WHERE remaining_quantity > 0;
+
+-- Anything else (holdings reshaped by corporate actions) gets a single lot
+-- at the average price, dated from its first unit movement.
+INSERT INTO tax_lots (user_id, stock_id, source, acquired_at, quantity, cost, remaining_quantity, remaining_cost)
+SELECT h.user_id, h.stock_id, 'OPENING',
+       COALESCE((
+           SELECT MIN(le.created_at) FROM ledger_entries le
+           WHERE le.user_id = h.user_id AND le.stock_id = h.stock_id AND le.account_type = 'STOCK_UNITS'
+       ), h.created_at, CURRENT_TIMESTAMP),
+       h.total_quantity, ROUND(h.total_quantity * h.average_price, 4),
+       h.total_quantity, ROUND(h.total_quantity * h.average_price, 4)
+FROM user_stock_holdings h
+WHERE h.total_quantity > 0
+AND NOT EXISTS (SELECT 1 FROM tax_lots l WHERE l.user_id = h.user_id AND l.stock_id = h.stock_id);